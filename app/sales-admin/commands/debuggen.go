@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/foundation/logger"
+)
+
+// DebugGen generates a value of the X-Debug-Log header signed with the debug
+// key of the sales-api, switching on verbose logging for the duration.
+func DebugGen(key string, duration string) error {
+	if key == "" {
+		fmt.Println("help: debuggen <debug_key> [duration]")
+		fmt.Printf("duration: up to %v, defaults to 15m\n", logger.MaxDebugTTL)
+		return ErrHelp
+	}
+
+	ttl := 15 * time.Minute
+	if duration != "" {
+		var err error
+		if ttl, err = time.ParseDuration(duration); err != nil {
+			return errors.Wrapf(err, "parsing duration %q", duration)
+		}
+	}
+	if ttl <= 0 || ttl > logger.MaxDebugTTL {
+		return errors.Errorf("duration %v not in (0, %v]", ttl, logger.MaxDebugTTL)
+	}
+
+	fmt.Printf("%s: %s\n", logger.DebugHeader, logger.SignDebug(key, time.Now().Add(ttl)))
+	return nil
+}
@@ -3,7 +3,7 @@ package commands
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/pkg/errors"
@@ -13,7 +13,7 @@ import (
 )
 
 // UserAdd adds new users into the database.
-func UserAdd(traceID string, log *log.Logger, cfg database.Config, name, email, password string) error {
+func UserAdd(traceID string, log *slog.Logger, cfg database.Config, name, email, password string) error {
 
 	if name == "" || email == "" || password == "" {
 		fmt.Println("help: useradd <name> <email> <password>")
@@ -3,7 +3,7 @@ package commands
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
 	"time"
@@ -14,7 +14,7 @@ import (
 )
 
 // Users retrieves all users from the database.
-func Users(traceID string, log *log.Logger, cfg database.Config, pageNumber string, rowsPerPage string) error {
+func Users(traceID string, log *slog.Logger, cfg database.Config, pageNumber string, rowsPerPage string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -3,7 +3,7 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 
 	"github.com/pkg/errors"
@@ -11,22 +11,27 @@ import (
 	"github.com/tullo/service/app/sales-admin/commands"
 	"github.com/tullo/service/foundation/config"
 	"github.com/tullo/service/foundation/database"
+	"github.com/tullo/service/foundation/logger"
 )
 
 // build is the git version of this program. It is set using build flags in the makefile.
 var build = "develop"
 
 func main() {
-	log := log.New(os.Stdout, "ADMIN : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
-	if err := run(log); err != nil {
+
+	// The level is set once the configuration is parsed.
+	var level slog.LevelVar
+	log := logger.New(os.Stdout, "sales-admin", &level)
+
+	if err := run(log, &level); err != nil {
 		if errors.Cause(err) != commands.ErrHelp {
-			log.Printf("error: %s", err)
+			log.Error("command failed", "error", err)
 		}
 		os.Exit(1)
 	}
 }
 
-func run(log *log.Logger) error {
+func run(log *slog.Logger, level *slog.LevelVar) error {
 
 	// =========================================================================
 	// Configuration
@@ -52,6 +57,12 @@ func run(log *log.Logger) error {
 		return errors.Wrap(err, "parsing config")
 	}
 
+	lvl, err := logger.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return errors.Wrap(err, "parsing config")
+	}
+	level.Set(lvl)
+
 	// =========================================================================
 	// Commands
 
@@ -104,6 +115,13 @@ func run(log *log.Logger) error {
 			return errors.Wrap(err, "generating token")
 		}
 
+	case "debuggen":
+		key := cfg.Args.Num(1)
+		duration := cfg.Args.Num(2)
+		if err := commands.DebugGen(key, duration); err != nil {
+			return errors.Wrap(err, "generating debug header")
+		}
+
 	default:
 		fmt.Println("migrate: create the schema in the database")
 		fmt.Println("seed: add data to the database")
@@ -111,6 +129,7 @@ func run(log *log.Logger) error {
 		fmt.Println("users: get a list of users from the database")
 		fmt.Println("keygen: generate a set of private/public key files")
 		fmt.Println("tokengen: generate a JWT for a user with claims")
+		fmt.Println("debuggen: generate a header switching on verbose logging of requests")
 		fmt.Println("provide a command to get more help.")
 		return commands.ErrHelp
 	}
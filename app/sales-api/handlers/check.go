@@ -2,7 +2,7 @@ package handlers
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
@@ -17,7 +17,7 @@ import (
 type checkGroup struct {
 	build string
 	db    *database.DB
-	log   *log.Logger
+	log   *slog.Logger
 	// ADD OTHER STATE LIKE THE LOGGER IF NEEDED.
 }
 
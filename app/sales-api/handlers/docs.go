@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	swaggerFiles "github.com/swaggo/files/v2"
+	"github.com/tullo/service/business/data/product"
+	"github.com/tullo/service/business/data/sale"
+	"github.com/tullo/service/business/data/user"
+	"github.com/tullo/service/business/data/webhook"
+	"github.com/tullo/service/business/event"
+	"github.com/tullo/service/foundation/openapi"
+	"github.com/tullo/service/foundation/web"
+	"go.opentelemetry.io/otel"
+)
+
+// docsGroup serves the OpenAPI document of the API and the Swagger UI.
+type docsGroup struct {
+	app   *web.App
+	build string
+}
+
+// openAPI returns the OpenAPI document describing the routes of the API.
+func (dg docsGroup) openAPI(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.docs.openapi")
+	defer span.End()
+
+	return web.Respond(ctx, w, dg.document(), http.StatusOK)
+}
+
+// document returns the OpenAPI document of the API.
+func (dg docsGroup) document() openapi.Document {
+	info := openapi.Info{
+		Title:   "Sales API",
+		Version: dg.build,
+	}
+	return dg.app.OpenAPI(info)
+}
+
+// swaggerInitializer configures the Swagger UI to load the OpenAPI document
+// served next to it.
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "openapi.json",
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    presets: [
+      SwaggerUIBundle.presets.apis,
+      SwaggerUIStandalonePreset
+    ],
+    plugins: [
+      SwaggerUIBundle.plugins.DownloadUrl
+    ],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// ui serves the embedded Swagger UI together with the OpenAPI document.
+func (dg docsGroup) ui(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.docs.ui")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	file := strings.TrimPrefix(r.URL.Path, "/debug/docs/")
+	switch file {
+	case "openapi.json":
+		return web.Respond(ctx, w, dg.document(), http.StatusOK)
+
+	case "swagger-initializer.js":
+		v.StatusCode = http.StatusOK
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		if _, err := w.Write([]byte(swaggerInitializer)); err != nil {
+			return errors.Wrap(err, "writing swagger initializer")
+		}
+		return nil
+
+	case "":
+		file = "index.html"
+	}
+
+	if _, err := fs.Stat(swaggerFiles.FS, file); err != nil {
+		return web.NewRequestError(errors.Errorf("%s not found", file), http.StatusNotFound)
+	}
+
+	v.StatusCode = http.StatusOK
+	http.ServeFileFS(w, r, swaggerFiles.FS, file)
+	return nil
+}
+
+// =============================================================================
+
+// Common parameters of the API.
+var (
+	pageParams = []openapi.Parameter{
+		{Name: "page", In: "path", Required: true, Description: "Page number starting at 1.", Schema: &openapi.Schema{Type: "integer", Minimum: &one}},
+		{Name: "rows", In: "path", Required: true, Description: "Number of rows per page.", Schema: &openapi.Schema{Type: "integer", Minimum: &one}},
+	}
+	listParams = []openapi.Parameter{
+		{Name: "page", In: "query", Description: "Page number starting at 1.", Schema: &openapi.Schema{Type: "integer", Minimum: &one}},
+		{Name: "rows", In: "query", Description: "Number of rows per page, capped at 100.", Schema: &openapi.Schema{Type: "integer", Minimum: &one}},
+		{Name: "count", In: "query", Description: "Include the total number of rows.", Schema: &openapi.Schema{Type: "boolean"}},
+	}
+	viewParams = []openapi.Parameter{
+		{Name: "fields", In: "query", Description: "Comma separated fields of the products to return. Leaving out sold and revenue skips their aggregation.", Schema: &openapi.Schema{Type: "string"}},
+		{Name: "include", In: "query", Description: "Comma separated relations to embed: sales, owner.", Schema: &openapi.Schema{Type: "string"}},
+	}
+	eventParams = []openapi.Parameter{
+		{Name: "product_id", In: "query", Description: "Only stream the events of the product.", Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+		{Name: "owner_id", In: "query", Description: "Only stream the events of the products created by the user.", Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+		{Name: "Last-Event-ID", In: "header", Description: "Resume the stream after the event.", Schema: &openapi.Schema{Type: "string"}},
+		{Name: "last_event_id", In: "query", Description: "Resume the stream after the event, for clients which can't set headers.", Schema: &openapi.Schema{Type: "string"}},
+	}
+	deliveryParams = []openapi.Parameter{
+		{Name: "status", In: "query", Description: "Only list the deliveries with the status: pending, delivered or failed.", Schema: &openapi.Schema{Type: "string"}},
+	}
+	idParam = []openapi.Parameter{
+		{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+	}
+	one = 1.0
+)
+
+// params joins lists of parameters.
+func params(lists ...[]openapi.Parameter) []openapi.Parameter {
+	var ps []openapi.Parameter
+	for _, list := range lists {
+		ps = append(ps, list...)
+	}
+	return ps
+}
+
+// tokenResponse is the body returned by the token route.
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+// Pages of the lists documented with the type of their rows.
+type (
+	userPage struct {
+		web.Page
+		Data []user.Info `json:"data"`
+	}
+	productPage struct {
+		web.Page
+		Data []product.Info `json:"data"`
+	}
+	salePage struct {
+		web.Page
+		Data []sale.Info `json:"data"`
+	}
+	deliveryPage struct {
+		web.Page
+		Data []webhook.Delivery `json:"data"`
+	}
+)
+
+// document adds the documentation of all the routes of the API.
+func document(app *web.App) {
+	docs := map[string]web.Doc{
+
+		// Users.
+		"GET /v1/users/{page}/{rows}": {
+			Summary: "List users", Tags: []string{"users"}, Auth: true, Params: pageParams,
+			Responses: map[int]interface{}{200: []user.Info{}, 400: nil, 401: nil, 403: nil},
+		},
+		"GET /v1/users/{id}": {
+			Summary: "Get a user", Tags: []string{"users"}, Auth: true, Params: idParam,
+			Responses: map[int]interface{}{200: user.Info{}, 400: nil, 401: nil, 403: nil, 404: nil},
+		},
+		"PUT /v1/users/{id}": {
+			Summary: "Update a user", Tags: []string{"users"}, Auth: true, Params: idParam,
+			Request:   user.UpdateUser{},
+			Responses: map[int]interface{}{204: nil, 400: nil, 401: nil, 403: nil, 404: nil},
+		},
+		"PATCH /v1/users/{id}": {
+			Summary: "Patch a user", Tags: []string{"users"}, Auth: true, Params: idParam,
+			Requests: map[string]interface{}{
+				web.MergePatchContentType: user.PatchUser{},
+				web.JSONPatchContentType:  []web.PatchOperation{},
+			},
+			Responses: map[int]interface{}{204: nil, 400: nil, 401: nil, 403: nil, 404: nil, 409: nil, 415: nil, 422: nil},
+		},
+		"POST /v1/users": {
+			Summary: "Create a user", Tags: []string{"users"}, Auth: true,
+			Request:   user.NewUser{},
+			Responses: map[int]interface{}{201: user.Info{}, 400: nil, 401: nil, 403: nil, 409: nil, 422: nil},
+		},
+		"DELETE /v1/users/{id}": {
+			Summary: "Delete a user", Tags: []string{"users"}, Auth: true, Params: idParam,
+			Responses: map[int]interface{}{204: nil, 400: nil, 401: nil, 403: nil},
+		},
+		"GET /v1/users/token/{kid}": {
+			Summary: "Generate a token", Tags: []string{"users"},
+			Description: "Authenticates with basic auth and returns a token signed by the key with the kid.",
+			Responses:   map[int]interface{}{200: tokenResponse{}, 401: nil},
+		},
+
+		// Products.
+		"GET /v1/products/{page}/{rows}": {
+			Summary: "List products", Tags: []string{"products"}, Auth: true, Params: params(pageParams, viewParams),
+			Responses: map[int]interface{}{200: []product.Info{}, 400: nil, 401: nil},
+		},
+		"GET /v1/products/export": {
+			Summary: "Export all products", Tags: []string{"products"}, Auth: true,
+			Description: "Streams all products as newline delimited JSON.",
+			ContentType: web.NDJSONContentType,
+			Responses:   map[int]interface{}{200: product.Info{}, 401: nil},
+		},
+		"POST /v1/products": {
+			Summary: "Create a product", Tags: []string{"products"}, Auth: true,
+			Request:   product.NewProduct{},
+			Responses: map[int]interface{}{201: product.Info{}, 400: nil, 401: nil, 409: nil, 422: nil},
+		},
+		"GET /v1/products/{id}": {
+			Summary: "Get a product", Tags: []string{"products"}, Auth: true, Params: params(idParam, viewParams),
+			Responses: map[int]interface{}{200: product.Info{}, 400: nil, 401: nil, 404: nil},
+		},
+		"PUT /v1/products/{id}": {
+			Summary: "Update a product", Tags: []string{"products"}, Auth: true, Params: idParam,
+			Request:   product.UpdateProduct{},
+			Responses: map[int]interface{}{204: nil, 400: nil, 401: nil, 403: nil, 404: nil},
+		},
+		"PATCH /v1/products/{id}": {
+			Summary: "Patch a product", Tags: []string{"products"}, Auth: true, Params: idParam,
+			Requests: map[string]interface{}{
+				web.MergePatchContentType: product.PatchProduct{},
+				web.JSONPatchContentType:  []web.PatchOperation{},
+			},
+			Responses: map[int]interface{}{204: nil, 400: nil, 401: nil, 403: nil, 404: nil, 409: nil, 415: nil, 422: nil},
+		},
+		"DELETE /v1/products/{id}": {
+			Summary: "Delete a product", Tags: []string{"products"}, Auth: true, Params: idParam,
+			Responses: map[int]interface{}{204: nil, 400: nil, 401: nil, 403: nil},
+		},
+
+		// Sales.
+		"POST /v1/products/{id}/sales": {
+			Summary: "Record a sale", Tags: []string{"sales"}, Auth: true, Params: idParam,
+			Request:   sale.NewSale{},
+			Responses: map[int]interface{}{201: sale.Info{}, 400: nil, 401: nil, 403: nil, 404: nil, 409: nil, 413: nil, 422: nil},
+		},
+		"GET /v1/products/{id}/sales": {
+			Summary: "List the sales of a product", Tags: []string{"sales"}, Auth: true, Params: idParam,
+			Responses: map[int]interface{}{200: []sale.Info{}, 401: nil},
+		},
+		"GET /v1/sales/export": {
+			Summary: "Export all sales", Tags: []string{"sales"}, Auth: true,
+			Description: "Streams all sales as newline delimited JSON.",
+			ContentType: web.NDJSONContentType,
+			Responses:   map[int]interface{}{200: sale.Info{}, 401: nil},
+		},
+
+		// Lists.
+		"GET /v2/users": {
+			Summary: "List a page of users", Tags: []string{"users"}, Auth: true, Params: listParams,
+			Responses: map[int]interface{}{200: userPage{}, 400: nil, 401: nil, 403: nil},
+		},
+		"GET /v2/products": {
+			Summary: "List a page of products", Tags: []string{"products"}, Auth: true, Params: params(listParams, viewParams),
+			Responses: map[int]interface{}{200: productPage{}, 400: nil, 401: nil},
+		},
+		"GET /v2/products/{id}/sales": {
+			Summary: "List a page of the sales of a product", Tags: []string{"sales"}, Auth: true,
+			Params:    params(idParam, listParams),
+			Responses: map[int]interface{}{200: salePage{}, 400: nil, 401: nil},
+		},
+
+		// Events.
+		"GET /v1/events": {
+			Summary: "Stream sale and inventory changes", Tags: []string{"events"}, Auth: true, Params: eventParams,
+			Description: "Pushes sale.created, product.updated and product.deleted events as Server-Sent Events. " +
+				"Users other than admins only receive the events of their own products. " +
+				"A stream.reset event is sent first when the events after the one resumed from are no longer buffered.",
+			ContentType: web.EventStreamContentType,
+			Responses:   map[int]interface{}{200: event.Event{}, 400: nil, 401: nil, 403: nil},
+		},
+
+		// GraphQL.
+		"POST /v1/graphql": {
+			Summary: "Execute a GraphQL query", Tags: []string{"graphql"}, Auth: true,
+			Description: "Queries users, products and sales. Errors of the query are reported in the errors of the response.",
+			Request:     graphqlRequest{},
+			Responses:   map[int]interface{}{200: graphqlResponse{}, 400: nil, 401: nil},
+		},
+
+		// Webhooks.
+		"POST /v1/webhooks": {
+			Summary: "Register a webhook endpoint", Tags: []string{"webhooks"}, Auth: true,
+			Description: "Events are posted to the URL with an X-Webhook-Signature header of the form t=<unix time>,v1=<hex HMAC-SHA256 of \"<unix time>.<body>\">. " +
+				"The secret is generated when none is provided and only returned here. It's stored in plaintext since it's needed to sign the deliveries.",
+			Request:   webhook.NewEndpoint{},
+			Responses: map[int]interface{}{201: webhook.Endpoint{}, 400: nil, 401: nil, 403: nil},
+		},
+		"GET /v1/webhooks": {
+			Summary: "List the webhook endpoints", Tags: []string{"webhooks"}, Auth: true,
+			Responses: map[int]interface{}{200: []webhook.Endpoint{}, 401: nil, 403: nil},
+		},
+		"DELETE /v1/webhooks/{id}": {
+			Summary: "Remove a webhook endpoint", Tags: []string{"webhooks"}, Auth: true, Params: idParam,
+			Responses: map[int]interface{}{204: nil, 400: nil, 401: nil, 403: nil},
+		},
+		"GET /v1/webhooks/deliveries": {
+			Summary: "List a page of webhook deliveries", Tags: []string{"webhooks"}, Auth: true, Params: params(deliveryParams, listParams),
+			Responses: map[int]interface{}{200: deliveryPage{}, 400: nil, 401: nil, 403: nil},
+		},
+		"POST /v1/webhooks/deliveries/{id}/replay": {
+			Summary: "Replay a failed webhook delivery", Tags: []string{"webhooks"}, Auth: true, Params: idParam,
+			Responses: map[int]interface{}{202: webhook.Delivery{}, 400: nil, 401: nil, 403: nil, 404: nil, 409: nil},
+		},
+
+		// Documentation.
+		"GET /v1/openapi.json": {
+			Summary: "Get the OpenAPI document", Tags: []string{"docs"},
+			Responses: map[int]interface{}{200: openapi.Document{}},
+		},
+	}
+
+	for route, doc := range docs {
+		verb, path, _ := strings.Cut(route, " ")
+		app.Document(verb, path, doc)
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/data"
+	"github.com/tullo/service/business/event"
+	"github.com/tullo/service/foundation/web"
+	"go.opentelemetry.io/otel"
+)
+
+// heartbeat is how often idle event streams are pinged.
+const heartbeat = 15 * time.Second
+
+// eventGroup represents the event stream handler set.
+type eventGroup struct {
+	events       *event.Broker
+	writeTimeout time.Duration
+}
+
+// Stream pushes the changes of products and their sales to the client as
+// Server-Sent Events. The events can be filtered by the product_id and
+// owner_id query parameters. Users other than admins only receive the events
+// of the products they own. Clients reconnecting with the Last-Event-ID
+// header, or the last_event_id query parameter, resume after that event. When
+// the events after it are no longer buffered a stream.reset event is sent
+// first.
+func (eg eventGroup) stream(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.event.stream")
+	defer span.End()
+
+	f := event.Filter{
+		ProductID: r.URL.Query().Get("product_id"),
+		OwnerID:   r.URL.Query().Get("owner_id"),
+	}
+	for _, id := range []string{f.ProductID, f.OwnerID} {
+		if _, err := uuid.Parse(id); id != "" && err != nil {
+			return data.ErrInvalidID
+		}
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	// The events carry the sales of the products, including the amounts
+	// paid, which only admins and the owners may see.
+	if !claims.Authorized(auth.RoleAdmin) {
+		if f.OwnerID != "" && f.OwnerID != claims.Subject {
+			return data.ErrForbidden
+		}
+		f.OwnerID = claims.Subject
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	sub := eg.events.Subscribe(f, lastEventID)
+	defer sub.Cancel()
+
+	stream := web.NewEventStream(ctx, w, eg.writeTimeout)
+	defer stream.Close()
+
+	// Failing to write means the client is gone, there is nobody left to
+	// report the error to.
+	if sub.Lost {
+		if err := stream.Send("", event.TypeReset, struct{}{}); err != nil {
+			return nil
+		}
+	}
+	for _, e := range sub.Backlog {
+		if err := stream.Send(e.ID, e.Type, e); err != nil {
+			return nil
+		}
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case e, ok := <-sub.C:
+
+			// The subscriber fell behind or the service is shutting down.
+			// The client reconnects and resumes from the buffer.
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(e.ID, e.Type, e); err != nil {
+				return nil
+			}
+
+		case <-ticker.C:
+			if err := stream.Ping(); err != nil {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package handlers
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"sync"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	gqlotel "github.com/graph-gophers/graphql-go/trace/otel"
+	"github.com/pkg/errors"
+	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/data"
+	"github.com/tullo/service/business/data/product"
+	"github.com/tullo/service/business/data/sale"
+	"github.com/tullo/service/business/data/user"
+	"github.com/tullo/service/foundation/i18n"
+	"github.com/tullo/service/foundation/web"
+	"go.opentelemetry.io/otel"
+)
+
+// graphqlSchema describes the users, products and sales served by the
+// GraphQL endpoint.
+//
+//go:embed schema.graphql
+var graphqlSchema string
+
+// maxGraphQLDepth limits the nesting of queries.
+const maxGraphQLDepth = 8
+
+// graphqlGroup represents the GraphQL API method handler set.
+type graphqlGroup struct {
+	log    *slog.Logger
+	schema *graphql.Schema
+}
+
+// newGraphQLGroup parses the GraphQL schema and binds it to the resolvers
+// calling the stores.
+func newGraphQLGroup(log *slog.Logger, usr user.Store, prd product.Store, sl sale.Store) graphqlGroup {
+	root := rootResolver{
+		user:    usr,
+		product: prd,
+		sale:    sl,
+	}
+	schema := graphql.MustParseSchema(graphqlSchema, &root,
+		graphql.Tracer(gqlotel.DefaultTracer()),
+		graphql.MaxDepth(maxGraphQLDepth),
+	)
+
+	return graphqlGroup{
+		log:    log,
+		schema: schema,
+	}
+}
+
+// graphqlRequest is the body of a GraphQL request.
+type graphqlRequest struct {
+	Query         string                 `json:"query" validate:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlResponse is the body of a GraphQL response.
+type graphqlResponse struct {
+	Data   interface{}             `json:"data,omitempty"`
+	Errors []*gqlerrors.QueryError `json:"errors,omitempty"`
+}
+
+// Query executes a GraphQL query. Errors of the query are part of the
+// response, which is sent with a 200 as long as the request is well formed.
+func (gg graphqlGroup) query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.graphql.query")
+	defer span.End()
+
+	if _, ok := ctx.Value(web.KeyValues).(*web.Values); !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req graphqlRequest
+	if err := web.Decode(r, &req); err != nil {
+		return errors.Wrap(err, "unable to decode payload")
+	}
+
+	res := gg.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	// The data is decoded so the response can be sent in any of the media
+	// types the client accepts.
+	resp := graphqlResponse{
+		Errors: res.Errors,
+	}
+	if len(res.Data) > 0 {
+		if err := json.Unmarshal(res.Data, &resp.Data); err != nil {
+			return errors.Wrap(err, "decoding graphql result")
+		}
+	}
+
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	for _, qe := range resp.Errors {
+		if qe.ResolverError != nil {
+			gg.reportError(ctx, lang, qe)
+		}
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// reportError replaces the message of an error of a resolver by a message
+// which is safe to show to clients and adds a machine-readable code.
+// Unexpected errors are logged.
+func (gg graphqlGroup) reportError(ctx context.Context, lang string, qe *gqlerrors.QueryError) {
+	var de *data.Error
+	var we *web.Error
+	switch {
+	case errors.As(qe.ResolverError, &de):
+		_, qe.Message = de.Translate(lang)
+		qe.Extensions = map[string]interface{}{"code": de.Code}
+
+	case errors.As(qe.ResolverError, &we) && we.Status < http.StatusInternalServerError:
+		qe.Message = we.Err.Error()
+		qe.Extensions = map[string]interface{}{"code": "invalid_argument"}
+
+	default:
+		gg.log.ErrorContext(ctx, "resolving graphql query", "error", qe.ResolverError)
+		qe.Message = http.StatusText(http.StatusInternalServerError)
+		qe.Extensions = map[string]interface{}{"code": "internal"}
+	}
+}
+
+// =============================================================================
+
+// rootResolver resolves the fields of the Query type by calling the stores.
+// The trace id and the claims of the request are taken from the context.
+type rootResolver struct {
+	user    user.Store
+	product product.Store
+	sale    sale.Store
+}
+
+// pageArgs are the arguments of the fields returning a page of a list.
+type pageArgs struct {
+	Page int32
+	Rows int32
+}
+
+// bounds checks the page arguments and caps the rows like the v2 lists.
+func (a pageArgs) bounds() (int, int, error) {
+	if a.Page < 1 {
+		return 0, 0, web.NewRequestError(fmt.Errorf("invalid page: %d", a.Page), http.StatusBadRequest)
+	}
+	if a.Rows < 1 {
+		return 0, 0, web.NewRequestError(fmt.Errorf("invalid rows: %d", a.Rows), http.StatusBadRequest)
+	}
+	return int(a.Page), min(int(a.Rows), web.MaxRowsPerPage), nil
+}
+
+// long is the Long scalar, a 64-bit integer. Amounts in cents outgrow the
+// 32 bits of Int.
+type long int64
+
+// ImplementsGraphQLType maps long to the Long scalar of the schema.
+func (long) ImplementsGraphQLType(name string) bool {
+	return name == "Long"
+}
+
+// UnmarshalGraphQL converts an input value to a long.
+func (l *long) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case int32:
+		*l = long(input)
+	case int64:
+		*l = long(input)
+	case float64:
+		if input != math.Trunc(input) || math.Abs(input) > 1<<53 {
+			return fmt.Errorf("invalid Long: %v", input)
+		}
+		*l = long(input)
+	default:
+		return fmt.Errorf("wrong type for Long: %T", input)
+	}
+	return nil
+}
+
+// requestValues returns the trace id and the claims of the request.
+func requestValues(ctx context.Context) (string, auth.Claims, error) {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return "", auth.Claims{}, web.NewShutdownError("web value missing from context")
+	}
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return "", auth.Claims{}, errors.New("claims missing from context")
+	}
+	return v.TraceID, claims, nil
+}
+
+// User resolves a single user. Users other than admins can only see
+// themselves.
+func (rr *rootResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.graphql.user")
+	defer span.End()
+
+	traceID, claims, err := requestValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := rr.user.QueryByID(ctx, traceID, claims, string(args.ID))
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "ID: %s", args.ID)
+	}
+
+	return &userResolver{usr: usr}, nil
+}
+
+// Users resolves a page of users. It's only available to admins.
+func (rr *rootResolver) Users(ctx context.Context, args pageArgs) ([]*userResolver, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.graphql.users")
+	defer span.End()
+
+	traceID, claims, err := requestValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.Authorized(auth.RoleAdmin) {
+		return nil, data.ErrForbidden
+	}
+
+	page, rows, err := args.bounds()
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := rr.user.Query(ctx, traceID, page, rows)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query for users")
+	}
+
+	resolvers := make([]*userResolver, len(users))
+	for i, usr := range users {
+		resolvers[i] = &userResolver{usr: usr}
+	}
+	return resolvers, nil
+}
+
+// Product resolves a single product. The aggregation of the sales is
+// skipped unless sold or revenue are selected.
+func (rr *rootResolver) Product(ctx context.Context, args struct{ ID graphql.ID }) (*productResolver, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.graphql.product")
+	defer span.End()
+
+	traceID, _, err := requestValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queryByID := rr.product.QueryByIDWithoutTotals
+	if totalsSelected(ctx) {
+		queryByID = rr.product.QueryByID
+	}
+
+	prd, err := queryByID(ctx, traceID, string(args.ID))
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "ID: %s", args.ID)
+	}
+
+	return rr.newProductBatch([]product.Info{prd})[0], nil
+}
+
+// Products resolves a page of products. The aggregation of the sales is
+// skipped unless sold or revenue are selected.
+func (rr *rootResolver) Products(ctx context.Context, args pageArgs) ([]*productResolver, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.graphql.products")
+	defer span.End()
+
+	traceID, _, err := requestValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page, rows, err := args.bounds()
+	if err != nil {
+		return nil, err
+	}
+
+	query := rr.product.QueryWithoutTotals
+	if totalsSelected(ctx) {
+		query = rr.product.Query
+	}
+
+	products, err := query(ctx, traceID, page, rows)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query products")
+	}
+
+	return rr.newProductBatch(products), nil
+}
+
+// totalsSelected reports whether the fields aggregated from the sales are
+// selected.
+func totalsSelected(ctx context.Context) bool {
+	return graphql.HasSelectedField(ctx, "sold") || graphql.HasSelectedField(ctx, "revenue")
+}
+
+// =============================================================================
+
+// productBatch holds the products resolved by a single field. The relations
+// of all of them are loaded at once by the first product asking for them,
+// which avoids a query per product.
+type productBatch struct {
+	root     *rootResolver
+	products []product.Info
+
+	salesOnce sync.Once
+	sales     map[string][]sale.Info
+	salesErr  error
+
+	ownersOnce sync.Once
+	owners     map[string]user.Profile
+	ownersErr  error
+}
+
+// newProductBatch returns the resolvers of products sharing a batch.
+func (rr *rootResolver) newProductBatch(products []product.Info) []*productResolver {
+	batch := productBatch{
+		root:     rr,
+		products: products,
+	}
+
+	resolvers := make([]*productResolver, len(products))
+	for i, prd := range products {
+		resolvers[i] = &productResolver{prd: prd, batch: &batch}
+	}
+	return resolvers
+}
+
+// loadSales loads the sales of all products of the batch.
+func (b *productBatch) loadSales(ctx context.Context) (map[string][]sale.Info, error) {
+	b.salesOnce.Do(func() {
+		ctx, span := otel.Tracer(name).Start(ctx, "handlers.graphql.loadSales")
+		defer span.End()
+
+		traceID, _, err := requestValues(ctx)
+		if err != nil {
+			b.salesErr = err
+			return
+		}
+
+		ids := make([]string, len(b.products))
+		for i, prd := range b.products {
+			ids[i] = prd.ID
+		}
+		b.sales, b.salesErr = b.root.sale.QueryByProducts(ctx, traceID, ids)
+	})
+	return b.sales, b.salesErr
+}
+
+// loadOwners loads the owners of all products of the batch.
+func (b *productBatch) loadOwners(ctx context.Context) (map[string]user.Profile, error) {
+	b.ownersOnce.Do(func() {
+		ctx, span := otel.Tracer(name).Start(ctx, "handlers.graphql.loadOwners")
+		defer span.End()
+
+		traceID, _, err := requestValues(ctx)
+		if err != nil {
+			b.ownersErr = err
+			return
+		}
+
+		ids := make([]string, len(b.products))
+		for i, prd := range b.products {
+			ids[i] = prd.UserID
+		}
+		b.owners, b.ownersErr = b.root.user.QueryProfiles(ctx, traceID, ids)
+	})
+	return b.owners, b.ownersErr
+}
+
+// productResolver resolves the fields of the Product type.
+type productResolver struct {
+	prd   product.Info
+	batch *productBatch
+}
+
+func (r *productResolver) ID() graphql.ID            { return graphql.ID(r.prd.ID) }
+func (r *productResolver) Name() string              { return r.prd.Name }
+func (r *productResolver) Cost() long                { return long(r.prd.Cost) }
+func (r *productResolver) Quantity() long            { return long(r.prd.Quantity) }
+func (r *productResolver) Sold() long                { return long(r.prd.Sold) }
+func (r *productResolver) Revenue() long             { return long(r.prd.Revenue) }
+func (r *productResolver) DateCreated() graphql.Time { return graphql.Time{Time: r.prd.DateCreated} }
+func (r *productResolver) DateUpdated() graphql.Time { return graphql.Time{Time: r.prd.DateUpdated} }
+
+// Owner resolves the public profile of the user who created the product.
+func (r *productResolver) Owner(ctx context.Context) (*ownerResolver, error) {
+	owners, err := r.batch.loadOwners(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query owners")
+	}
+
+	p, ok := owners[r.prd.UserID]
+	if !ok {
+		return nil, nil
+	}
+	return &ownerResolver{profile: p}, nil
+}
+
+// Sales resolves the sales of the product.
+func (r *productResolver) Sales(ctx context.Context) ([]*saleResolver, error) {
+	sales, err := r.batch.loadSales(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query sales")
+	}
+
+	list := sales[r.prd.ID]
+	resolvers := make([]*saleResolver, len(list))
+	for i, sl := range list {
+		resolvers[i] = &saleResolver{sale: sl}
+	}
+	return resolvers, nil
+}
+
+// userResolver resolves the fields of the User type.
+type userResolver struct {
+	usr user.Info
+}
+
+func (r *userResolver) ID() graphql.ID            { return graphql.ID(r.usr.ID) }
+func (r *userResolver) Name() string              { return r.usr.Name }
+func (r *userResolver) Email() string             { return r.usr.Email }
+func (r *userResolver) Roles() []string           { return r.usr.Roles }
+func (r *userResolver) DateCreated() graphql.Time { return graphql.Time{Time: r.usr.DateCreated} }
+func (r *userResolver) DateUpdated() graphql.Time { return graphql.Time{Time: r.usr.DateUpdated} }
+
+// ownerResolver resolves the fields of the Owner type.
+type ownerResolver struct {
+	profile user.Profile
+}
+
+func (r *ownerResolver) ID() graphql.ID { return graphql.ID(r.profile.ID) }
+func (r *ownerResolver) Name() string   { return r.profile.Name }
+
+// saleResolver resolves the fields of the Sale type.
+type saleResolver struct {
+	sale sale.Info
+}
+
+func (r *saleResolver) ID() graphql.ID            { return graphql.ID(r.sale.ID) }
+func (r *saleResolver) Quantity() long            { return long(r.sale.Quantity) }
+func (r *saleResolver) Paid() long                { return long(r.sale.Paid) }
+func (r *saleResolver) DateCreated() graphql.Time { return graphql.Time{Time: r.sale.DateCreated} }
@@ -3,23 +3,27 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/tullo/service/business/auth"
-	"github.com/tullo/service/business/data"
 	"github.com/tullo/service/business/data/product"
 	"github.com/tullo/service/business/data/sale"
+	"github.com/tullo/service/business/data/user"
 	"github.com/tullo/service/foundation/web"
 	"go.opentelemetry.io/otel"
 )
 
 // productGroup represents the Product API method handler set.
 type productGroup struct {
-	product product.Store
-	sale    sale.Store
+	log          *slog.Logger
+	product      product.Store
+	sale         sale.Store
+	user         user.Store
+	writeTimeout time.Duration
 }
 
 // Query gets all existing products in the system.
@@ -43,12 +47,73 @@ func (pg productGroup) query(ctx context.Context, w http.ResponseWriter, r *http
 		return web.NewRequestError(fmt.Errorf("invalid rows format: %s", rows), http.StatusBadRequest)
 	}
 
-	products, err := pg.product.Query(ctx, v.TraceID, pageNumber, rowsPerPage)
+	pv, err := parseProductView(r)
+	if err != nil {
+		return err
+	}
+
+	products, err := pg.queryPage(ctx, v.TraceID, pv, pageNumber, rowsPerPage)
+	if err != nil {
+		return errors.Wrap(err, "unable to query products")
+	}
+
+	if pv.plain() {
+		return web.Respond(ctx, w, products, http.StatusOK)
+	}
+
+	docs, err := pg.shape(ctx, v.TraceID, pv, products)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, docs, http.StatusOK)
+}
+
+// List returns a page of the products in the system wrapped in an envelope
+// with pagination metadata. The products are only counted on request.
+func (pg productGroup) list(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.product.list")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	pq, err := web.ParsePage(r)
+	if err != nil {
+		return err
+	}
+
+	pv, err := parseProductView(r)
+	if err != nil {
+		return err
+	}
+
+	products, err := pg.queryPage(ctx, v.TraceID, pv, pq.Page, pq.RowsPerPage)
 	if err != nil {
 		return errors.Wrap(err, "unable to query products")
 	}
 
-	return web.Respond(ctx, w, products, http.StatusOK)
+	var total *int
+	if pq.Count {
+		count, err := pg.product.Count(ctx, v.TraceID)
+		if err != nil {
+			return errors.Wrap(err, "unable to count products")
+		}
+		total = &count
+	}
+
+	if pv.plain() {
+		return web.Respond(ctx, w, web.NewPage(r, pq, products, len(products), total), http.StatusOK)
+	}
+
+	docs, err := pg.shape(ctx, v.TraceID, pv, products)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, web.NewPage(r, pq, docs, len(products), total), http.StatusOK)
 }
 
 // QueryByID returns the specified product from the system.
@@ -62,20 +127,31 @@ func (pg productGroup) queryByID(ctx context.Context, w http.ResponseWriter, r *
 		return web.NewShutdownError("web value missing from context")
 	}
 
+	pv, err := parseProductView(r)
+	if err != nil {
+		return err
+	}
+
 	id := web.Param(r, "id")
-	prod, err := pg.product.QueryByID(ctx, v.TraceID, id)
-	if err != nil {
-		switch err {
-		case data.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
-		case data.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
-		default:
-			return errors.Wrapf(err, "ID: %s", id)
-		}
+	queryByID := pg.product.QueryByID
+	if !pv.totals() {
+		queryByID = pg.product.QueryByIDWithoutTotals
+	}
+	prod, err := queryByID(ctx, v.TraceID, id)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", id)
 	}
 
-	return web.Respond(ctx, w, prod, http.StatusOK)
+	if pv.plain() {
+		return web.Respond(ctx, w, prod, http.StatusOK)
+	}
+
+	docs, err := pg.shape(ctx, v.TraceID, pv, []product.Info{prod})
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, docs[0], http.StatusOK)
 }
 
 // Create decodes the body of a request to create a new product. The full
@@ -132,16 +208,43 @@ func (pg productGroup) update(ctx context.Context, w http.ResponseWriter, r *htt
 
 	id := web.Param(r, "id")
 	if err := pg.product.Update(ctx, v.TraceID, claims, id, up, v.Now); err != nil {
-		switch err {
-		case data.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
-		case data.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
-		case data.ErrForbidden:
-			return web.NewRequestError(err, http.StatusForbidden)
-		default:
-			return errors.Wrapf(err, "ID: %q Product: %+v", id, up)
-		}
+		return errors.Wrapf(err, "ID: %q Product: %+v", id, up)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Patch applies a JSON Merge Patch or JSON Patch document in the body of the
+// request to an existing product. The ID of the product is part of the
+// request URL.
+func (pg productGroup) patch(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.product.patch")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	id := web.Param(r, "id")
+	prd, err := pg.product.QueryByID(ctx, v.TraceID, id)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", id)
+	}
+
+	pp := product.NewPatchProduct(prd)
+	if err := web.Patch(r, &pp); err != nil {
+		return errors.Wrap(err, "patching product")
+	}
+
+	if err := pg.product.Update(ctx, v.TraceID, claims, id, pp.Update(), v.Now); err != nil {
+		return errors.Wrapf(err, "ID: %q Product: %+v", id, pp)
 	}
 
 	return web.Respond(ctx, w, nil, http.StatusNoContent)
@@ -165,12 +268,7 @@ func (pg productGroup) delete(ctx context.Context, w http.ResponseWriter, r *htt
 
 	id := web.Param(r, "id")
 	if err := pg.product.Delete(ctx, v.TraceID, claims, id); err != nil {
-		switch err {
-		case data.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
-		default:
-			return errors.Wrapf(err, "ID: %s", id)
-		}
+		return errors.Wrapf(err, "ID: %s", id)
 	}
 
 	return web.Respond(ctx, w, nil, http.StatusNoContent)
@@ -221,3 +319,247 @@ func (pg productGroup) querySales(ctx context.Context, w http.ResponseWriter, r
 
 	return web.Respond(ctx, w, list, http.StatusOK)
 }
+
+// ListSales returns a page of the sales of a product wrapped in an envelope
+// with pagination metadata. The sales are only counted on request.
+func (pg productGroup) listSales(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.product.listSales")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	pq, err := web.ParsePage(r)
+	if err != nil {
+		return err
+	}
+
+	id := web.Param(r, "id")
+	sales, err := pg.sale.Query(ctx, v.TraceID, id, pq.Page, pq.RowsPerPage)
+	if err != nil {
+		return errors.Wrap(err, "getting sales page")
+	}
+
+	var total *int
+	if pq.Count {
+		count, err := pg.sale.Count(ctx, v.TraceID, id)
+		if err != nil {
+			return errors.Wrap(err, "counting sales")
+		}
+		total = &count
+	}
+
+	return web.Respond(ctx, w, web.NewPage(r, pq, sales, len(sales), total), http.StatusOK)
+}
+
+// Export streams all products in the system as newline delimited JSON. The
+// query is stopped when the client disconnects.
+func (pg productGroup) export(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.product.export")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	es := exportStream{ctx: ctx, w: w, writeTimeout: pg.writeTimeout}
+	defer es.close()
+
+	send := func(prd product.Info) error {
+		return es.send(prd)
+	}
+	if err := pg.product.Export(ctx, v.TraceID, send); err != nil {
+
+		// There is nobody left to report the error to.
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Until a row is sent the error can still be reported.
+		if es.stream == nil {
+			return errors.Wrap(err, "unable to export products")
+		}
+
+		// The rows sent can't be followed by an error response, so the
+		// stream just ends.
+		pg.log.ErrorContext(ctx, "exporting products", "error", err)
+		return nil
+	}
+
+	// An empty export is still a stream.
+	es.open()
+	return nil
+}
+
+// ExportSales streams all sales in the system as newline delimited JSON. The
+// query is stopped when the client disconnects.
+func (pg productGroup) exportSales(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.product.exportSales")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	es := exportStream{ctx: ctx, w: w, writeTimeout: pg.writeTimeout}
+	defer es.close()
+
+	send := func(s sale.Info) error {
+		return es.send(s)
+	}
+	if err := pg.sale.Export(ctx, v.TraceID, send); err != nil {
+
+		// There is nobody left to report the error to.
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Until a row is sent the error can still be reported.
+		if es.stream == nil {
+			return errors.Wrap(err, "unable to export sales")
+		}
+
+		// The rows sent can't be followed by an error response, so the
+		// stream just ends.
+		pg.log.ErrorContext(ctx, "exporting sales", "error", err)
+		return nil
+	}
+
+	// An empty export is still a stream.
+	es.open()
+	return nil
+}
+
+// exportStream writes the rows of an export to the client. The stream is
+// only opened with its first row, so the status isn't committed before the
+// query succeeded.
+type exportStream struct {
+	ctx          context.Context
+	w            http.ResponseWriter
+	writeTimeout time.Duration
+	stream       *web.Stream
+}
+
+// open writes the response headers, once.
+func (es *exportStream) open() {
+	if es.stream == nil {
+		es.stream = web.NewStream(es.ctx, es.w, http.StatusOK, es.writeTimeout)
+	}
+}
+
+// send writes a row, opening the stream first.
+func (es *exportStream) send(val interface{}) error {
+	es.open()
+	return es.stream.Send(val)
+}
+
+// close ends the stream when it was opened.
+func (es *exportStream) close() {
+	if es.stream != nil {
+		es.stream.Close()
+	}
+}
+
+// =============================================================================
+
+// Relations of a product which can be embedded in responses.
+const (
+	includeSales = "sales"
+	includeOwner = "owner"
+)
+
+// productView holds the sparse fieldset and the embedded relations requested
+// for product responses.
+type productView struct {
+	fields   web.Fieldset
+	includes web.Fieldset
+}
+
+// parseProductView reads the fields and include query parameters of a
+// request for products.
+func parseProductView(r *http.Request) (productView, error) {
+	fields, err := web.ParseFields(r, product.Info{})
+	if err != nil {
+		return productView{}, err
+	}
+	includes, err := web.ParseIncludes(r, includeSales, includeOwner)
+	if err != nil {
+		return productView{}, err
+	}
+	return productView{fields: fields, includes: includes}, nil
+}
+
+// plain reports whether the products are returned as they are.
+func (pv productView) plain() bool {
+	return pv.fields == nil && len(pv.includes) == 0
+}
+
+// totals reports whether the fields aggregated from the sales are needed.
+func (pv productView) totals() bool {
+	return pv.fields.Any("sold", "revenue")
+}
+
+// queryPage gets a page of products, skipping the aggregation of the sales
+// when the totals are not needed.
+func (pg productGroup) queryPage(ctx context.Context, traceID string, pv productView, pageNumber int, rowsPerPage int) ([]product.Info, error) {
+	if !pv.totals() {
+		return pg.product.QueryWithoutTotals(ctx, traceID, pageNumber, rowsPerPage)
+	}
+	return pg.product.Query(ctx, traceID, pageNumber, rowsPerPage)
+}
+
+// shape trims the products to the requested fields and embeds the requested
+// relations. The relations of all products are fetched at once.
+func (pg productGroup) shape(ctx context.Context, traceID string, pv productView, products []product.Info) ([]map[string]interface{}, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.product.shape")
+	defer span.End()
+
+	docs := web.Sparse(products, pv.fields).([]map[string]interface{})
+	if len(products) == 0 {
+		return docs, nil
+	}
+
+	if pv.includes.Has(includeSales) {
+		ids := make([]string, len(products))
+		for i, prd := range products {
+			ids[i] = prd.ID
+		}
+		sales, err := pg.sale.QueryByProducts(ctx, traceID, ids)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to query sales")
+		}
+		for i, prd := range products {
+			list := sales[prd.ID]
+			if list == nil {
+				list = []sale.Info{}
+			}
+			docs[i][includeSales] = list
+		}
+	}
+
+	if pv.includes.Has(includeOwner) {
+		ids := make([]string, len(products))
+		for i, prd := range products {
+			ids[i] = prd.UserID
+		}
+		profiles, err := pg.user.QueryProfiles(ctx, traceID, ids)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to query owners")
+		}
+		for i, prd := range products {
+			var owner *user.Profile
+			if p, ok := profiles[prd.UserID]; ok {
+				owner = &p
+			}
+			docs[i][includeOwner] = owner
+		}
+	}
+
+	return docs, nil
+}
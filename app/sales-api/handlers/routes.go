@@ -1,28 +1,69 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/data/idempotency"
 	"github.com/tullo/service/business/data/product"
 	"github.com/tullo/service/business/data/sale"
 	"github.com/tullo/service/business/data/user"
+	"github.com/tullo/service/business/data/webhook"
+	"github.com/tullo/service/business/event"
 	"github.com/tullo/service/business/mid"
 	"github.com/tullo/service/foundation/database"
+	"github.com/tullo/service/foundation/logger"
 	"github.com/tullo/service/foundation/web"
 )
 
-// API constructs an http.Handler with all application routes defined.
-func API(build string, shutdown chan os.Signal, log *log.Logger, db *database.DB, a *auth.Auth) http.Handler {
+// APIConfig contains all the mandatory systems required by handlers.
+type APIConfig struct {
+	Build          string
+	Shutdown       chan os.Signal
+	Log            *slog.Logger
+	Levels         *logger.Levels
+	DebugKey       string
+	DB             *database.DB
+	Auth           *auth.Auth
+	IdempotencyTTL time.Duration
+	LegacyErrors   bool
+	MaxBodyBytes   int64
+	WriteTimeout   time.Duration
+	Events         *event.Broker
+	Metrics        prometheus.Registerer
+}
+
+// defaultEventBufferSize is the number of events buffered for resuming event
+// streams when no broker is configured.
+const defaultEventBufferSize = 1024
+
+// maxSaleBodyBytes limits the size of new sale documents.
+const maxSaleBodyBytes = 1 << 10
+
+// API constructs an http.Handler with all application routes defined. The
+// loggers of the web, auth and stores components follow their levels. The
+// request metrics are registered with Metrics when set.
+func API(cfg APIConfig) http.Handler {
+	db, a := cfg.DB, cfg.Auth
+	log := cfg.Levels.Logger(cfg.Log, "web")
+	authLog := cfg.Levels.Logger(cfg.Log, "auth")
+	storeLog := cfg.Levels.Logger(cfg.Log, "stores")
+
+	events := cfg.Events
+	if events == nil {
+		events = event.NewBroker(defaultEventBufferSize)
+	}
 
 	// Construct the web.App which holds all routes as well as common Middleware.
-	app := web.NewApp(shutdown, mid.Logger(log), mid.Errors(log), mid.Metrics(), mid.Panics(log))
+	app := web.NewApp(cfg.Shutdown, mid.DebugLog(cfg.DebugKey), web.MaxBodyBytes(cfg.MaxBodyBytes), mid.Logger(log), mid.Metrics(cfg.Metrics), mid.Errors(log, cfg.LegacyErrors), mid.Panics(log))
 
 	// Register debug check endpoints. This routes are not authenticated.
 	cg := checkGroup{
-		build: build,
+		build: cfg.Build,
 		db:    db,
 		log:   log,
 	}
@@ -30,33 +71,80 @@ func API(build string, shutdown chan os.Signal, log *log.Logger, db *database.DB
 	app.HandleDebug(http.MethodGet, "/readiness", cg.readiness)
 	app.HandleDebug(http.MethodGet, "/liveness", cg.liveness)
 
+	// Replays stored responses for POST requests carrying an Idempotency-Key.
+	idem := mid.Idempotency(log, idempotency.NewStore(storeLog, db), cfg.IdempotencyTTL)
+
 	// Register user management and authentication endpoints.
 	ug := userGroup{
-		user: user.NewStore(log, db),
+		user: user.NewStore(storeLog, db),
 		auth: a,
 	}
 
-	app.Handle(http.MethodGet, "/v1/users/{page}/{rows}", ug.query, mid.Authenticate(a), mid.Authorize(auth.RoleAdmin))
-	app.Handle(http.MethodGet, "/v1/users/{id}", ug.queryByID, mid.Authenticate(a))
-	app.Handle(http.MethodPut, "/v1/users/{id}", ug.update, mid.Authenticate(a), mid.Authorize(auth.RoleAdmin))
-	app.Handle(http.MethodPost, "/v1/users", ug.create, mid.Authenticate(a), mid.Authorize(auth.RoleAdmin))
-	app.Handle(http.MethodDelete, "/v1/users/{id}", ug.delete, mid.Authenticate(a), mid.Authorize(auth.RoleAdmin))
+	app.Handle(http.MethodGet, "/v1/users/{page}/{rows}", ug.query, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin))
+	app.Handle(http.MethodGet, "/v1/users/{id}", ug.queryByID, mid.Authenticate(authLog, a))
+	app.Handle(http.MethodPut, "/v1/users/{id}", ug.update, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin))
+	app.Handle(http.MethodPatch, "/v1/users/{id}", ug.patch, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin))
+	app.Handle(http.MethodPost, "/v1/users", ug.create, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin), idem)
+	app.Handle(http.MethodDelete, "/v1/users/{id}", ug.delete, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin))
 	// This route is not authenticated
 	app.Handle(http.MethodGet, "/v1/users/token/{kid}", ug.token)
 
 	// Register product and sale endpoints.
 	pg := productGroup{
-		product: product.NewStore(log, db),
-		sale:    sale.NewStore(log, db),
+		log:          log,
+		product:      product.NewStore(storeLog, db, events.Notify),
+		sale:         sale.NewStore(storeLog, db, events.Notify),
+		user:         user.NewStore(storeLog, db),
+		writeTimeout: cfg.WriteTimeout,
+	}
+	app.Handle(http.MethodGet, "/v1/products/{page}/{rows}", pg.query, mid.Authenticate(authLog, a))
+	app.Handle(http.MethodGet, "/v1/products/export", pg.export, mid.Authenticate(authLog, a))
+	app.Handle(http.MethodPost, "/v1/products", pg.create, mid.Authenticate(authLog, a), idem)
+	app.Handle(http.MethodGet, "/v1/products/{id}", pg.queryByID, mid.Authenticate(authLog, a))
+	app.Handle(http.MethodPut, "/v1/products/{id}", pg.update, mid.Authenticate(authLog, a))
+	app.Handle(http.MethodPatch, "/v1/products/{id}", pg.patch, mid.Authenticate(authLog, a))
+	app.Handle(http.MethodDelete, "/v1/products/{id}", pg.delete, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin))
+
+	app.Handle(http.MethodPost, "/v1/products/{id}/sales", pg.addSale, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin), web.MaxBodyBytes(maxSaleBodyBytes), idem)
+	app.Handle(http.MethodGet, "/v1/products/{id}/sales", pg.querySales, mid.Authenticate(authLog, a))
+	app.Handle(http.MethodGet, "/v1/sales/export", pg.exportSales, mid.Authenticate(authLog, a))
+
+	// Register the list endpoints returning pages wrapped in an envelope.
+	app.Handle(http.MethodGet, "/v2/users", ug.list, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin))
+	app.Handle(http.MethodGet, "/v2/products", pg.list, mid.Authenticate(authLog, a))
+	app.Handle(http.MethodGet, "/v2/products/{id}/sales", pg.listSales, mid.Authenticate(authLog, a))
+
+	// Register the stream of sale and inventory changes.
+	eg := eventGroup{
+		events:       events,
+		writeTimeout: cfg.WriteTimeout,
+	}
+	app.Handle(http.MethodGet, "/v1/events", eg.stream, mid.Authenticate(authLog, a))
+
+	// Register the webhook endpoints and their deliveries. The registration
+	// isn't idempotent, its response holds the one-time secret.
+	wg := webhookGroup{
+		webhook: webhook.NewStore(storeLog, db),
+	}
+	app.Handle(http.MethodPost, "/v1/webhooks", wg.create, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin))
+	app.Handle(http.MethodGet, "/v1/webhooks", wg.query, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin))
+	app.Handle(http.MethodDelete, "/v1/webhooks/{id}", wg.delete, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin))
+	app.Handle(http.MethodGet, "/v1/webhooks/deliveries", wg.listDeliveries, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin))
+	app.Handle(http.MethodPost, "/v1/webhooks/deliveries/{id}/replay", wg.replay, mid.Authenticate(authLog, a), mid.Authorize(auth.RoleAdmin))
+
+	// Register the GraphQL endpoint over the user, product and sale stores.
+	gg := newGraphQLGroup(log, ug.user, pg.product, pg.sale)
+	app.Handle(http.MethodPost, "/v1/graphql", gg.query, mid.Authenticate(authLog, a))
+
+	// Register the OpenAPI document of the API and the Swagger UI. These
+	// routes are not authenticated.
+	dg := docsGroup{
+		app:   app,
+		build: cfg.Build,
 	}
-	app.Handle(http.MethodGet, "/v1/products/{page}/{rows}", pg.query, mid.Authenticate(a))
-	app.Handle(http.MethodPost, "/v1/products", pg.create, mid.Authenticate(a))
-	app.Handle(http.MethodGet, "/v1/products/{id}", pg.queryByID, mid.Authenticate(a))
-	app.Handle(http.MethodPut, "/v1/products/{id}", pg.update, mid.Authenticate(a))
-	app.Handle(http.MethodDelete, "/v1/products/{id}", pg.delete, mid.Authenticate(a), mid.Authorize(auth.RoleAdmin))
-
-	app.Handle(http.MethodPost, "/v1/products/{id}/sales", pg.addSale, mid.Authenticate(a), mid.Authorize(auth.RoleAdmin))
-	app.Handle(http.MethodGet, "/v1/products/{id}/sales", pg.querySales, mid.Authenticate(a))
+	app.Handle(http.MethodGet, "/v1/openapi.json", dg.openAPI)
+	app.HandleDebug(http.MethodGet, "/docs/", dg.ui)
+	document(app)
 
 	return app
 }
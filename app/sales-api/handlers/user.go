@@ -8,7 +8,6 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/tullo/service/business/auth"
-	"github.com/tullo/service/business/data"
 	"github.com/tullo/service/business/data/user"
 	"github.com/tullo/service/foundation/web"
 	"go.opentelemetry.io/otel"
@@ -49,6 +48,39 @@ func (ug userGroup) query(ctx context.Context, w http.ResponseWriter, r *http.Re
 	return web.Respond(ctx, w, users, http.StatusOK)
 }
 
+// List returns a page of the users in the system wrapped in an envelope
+// with pagination metadata. The users are only counted on request.
+func (ug userGroup) list(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.user.list")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	pq, err := web.ParsePage(r)
+	if err != nil {
+		return err
+	}
+
+	users, err := ug.user.Query(ctx, v.TraceID, pq.Page, pq.RowsPerPage)
+	if err != nil {
+		return errors.Wrap(err, "unable to query for users")
+	}
+
+	var total *int
+	if pq.Count {
+		count, err := ug.user.Count(ctx, v.TraceID)
+		if err != nil {
+			return errors.Wrap(err, "unable to count users")
+		}
+		total = &count
+	}
+
+	return web.Respond(ctx, w, web.NewPage(r, pq, users, len(users), total), http.StatusOK)
+}
+
 // QueryByID returns the specified user from the system.
 func (ug userGroup) queryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	ctx, span := otel.Tracer(name).Start(ctx, "handlers.user.queryByID")
@@ -67,16 +99,7 @@ func (ug userGroup) queryByID(ctx context.Context, w http.ResponseWriter, r *htt
 	id := web.Param(r, "id")
 	usr, err := ug.user.QueryByID(ctx, v.TraceID, claims, id)
 	if err != nil {
-		switch err {
-		case data.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
-		case data.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
-		case data.ErrForbidden:
-			return web.NewRequestError(err, http.StatusForbidden)
-		default:
-			return errors.Wrapf(err, "ID: %s", id)
-		}
+		return errors.Wrapf(err, "ID: %s", id)
 	}
 
 	return web.Respond(ctx, w, usr, http.StatusOK)
@@ -130,16 +153,42 @@ func (ug userGroup) update(ctx context.Context, w http.ResponseWriter, r *http.R
 	id := web.Param(r, "id")
 	err := ug.user.Update(ctx, v.TraceID, claims, id, upd, v.Now)
 	if err != nil {
-		switch err {
-		case data.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
-		case data.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
-		case data.ErrForbidden:
-			return web.NewRequestError(err, http.StatusForbidden)
-		default:
-			return errors.Wrapf(err, "ID: %s  User: %+v", id, &upd)
-		}
+		return errors.Wrapf(err, "ID: %s  User: %+v", id, &upd)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Patch applies a JSON Merge Patch or JSON Patch document in the body of the
+// request to an existing user.
+func (ug userGroup) patch(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.user.patch")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return errors.New("claims missing from context")
+	}
+
+	id := web.Param(r, "id")
+	usr, err := ug.user.QueryByID(ctx, v.TraceID, claims, id)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", id)
+	}
+
+	pu := user.NewPatchUser(usr)
+	if err := web.Patch(r, &pu); err != nil {
+		return errors.Wrap(err, "patching user")
+	}
+
+	if err := ug.user.Update(ctx, v.TraceID, claims, id, pu.Update(), v.Now); err != nil {
+		return errors.Wrapf(err, "ID: %s  User: %+v", id, &pu)
 	}
 
 	return web.Respond(ctx, w, nil, http.StatusNoContent)
@@ -164,16 +213,7 @@ func (ug userGroup) delete(ctx context.Context, w http.ResponseWriter, r *http.R
 	id := web.Param(r, "id")
 	err := ug.user.Delete(ctx, v.TraceID, claims, id)
 	if err != nil {
-		switch err {
-		case data.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
-		case data.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
-		case data.ErrForbidden:
-			return web.NewRequestError(err, http.StatusForbidden)
-		default:
-			return errors.Wrapf(err, "ID: %s", id)
-		}
+		return errors.Wrapf(err, "ID: %s", id)
 	}
 
 	return web.Respond(ctx, w, nil, http.StatusNoContent)
@@ -199,12 +239,7 @@ func (ug userGroup) token(ctx context.Context, w http.ResponseWriter, r *http.Re
 
 	claims, err := ug.user.Authenticate(ctx, v.TraceID, v.Now, email, pass)
 	if err != nil {
-		switch err {
-		case data.ErrAuthenticationFailure:
-			return web.NewRequestError(err, http.StatusUnauthorized)
-		default:
-			return errors.Wrap(err, "authenticating")
-		}
+		return errors.Wrap(err, "authenticating")
 	}
 
 	kid := web.Param(r, "kid")
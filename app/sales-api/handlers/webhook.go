@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/business/data/webhook"
+	"github.com/tullo/service/foundation/web"
+	"go.opentelemetry.io/otel"
+)
+
+// webhookGroup represents the Webhook API method handler set.
+type webhookGroup struct {
+	webhook webhook.Store
+}
+
+// Create registers an endpoint events are delivered to. The response holds
+// the secret of the signatures, which isn't returned again.
+func (wg webhookGroup) create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.webhook.create")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var ne webhook.NewEndpoint
+	if err := web.Decode(r, &ne); err != nil {
+		return errors.Wrap(err, "decoding new endpoint")
+	}
+
+	ep, err := wg.webhook.CreateEndpoint(ctx, v.TraceID, ne, v.Now)
+	if err != nil {
+		return errors.Wrapf(err, "creating new endpoint: %+v", ne.URL)
+	}
+
+	return web.Respond(ctx, w, ep, http.StatusCreated)
+}
+
+// Query returns the registered endpoints.
+func (wg webhookGroup) query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.webhook.query")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	endpoints, err := wg.webhook.QueryEndpoints(ctx, v.TraceID)
+	if err != nil {
+		return errors.Wrap(err, "unable to query for endpoints")
+	}
+
+	return web.Respond(ctx, w, endpoints, http.StatusOK)
+}
+
+// Delete removes an endpoint together with its deliveries.
+func (wg webhookGroup) delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.webhook.delete")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := wg.webhook.DeleteEndpoint(ctx, v.TraceID, id); err != nil {
+		return errors.Wrapf(err, "ID: %s", id)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// ListDeliveries returns a page of the deliveries, optionally with a status,
+// wrapped in an envelope with pagination metadata.
+func (wg webhookGroup) listDeliveries(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.webhook.listDeliveries")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	pq, err := web.ParsePage(r)
+	if err != nil {
+		return err
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", webhook.StatusPending, webhook.StatusDelivered, webhook.StatusFailed:
+	default:
+		return web.NewRequestError(fmt.Errorf("invalid status: %s", status), http.StatusBadRequest)
+	}
+
+	deliveries, err := wg.webhook.QueryDeliveries(ctx, v.TraceID, status, pq.Page, pq.RowsPerPage)
+	if err != nil {
+		return errors.Wrap(err, "unable to query for deliveries")
+	}
+
+	var total *int
+	if pq.Count {
+		count, err := wg.webhook.CountDeliveries(ctx, v.TraceID, status)
+		if err != nil {
+			return errors.Wrap(err, "unable to count deliveries")
+		}
+		total = &count
+	}
+
+	return web.Respond(ctx, w, web.NewPage(r, pq, deliveries, len(deliveries), total), http.StatusOK)
+}
+
+// Replay queues a failed delivery to be attempted again.
+func (wg webhookGroup) replay(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "handlers.webhook.replay")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	d, err := wg.webhook.Replay(ctx, v.TraceID, id, v.Now)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", id)
+	}
+
+	return web.Respond(ctx, w, d, http.StatusAccepted)
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	stderrors "errors"
 	"expvar" // Register the expvar handlers
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	_ "net/http/pprof" // Register the pprof handlers
 	"os"
@@ -13,13 +15,21 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/tullo/conf"
 	"github.com/tullo/service/app/sales-api/handlers"
+	"github.com/tullo/service/app/sales-api/rpc"
 	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/data/webhook"
+	"github.com/tullo/service/business/event"
 	"github.com/tullo/service/foundation/config"
 	"github.com/tullo/service/foundation/database"
 	"github.com/tullo/service/foundation/keystore"
+	"github.com/tullo/service/foundation/logger"
 	"github.com/tullo/service/foundation/tracer"
+	"google.golang.org/grpc"
 )
 
 /*
@@ -35,30 +45,50 @@ var build = "develop"
 type deps struct {
 	auth    *auth.Auth
 	db      *database.DB
+	levels  *logger.Levels
+	metrics *prometheus.Registry
+	events  *event.Broker
 	cfg     *config.AppConfig
-	log     *log.Logger
+	log     *slog.Logger
 	srverr  chan error
 	srvdown chan os.Signal
 }
 
 func main() {
-	log := log.New(os.Stdout, "SALES : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
 
-	if err := run(log); err != nil {
-		log.Println("main: error:", err)
+	// The level is set once the configuration is parsed.
+	var level slog.LevelVar
+	log := logger.New(os.Stdout, "sales-api", &level)
+
+	if err := run(log, &level); err != nil {
+		log.Error("startup", "error", err)
 		os.Exit(1)
 	}
 }
 
-func run(log *log.Logger) error {
+func run(log *slog.Logger, level *slog.LevelVar) error {
 
 	// Print the build version for our logs.
-	log.Printf("main: Application initializing : version %q", build)
-	defer log.Println("main: Completed")
+	log.Info("startup", "status", "initializing application", "version", build)
+	defer log.Info("shutdown", "status", "completed")
 
 	// Expose the build version under /debug/vars.
 	expvar.NewString("build").Set(build)
 
+	// Collect the Go runtime and process metrics and the build information
+	// for /metrics.
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(collectors.WithGoCollectorRuntimeMetrics(collectors.MetricsAll)),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewBuildInfoCollector(),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "sales_api_build_info",
+			Help:        "Build version of the sales-api, always 1.",
+			ConstLabels: prometheus.Labels{"version": build},
+		}, func() float64 { return 1 }),
+	)
+
 	var err error
 
 	// =========================================================================
@@ -85,11 +115,21 @@ func run(log *log.Logger) error {
 		return errors.Wrap(err, "parsing config")
 	}
 
+	lvl, err := logger.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return errors.Wrap(err, "parsing config")
+	}
+	level.Set(lvl)
+
+	// The levels of the components can be changed at runtime on the debug
+	// service.
+	levels := logger.NewLevels(lvl, "web", "database", "auth", "stores")
+
 	out, err := conf.String(&cfg)
 	if err != nil {
 		return errors.Wrap(err, "generating output for config")
 	}
-	log.Printf("main: Config :\n%v\n", out)
+	log.Info("startup", "config", out)
 
 	// =========================================================================
 	// Initialize authentication support
@@ -102,37 +142,49 @@ func run(log *log.Logger) error {
 	// =========================================================================
 	// Start Database Support
 
-	log.Println("main: Initializing database support")
+	log.Info("startup", "status", "initializing database support", "host", cfg.DB.Host)
 
 	db, err := database.Connect(
 		context.Background(),
 		database.Config{
-			User:         cfg.DB.User,
-			Password:     cfg.DB.Password,
-			Host:         cfg.DB.Host,
-			Name:         cfg.DB.Name,
-			DisableTLS:   cfg.DB.DisableTLS,
-			MaxIdleConns: cfg.DB.MaxIdleConns,
-			MaxOpenConns: cfg.DB.MaxOpenConns,
+			User:            cfg.DB.User,
+			Password:        cfg.DB.Password,
+			Host:            cfg.DB.Host,
+			Name:            cfg.DB.Name,
+			DisableTLS:      cfg.DB.DisableTLS,
+			MaxIdleConns:    cfg.DB.MaxIdleConns,
+			MaxOpenConns:    cfg.DB.MaxOpenConns,
+			Log:             levels.Logger(log, "database"),
+			TraceStatements: cfg.DB.TraceStatements,
 		})
 	if err != nil {
 		return errors.Wrap(err, "connecting to db")
 	}
 
 	defer func() {
-		log.Printf("main: Database Stopping : %s", cfg.DB.Host)
+		log.Info("shutdown", "status", "stopping database support", "host", cfg.DB.Host)
 		db.Close()
 	}()
 
+	reg.MustRegister(database.NewPoolCollector(db))
+
 	// =========================================================================
 	// Start Tracing Support
 
-	log.Println("main: Initializing zipkin tracing support")
+	log.Info("startup", "status", "initializing tracing support", "exporter", cfg.Trace.Exporter)
 
+	// The pod is described by the Kubernetes Downward API.
 	tr := tracer.Config{
-		ServiceName: cfg.Zipkin.ServiceName,
-		ReporterURI: cfg.Zipkin.ReporterURI,
-		Probability: cfg.Zipkin.Probability,
+		ServiceName:       cfg.Trace.ServiceName,
+		Version:           build,
+		Exporter:          cfg.Trace.Exporter,
+		Endpoint:          cfg.Trace.Endpoint,
+		Probability:       cfg.Trace.Probability,
+		KeepLatency:       cfg.Trace.KeepLatency,
+		MaxBufferedTraces: cfg.Trace.MaxBuffered,
+		Pod:               os.Getenv("KUBERNETES_PODNAME"),
+		Namespace:         os.Getenv("KUBERNETES_NAMESPACE"),
+		Node:              os.Getenv("KUBERNETES_NODENAME"),
 	}
 	shutdownTP, err := tracer.Init(log, &tr)
 	if err != nil {
@@ -140,16 +192,16 @@ func run(log *log.Logger) error {
 	}
 
 	defer func() {
-		log.Printf("main: TracerProvider Stopping")
+		log.Info("shutdown", "status", "stopping tracer provider")
 		if err = shutdownTP(context.Background()); err != nil {
-			log.Fatal("failed to shutdown TracerProvider: %w", err)
+			log.Error("shutdown", "status", "stopping tracer provider", "error", err)
 		}
 	}()
 
 	// =========================================================================
 	// Start Debug Service
 
-	startDebugService(log, &cfg)
+	startDebugService(log, &cfg, levels, reg)
 
 	// =========================================================================
 	// Start API Service
@@ -157,6 +209,9 @@ func run(log *log.Logger) error {
 	d := deps{
 		auth:    auth,
 		db:      db,
+		levels:  levels,
+		metrics: reg,
+		events:  event.NewBroker(cfg.Web.EventBufferSize),
 		cfg:     &cfg,
 		log:     log,
 		srverr:  nil,
@@ -164,40 +219,101 @@ func run(log *log.Logger) error {
 	}
 	api := initAPI(&d)
 
+	// End the event streams so shutdown isn't held up by them.
+	api.RegisterOnShutdown(d.events.Close)
+
 	// Start the service listening for requests.
 	go func() {
-		log.Printf("main: API listening on %s", api.Addr)
+		log.Info("startup", "status", "api router started", "host", api.Addr)
 		d.srverr <- api.ListenAndServe()
 	}()
 
+	// =========================================================================
+	// Start gRPC Service
+
+	log.Info("startup", "status", "initializing gRPC support")
+
+	lis, err := net.Listen("tcp", cfg.Web.GRPCHost)
+	if err != nil {
+		return errors.Wrap(err, "listening for gRPC")
+	}
+	grpcSrv := rpc.NewServer(rpc.Config{
+		Log:      log,
+		Levels:   levels,
+		DebugKey: cfg.Log.DebugKey,
+		DB:       db,
+		Auth:     auth,
+		Events:   d.events,
+	})
+
+	go func() {
+		log.Info("startup", "status", "gRPC server started", "host", lis.Addr().String())
+		d.srverr <- grpcSrv.Serve(lis)
+	}()
+
+	// =========================================================================
+	// Start Webhook Dispatcher
+
+	log.Info("startup", "status", "initializing webhook dispatcher")
+
+	dispatcher, err := webhook.NewDispatcher(levels.Logger(log, "stores"), db, webhook.Config{
+		Interval:     cfg.Webhooks.Interval,
+		Timeout:      cfg.Webhooks.Timeout,
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		MinBackoff:   cfg.Webhooks.MinBackoff,
+		MaxBackoff:   cfg.Webhooks.MaxBackoff,
+		AllowPrivate: cfg.Webhooks.AllowPrivate,
+	})
+	if err != nil {
+		return errors.Wrap(err, "constructing webhook dispatcher")
+	}
+
+	dctx, stopDispatcher := context.WithCancel(context.Background())
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		dispatcher.Run(dctx)
+	}()
+
+	// Stop the dispatcher before the database is closed. Deliveries cut
+	// short are attempted again once their lease ends.
+	defer func() {
+		log.Info("shutdown", "status", "stopping webhook dispatcher")
+		stopDispatcher()
+		<-dispatched
+	}()
+
 	// =========================================================================
 	// Shutdown
 
 	timeout := &cfg.Web.ShutdownTimeout
-	appShutdown(api, &d, timeout)
-
-	return nil
+	return appShutdown(api, grpcSrv, &d, timeout)
 }
 
 // startDebugService launches a goroutine serving registered debug handlers.
 //
 // /debug/pprof - handler added to the default mux by importing the net/http/pprof package.
 // /debug/vars - handler added to the default mux by importing the expvar package.
+// /debug/log/levels - reads the log levels of the components and changes them on PUT.
+// /metrics - the metrics of the registry in the Prometheus exposition format.
 //
 // Not concerned with shutting this down when the application is shutdown.
-func startDebugService(log *log.Logger, cfg *config.AppConfig) {
-	log.Println("main: Initializing debugging support")
+func startDebugService(log *slog.Logger, cfg *config.AppConfig, levels *logger.Levels, reg *prometheus.Registry) {
+	log.Info("startup", "status", "initializing debugging support")
+
+	http.Handle("/debug/log/levels", levels)
+	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
 
 	go func() {
-		log.Printf("main: Debug Listening %s", cfg.Web.DebugHost)
+		log.Info("startup", "status", "debug router started", "host", cfg.Web.DebugHost)
 		if err := http.ListenAndServe(cfg.Web.DebugHost, http.DefaultServeMux); err != nil {
-			log.Printf("main: Debug Listener closed : %v", err)
+			log.Error("shutdown", "status", "debug router closed", "host", cfg.Web.DebugHost, "error", err)
 		}
 	}()
 }
 
-func initAuthSupport(log *log.Logger, cfg *config.AppConfig) (*auth.Auth, error) {
-	log.Println("main: Initializing authentication support")
+func initAuthSupport(log *slog.Logger, cfg *config.AppConfig) (*auth.Auth, error) {
+	log.Info("startup", "status", "initializing authentication support")
 
 	// Construct a key store based on the key files stored in the specified
 	// directory.
@@ -215,7 +331,7 @@ func initAuthSupport(log *log.Logger, cfg *config.AppConfig) (*auth.Auth, error)
 }
 
 func initAPI(d *deps) *http.Server {
-	d.log.Println("main: Initializing API support")
+	d.log.Info("startup", "status", "initializing API support")
 
 	// Make a channel to listen for errors coming from the listener. Use a
 	// buffered channel so the goroutine can exit if we don't collect this error.
@@ -227,36 +343,64 @@ func initAPI(d *deps) *http.Server {
 	signal.Notify(d.srvdown, syscall.SIGINT, syscall.SIGTERM)
 
 	api := http.Server{
-		Addr:         d.cfg.Web.APIHost,
-		Handler:      handlers.API(build, d.srvdown, d.log, d.db, d.auth),
+		Addr: d.cfg.Web.APIHost,
+		Handler: handlers.API(handlers.APIConfig{
+			Build:          build,
+			Shutdown:       d.srvdown,
+			Log:            d.log,
+			Levels:         d.levels,
+			DebugKey:       d.cfg.Log.DebugKey,
+			DB:             d.db,
+			Auth:           d.auth,
+			IdempotencyTTL: d.cfg.Web.IdempotencyTTL,
+			LegacyErrors:   d.cfg.Web.LegacyErrors,
+			MaxBodyBytes:   d.cfg.Web.MaxBodyBytes,
+			WriteTimeout:   d.cfg.Web.WriteTimeout,
+			Events:         d.events,
+			Metrics:        d.metrics,
+		}),
 		ReadTimeout:  d.cfg.Web.ReadTimeout,
 		WriteTimeout: d.cfg.Web.WriteTimeout,
+		ErrorLog:     logger.StdLogger(d.levels.Logger(d.log, "web"), slog.LevelError),
 	}
 
 	return &api
 }
 
-func appShutdown(api *http.Server, deps *deps, d *time.Duration) error {
-	// Blocking main and waiting for shutdown.
+func appShutdown(api *http.Server, grpcSrv *grpc.Server, deps *deps, d *time.Duration) error {
+	var errs []error
+
+	// Blocking main and waiting for shutdown. When one of the servers
+	// failed the other one is still stopped.
 	select {
 	case err := <-deps.srverr:
-		return errors.Wrap(err, "server error")
+		errs = append(errs, errors.Wrap(err, "server error"))
+		deps.log.Error("shutdown", "status", "shutdown started", "error", err)
 
 	case sig := <-deps.srvdown:
-		deps.log.Printf("main: %v : Start shutdown", sig)
+		deps.log.Info("shutdown", "status", "shutdown started", "signal", sig.String())
+	}
 
-		// Give outstanding requests a deadline for completion.
-		ctx, cancel := context.WithTimeout(context.Background(), *d)
-		defer cancel()
+	// Give outstanding requests a deadline for completion.
+	ctx, cancel := context.WithTimeout(context.Background(), *d)
+	defer cancel()
 
-		// Asking listener to shutdown and shed load.
-		if err := api.Shutdown(ctx); err != nil {
-			api.Close()
-			return errors.Wrap(err, "could not stop server gracefully")
-		}
+	// Asking listener to shutdown and shed load.
+	if err := api.Shutdown(ctx); err != nil {
+		api.Close()
+		errs = append(errs, errors.Wrap(err, "could not stop server gracefully"))
+	}
+
+	// Asking the gRPC server to finish the outstanding calls. It's stopped
+	// once the deadline passed.
+	if err := rpc.Shutdown(ctx, grpcSrv); err != nil {
+		errs = append(errs, errors.Wrap(err, "could not stop gRPC server gracefully"))
+	}
 
-		deps.log.Printf("main: %v: Completed shutdown", sig)
+	if len(errs) > 0 {
+		return stderrors.Join(errs...)
 	}
 
+	deps.log.Info("shutdown", "status", "shutdown complete")
 	return nil
 }
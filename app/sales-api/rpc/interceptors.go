@@ -0,0 +1,226 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/data"
+	"github.com/tullo/service/foundation/i18n"
+	"github.com/tullo/service/foundation/logger"
+	"github.com/tullo/service/foundation/tracer"
+	"github.com/tullo/service/foundation/validate"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// DebugLog switches on verbose logging for calls carrying x-debug-log
+// metadata signed with the key, including the SQL statements they execute.
+// The metadata is ignored when the key is empty.
+func DebugLog(key string) grpc.UnaryServerInterceptor {
+	i := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(logger.DebugHeader); len(v) > 0 && logger.VerifyDebug(key, v[0], time.Now()) {
+				ctx = logger.WithVerbose(ctx)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+
+	return i
+}
+
+// Logger writes some information about each call to the logs: the method,
+// peer address and, once completed, the code and latency.
+func Logger(log *slog.Logger) grpc.UnaryServerInterceptor {
+	i := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := otel.Tracer(name).Start(ctx, "rpc.logger")
+		defer span.End()
+
+		var addr string
+		if p, ok := peer.FromContext(ctx); ok {
+			addr = p.Addr.String()
+		}
+
+		start := time.Now()
+		log.InfoContext(ctx, "call started",
+			"method", info.FullMethod,
+			"remote_addr", addr,
+		)
+
+		resp, err := handler(ctx, req)
+
+		log.InfoContext(ctx, "call completed",
+			"method", info.FullMethod,
+			"remote_addr", addr,
+			"code", status.Code(err).String(),
+			"latency", time.Since(start),
+		)
+
+		return resp, err
+	}
+
+	return i
+}
+
+// Errors converts errors coming out of the call chain into gRPC status
+// errors. Business errors get the code matching their HTTP status and
+// validation errors list the invalid fields as a BadRequest detail in the
+// language negotiated from the accept-language metadata. Unexpected errors
+// are logged and reported as internal errors.
+func Errors(log *slog.Logger) grpc.UnaryServerInterceptor {
+	i := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := otel.Tracer(name).Start(ctx, "rpc.errors")
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+
+		log.ErrorContext(ctx, "call failed", "error", err)
+
+		var lang string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			lang = i18n.Negotiate(strings.Join(md.Get("accept-language"), ","))
+		}
+
+		// Keep the trace of calls failing on the server side.
+		err = statusError(err, lang)
+		switch status.Code(err) {
+		case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+			tracer.Keep(ctx)
+		}
+
+		return nil, err
+	}
+
+	return i
+}
+
+// statusError returns the gRPC status error reporting an error.
+func statusError(err error, lang string) error {
+	if st, ok := status.FromError(err); ok {
+		return st.Err()
+	}
+
+	var de *data.Error
+	var ve *validate.Errors
+	switch {
+	case errors.As(err, &de):
+		_, msg := de.Translate(lang)
+		return status.Error(codeOf(de.Status), msg)
+
+	case errors.As(err, &ve):
+		br := errdetails.BadRequest{}
+		for _, f := range ve.Fields(lang) {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       f.Field,
+				Description: f.Error,
+			})
+		}
+		st := status.New(codes.InvalidArgument, ve.Summary(lang))
+		if det, err := st.WithDetails(&br); err == nil {
+			st = det
+		}
+		return st.Err()
+	}
+
+	return status.Error(codes.Internal, http.StatusText(http.StatusInternalServerError))
+}
+
+// codeOf maps the HTTP status of a business error to a gRPC code.
+func codeOf(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	}
+	return codes.Internal
+}
+
+// Panics recovers from panics and converts the panic to an error so it is
+// reported by Errors.
+func Panics(log *slog.Logger) grpc.UnaryServerInterceptor {
+	i := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx, span := otel.Tracer(name).Start(ctx, "rpc.panics")
+		defer span.End()
+
+		// Defer a function to recover from a panic and set the err return
+		// variable after the fact.
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+
+				// Log the Go stack trace for this panic'd goroutine.
+				log.ErrorContext(ctx, "call panicked", "error", err, "stack", string(debug.Stack()))
+				tracer.Keep(ctx)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+
+	return i
+}
+
+// Authenticate validates a JWT from the `authorization` metadata and adds
+// its claims to the context. Rejected tokens are logged at debug level.
+func Authenticate(log *slog.Logger, a *auth.Auth) grpc.UnaryServerInterceptor {
+	i := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := otel.Tracer(name).Start(ctx, "rpc.authenticate")
+		defer span.End()
+
+		// Expecting metadata format `Bearer <token>`.
+		var authStr string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get("authorization"); len(v) > 0 {
+				authStr = v[0]
+			}
+		}
+		parts := strings.Split(authStr, " ")
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			const msg = "expected authorization metadata format: Bearer <token>"
+			log.DebugContext(ctx, "authentication failed", "error", msg)
+			return nil, status.Error(codes.Unauthenticated, msg)
+		}
+
+		// Validate the token is signed by us.
+		claims, err := a.ValidateToken(parts[1])
+		if err != nil {
+			log.DebugContext(ctx, "authentication failed", "error", err)
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		log.DebugContext(ctx, "authenticated", "subject", claims.Subject, "roles", claims.Roles)
+
+		// Add claims to the context so they can be retrieved later.
+		ctx = context.WithValue(ctx, auth.Key, claims)
+
+		return handler(ctx, req)
+	}
+
+	return i
+}
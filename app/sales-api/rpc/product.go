@@ -0,0 +1,205 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/app/sales-api/rpc/salespb"
+	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/data/product"
+	"github.com/tullo/service/business/data/sale"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// productServer implements the ProductService.
+type productServer struct {
+	salespb.UnimplementedProductServiceServer
+	product product.Store
+}
+
+// ListProducts returns a page of the products.
+func (ps *productServer) ListProducts(ctx context.Context, req *salespb.ListProductsRequest) (*salespb.ListProductsResponse, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.product.list")
+	defer span.End()
+
+	pageNumber, rowsPerPage, err := page(req.GetPage())
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := ps.product.Query(ctx, traceID(ctx), pageNumber, rowsPerPage)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query products")
+	}
+
+	resp := salespb.ListProductsResponse{
+		Products: make([]*salespb.Product, len(products)),
+	}
+	for i, prd := range products {
+		resp.Products[i] = toProduct(prd)
+	}
+	return &resp, nil
+}
+
+// GetProduct returns a product.
+func (ps *productServer) GetProduct(ctx context.Context, req *salespb.GetProductRequest) (*salespb.Product, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.product.get")
+	defer span.End()
+
+	prd, err := ps.product.QueryByID(ctx, traceID(ctx), req.GetId())
+	if err != nil {
+		return nil, errors.Wrapf(err, "ID: %s", req.GetId())
+	}
+
+	return toProduct(prd), nil
+}
+
+// CreateProduct adds a product owned by the caller.
+func (ps *productServer) CreateProduct(ctx context.Context, req *salespb.CreateProductRequest) (*salespb.Product, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.product.create")
+	defer span.End()
+
+	claims, err := claims(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	np := product.NewProduct{
+		Name:     req.GetName(),
+		Cost:     int(req.GetCost()),
+		Quantity: int(req.GetQuantity()),
+	}
+	prd, err := ps.product.Create(ctx, traceID(ctx), claims, np, time.Now())
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating new product: %+v", np)
+	}
+
+	return toProduct(prd), nil
+}
+
+// UpdateProduct changes the fields of a product which are set.
+func (ps *productServer) UpdateProduct(ctx context.Context, req *salespb.UpdateProductRequest) (*emptypb.Empty, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.product.update")
+	defer span.End()
+
+	claims, err := claims(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var up product.UpdateProduct
+	up.Name = req.Name
+	if req.Cost != nil {
+		cost := int(req.GetCost())
+		up.Cost = &cost
+	}
+	if req.Quantity != nil {
+		quantity := int(req.GetQuantity())
+		up.Quantity = &quantity
+	}
+
+	if err := ps.product.Update(ctx, traceID(ctx), claims, req.GetId(), up, time.Now()); err != nil {
+		return nil, errors.Wrapf(err, "ID: %q Product: %+v", req.GetId(), up)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteProduct removes a product. It requires the ADMIN role.
+func (ps *productServer) DeleteProduct(ctx context.Context, req *salespb.DeleteProductRequest) (*emptypb.Empty, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.product.delete")
+	defer span.End()
+
+	claims, err := authorize(ctx, auth.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ps.product.Delete(ctx, traceID(ctx), claims, req.GetId()); err != nil {
+		return nil, errors.Wrapf(err, "ID: %s", req.GetId())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// toProduct converts a product to its message.
+func toProduct(prd product.Info) *salespb.Product {
+	return &salespb.Product{
+		Id:          prd.ID,
+		Name:        prd.Name,
+		Cost:        int64(prd.Cost),
+		Quantity:    int64(prd.Quantity),
+		Sold:        int64(prd.Sold),
+		Revenue:     int64(prd.Revenue),
+		UserId:      prd.UserID,
+		DateCreated: timestamppb.New(prd.DateCreated),
+		DateUpdated: timestamppb.New(prd.DateUpdated),
+	}
+}
+
+// =============================================================================
+
+// saleServer implements the SaleService.
+type saleServer struct {
+	salespb.UnimplementedSaleServiceServer
+	sale sale.Store
+}
+
+// AddSale records a sale of a product. It requires the ADMIN role.
+func (ss *saleServer) AddSale(ctx context.Context, req *salespb.AddSaleRequest) (*salespb.Sale, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.sale.add")
+	defer span.End()
+
+	if _, err := authorize(ctx, auth.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	ns := sale.NewSale{
+		Quantity: int(req.GetQuantity()),
+		Paid:     int(req.GetPaid()),
+	}
+	sl, err := ss.sale.AddSale(ctx, traceID(ctx), ns, req.GetProductId(), time.Now())
+	if err != nil {
+		return nil, errors.Wrap(err, "adding new sale")
+	}
+
+	return toSale(sl), nil
+}
+
+// ListSales returns a page of the sales of a product, the most recent first.
+func (ss *saleServer) ListSales(ctx context.Context, req *salespb.ListSalesRequest) (*salespb.ListSalesResponse, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.sale.list")
+	defer span.End()
+
+	pageNumber, rowsPerPage, err := page(req.GetPage())
+	if err != nil {
+		return nil, err
+	}
+
+	sales, err := ss.sale.Query(ctx, traceID(ctx), req.GetProductId(), pageNumber, rowsPerPage)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting sales page")
+	}
+
+	resp := salespb.ListSalesResponse{
+		Sales: make([]*salespb.Sale, len(sales)),
+	}
+	for i, sl := range sales {
+		resp.Sales[i] = toSale(sl)
+	}
+	return &resp, nil
+}
+
+// toSale converts a sale to its message.
+func toSale(sl sale.Info) *salespb.Sale {
+	return &salespb.Sale{
+		Id:          sl.ID,
+		ProductId:   sl.ProductID,
+		Quantity:    int64(sl.Quantity),
+		Paid:        int64(sl.Paid),
+		DateCreated: timestamppb.New(sl.DateCreated),
+	}
+}
@@ -0,0 +1,140 @@
+// Package rpc serves the products, sales and users of the sales-api over
+// gRPC. The services call the same stores as the REST handlers and
+// authenticate with the same JWTs.
+package rpc
+
+//go:generate protoc --go_out=salespb --go_opt=paths=source_relative --go-grpc_out=salespb --go-grpc_opt=paths=source_relative -I salespb sales.proto
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/tullo/service/app/sales-api/rpc/salespb"
+	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/data/outbox"
+	"github.com/tullo/service/business/data/product"
+	"github.com/tullo/service/business/data/sale"
+	"github.com/tullo/service/business/data/user"
+	"github.com/tullo/service/business/event"
+	"github.com/tullo/service/foundation/database"
+	"github.com/tullo/service/foundation/logger"
+	"github.com/tullo/service/foundation/web"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const name = "rpc"
+
+// Config holds the dependencies of the gRPC server.
+type Config struct {
+	Log      *slog.Logger
+	Levels   *logger.Levels
+	DebugKey string
+	DB       *database.DB
+	Auth     *auth.Auth
+	Events   *event.Broker
+}
+
+// NewServer constructs a gRPC server with all services registered. Trace
+// context is propagated from the metadata of incoming calls like it's from
+// the headers of HTTP requests. The loggers of the web, auth and stores
+// components follow their levels.
+func NewServer(cfg Config) *grpc.Server {
+	log := cfg.Levels.Logger(cfg.Log, "web")
+	authLog := cfg.Levels.Logger(cfg.Log, "auth")
+	storeLog := cfg.Levels.Logger(cfg.Log, "stores")
+
+	srv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			DebugLog(cfg.DebugKey),
+			Logger(log),
+			Errors(log),
+			Panics(log),
+			Authenticate(authLog, cfg.Auth),
+		),
+	)
+
+	usr := user.NewStore(storeLog, cfg.DB)
+	// The stores publish the events of the committed changes.
+	var notify outbox.Notify
+	if cfg.Events != nil {
+		notify = cfg.Events.Notify
+	}
+	prd := product.NewStore(storeLog, cfg.DB, notify)
+	sl := sale.NewStore(storeLog, cfg.DB, notify)
+
+	salespb.RegisterProductServiceServer(srv, &productServer{product: prd})
+	salespb.RegisterSaleServiceServer(srv, &saleServer{sale: sl})
+	salespb.RegisterUserServiceServer(srv, &userServer{user: usr})
+
+	return srv
+}
+
+// Shutdown stops the server gracefully. Calls still running when the context
+// is done are canceled.
+func Shutdown(ctx context.Context, srv *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		srv.Stop()
+		return ctx.Err()
+	}
+}
+
+// =============================================================================
+
+// traceID returns the id of the trace of a call.
+func traceID(ctx context.Context) string {
+	return trace.SpanFromContext(ctx).SpanContext().TraceID().String()
+}
+
+// claims returns the claims of the authenticated caller.
+func claims(ctx context.Context) (auth.Claims, error) {
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return auth.Claims{}, status.Error(codes.Unauthenticated, "claims missing from context")
+	}
+	return claims, nil
+}
+
+// authorize checks the caller has at least one of the roles.
+func authorize(ctx context.Context, roles ...string) (auth.Claims, error) {
+	c, err := claims(ctx)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+	if !c.Authorized(roles...) {
+		return auth.Claims{}, status.Errorf(codes.PermissionDenied, "you are not authorized for that action: claims: %v exp: %v", c.Roles, roles)
+	}
+	return c, nil
+}
+
+// page returns the page number and the rows per page selected by a Page
+// like the v2 lists of the REST API do.
+func page(p *salespb.Page) (int, int, error) {
+	number, rows := int(p.GetPage()), int(p.GetRows())
+	switch {
+	case number < 0:
+		return 0, 0, status.Errorf(codes.InvalidArgument, "invalid page: %d", number)
+	case number == 0:
+		number = 1
+	}
+	switch {
+	case rows < 0:
+		return 0, 0, status.Errorf(codes.InvalidArgument, "invalid rows: %d", rows)
+	case rows == 0:
+		rows = web.DefaultRowsPerPage
+	}
+	return number, min(rows, web.MaxRowsPerPage), nil
+}
@@ -0,0 +1,144 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/app/sales-api/rpc/salespb"
+	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/data/user"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// userServer implements the UserService.
+type userServer struct {
+	salespb.UnimplementedUserServiceServer
+	user user.Store
+}
+
+// ListUsers returns a page of the users. It requires the ADMIN role.
+func (us *userServer) ListUsers(ctx context.Context, req *salespb.ListUsersRequest) (*salespb.ListUsersResponse, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.user.list")
+	defer span.End()
+
+	if _, err := authorize(ctx, auth.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	pageNumber, rowsPerPage, err := page(req.GetPage())
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := us.user.Query(ctx, traceID(ctx), pageNumber, rowsPerPage)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query for users")
+	}
+
+	resp := salespb.ListUsersResponse{
+		Users: make([]*salespb.User, len(users)),
+	}
+	for i, usr := range users {
+		resp.Users[i] = toUser(usr)
+	}
+	return &resp, nil
+}
+
+// GetUser returns a user. Users other than admins can only get themselves.
+func (us *userServer) GetUser(ctx context.Context, req *salespb.GetUserRequest) (*salespb.User, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.user.get")
+	defer span.End()
+
+	claims, err := claims(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := us.user.QueryByID(ctx, traceID(ctx), claims, req.GetId())
+	if err != nil {
+		return nil, errors.Wrapf(err, "ID: %s", req.GetId())
+	}
+
+	return toUser(usr), nil
+}
+
+// CreateUser adds a user. It requires the ADMIN role.
+func (us *userServer) CreateUser(ctx context.Context, req *salespb.CreateUserRequest) (*salespb.User, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.user.create")
+	defer span.End()
+
+	if _, err := authorize(ctx, auth.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	nu := user.NewUser{
+		Name:            req.GetName(),
+		Email:           req.GetEmail(),
+		Roles:           req.GetRoles(),
+		Password:        req.GetPassword(),
+		PasswordConfirm: req.GetPasswordConfirm(),
+	}
+	usr, err := us.user.Create(ctx, traceID(ctx), nu, time.Now())
+	if err != nil {
+		return nil, errors.Wrapf(err, "User: %s", nu.Email)
+	}
+
+	return toUser(usr), nil
+}
+
+// UpdateUser changes the fields of a user which are set. It requires the
+// ADMIN role.
+func (us *userServer) UpdateUser(ctx context.Context, req *salespb.UpdateUserRequest) (*emptypb.Empty, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.user.update")
+	defer span.End()
+
+	claims, err := authorize(ctx, auth.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	uu := user.UpdateUser{
+		Name:            req.Name,
+		Email:           req.Email,
+		Roles:           req.GetRoles(),
+		Password:        req.Password,
+		PasswordConfirm: req.PasswordConfirm,
+	}
+	if err := us.user.Update(ctx, traceID(ctx), claims, req.GetId(), uu, time.Now()); err != nil {
+		return nil, errors.Wrapf(err, "ID: %s", req.GetId())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteUser removes a user. It requires the ADMIN role.
+func (us *userServer) DeleteUser(ctx context.Context, req *salespb.DeleteUserRequest) (*emptypb.Empty, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "rpc.user.delete")
+	defer span.End()
+
+	claims, err := authorize(ctx, auth.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := us.user.Delete(ctx, traceID(ctx), claims, req.GetId()); err != nil {
+		return nil, errors.Wrapf(err, "ID: %s", req.GetId())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// toUser converts a user to its message.
+func toUser(usr user.Info) *salespb.User {
+	return &salespb.User{
+		Id:          usr.ID,
+		Name:        usr.Name,
+		Email:       usr.Email,
+		Roles:       usr.Roles,
+		DateCreated: timestamppb.New(usr.DateCreated),
+		DateUpdated: timestamppb.New(usr.DateUpdated),
+	}
+}
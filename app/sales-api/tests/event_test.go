@@ -0,0 +1,274 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tullo/service/app/sales-api/handlers"
+	"github.com/tullo/service/business/data/tests"
+	"github.com/tullo/service/business/event"
+	"github.com/tullo/service/foundation/web"
+)
+
+// Seeded products, the user owning them and the admin.
+const (
+	comicBooksID    = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	mcDonaldsToysID = "72f8b983-3eb4-48db-9ed0-e45cc6bd716b"
+	userGopherID    = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+	adminGopherID   = "5cf37266-3473-4006-984f-9325122678b7"
+)
+
+// EventTests holds methods for each event stream subtest.
+type EventTests struct {
+	srv        *httptest.Server
+	adminToken string
+	userToken  string
+	lastID     string
+}
+
+// TestEvents runs a series of tests to exercise the event stream against a
+// running server, so events are flushed like they are to real clients.
+func TestEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
+	defer cancel()
+
+	test := tests.NewIntegration(t, ctx)
+	t.Cleanup(test.Teardown)
+
+	shutdown := make(chan os.Signal, 1)
+	srv := httptest.NewServer(handlers.API(handlers.APIConfig{
+		Build:          "develop",
+		Shutdown:       shutdown,
+		Log:            test.Log,
+		DB:             test.DB,
+		Auth:           test.Auth,
+		IdempotencyTTL: time.Hour,
+		WriteTimeout:   5 * time.Second,
+		Events:         event.NewBroker(16),
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := EventTests{
+		srv:        srv,
+		adminToken: test.Token("admin@example.com", "gophers"),
+		userToken:  test.Token("user@example.com", "gophers"),
+	}
+
+	t.Run("getEvents401", tests.getEvents401)
+	t.Run("streamSale", tests.streamSale)
+	t.Run("resumeStream", tests.resumeStream)
+	t.Run("streamOwnProducts", tests.streamOwnProducts)
+}
+
+// request sends a request authenticated as admin to the server.
+func (et *EventTests) request(ctx context.Context, method string, path string, body string, header http.Header) (*http.Response, error) {
+	return et.requestAs(ctx, et.adminToken, method, path, body, header)
+}
+
+// requestAs sends a request authenticated by the token to the server.
+func (et *EventTests) requestAs(ctx context.Context, token string, method string, path string, body string, header http.Header) (*http.Response, error) {
+	r, err := http.NewRequestWithContext(ctx, method, et.srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultClient.Do(r)
+}
+
+// change sends a request changing data and checks it succeeded.
+func (et *EventTests) change(t *testing.T, method string, path string, body string) {
+	resp, err := et.request(context.Background(), method, path, body, nil)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to send %s %s : %v", tests.Failed, method, path, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		t.Fatalf("\t%s\tShould be able to send %s %s : %v", tests.Failed, method, path, resp.StatusCode)
+	}
+}
+
+// next reads the next event of a stream.
+func next(sc *bufio.Scanner) (string, event.Event, error) {
+	var typ string
+	var e event.Event
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "" && typ != "":
+			return typ, e, nil
+		case strings.HasPrefix(line, "event: "):
+			typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+				return "", event.Event{}, err
+			}
+		}
+	}
+	return "", event.Event{}, sc.Err()
+}
+
+// getEvents401 validates the stream requires authentication.
+func (et *EventTests) getEvents401(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/events", nil)
+	w := httptest.NewRecorder()
+	et.srv.Config.Handler.ServeHTTP(w, r)
+
+	t.Log("Given the need to validate the event stream requires authentication.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen streaming without a token.", testID)
+		{
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 401 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 401 for the response.", tests.Success, testID)
+		}
+	}
+}
+
+// streamSale validates a sale of the product streamed is pushed while changes
+// of other products are filtered out.
+func (et *EventTests) streamSale(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	header := http.Header{"Accept": {web.EventStreamContentType}}
+	resp, err := et.request(ctx, http.MethodGet, "/v1/events?product_id="+comicBooksID, "", header)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to open the stream : %v", tests.Failed, err)
+	}
+	defer resp.Body.Close()
+
+	t.Log("Given the need to push the sales of a product.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a sale of the product is added.", testID)
+		{
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 for the response : %v", tests.Failed, testID, resp.StatusCode)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 200 for the response.", tests.Success, testID)
+
+			et.change(t, http.MethodPut, "/v1/products/"+mcDonaldsToysID, `{"quantity": 121}`)
+			et.change(t, http.MethodPost, "/v1/products/"+comicBooksID+"/sales", `{"quantity": 1, "paid": 50}`)
+
+			typ, e, err := next(bufio.NewScanner(resp.Body))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read an event : %v", tests.Failed, testID, err)
+			}
+			if typ != event.TypeSaleCreated || e.ProductID != comicBooksID || e.OwnerID != userGopherID {
+				t.Fatalf("\t%s\tTest %d:\tShould receive the sale of the product : %s %+v", tests.Failed, testID, typ, e)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive the sale of the product.", tests.Success, testID)
+
+			et.lastID = e.ID
+		}
+	}
+}
+
+// resumeStream validates reconnecting clients receive the events published
+// after the last one they saw.
+func (et *EventTests) resumeStream(t *testing.T) {
+	et.change(t, http.MethodPut, "/v1/products/"+comicBooksID, `{"quantity": 43}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	header := http.Header{"Last-Event-Id": {et.lastID}}
+	resp, err := et.request(ctx, http.MethodGet, "/v1/events?owner_id="+userGopherID, "", header)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to open the stream : %v", tests.Failed, err)
+	}
+	defer resp.Body.Close()
+
+	t.Log("Given the need to resume the event stream.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen reconnecting with the last event seen.", testID)
+		{
+			typ, e, err := next(bufio.NewScanner(resp.Body))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read an event : %v", tests.Failed, testID, err)
+			}
+			if typ != event.TypeProductUpdated || e.ProductID != comicBooksID || e.OwnerID != userGopherID {
+				t.Fatalf("\t%s\tTest %d:\tShould replay the events after the last one seen : %s %+v", tests.Failed, testID, typ, e)
+			}
+			t.Logf("\t%s\tTest %d:\tShould replay the events after the last one seen.", tests.Success, testID)
+		}
+	}
+}
+
+// streamOwnProducts validates users other than admins only receive the
+// events of their own products.
+func (et *EventTests) streamOwnProducts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Log("Given the need to keep the events of products from other users.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a user streams the events of another user.", testID)
+		{
+			resp, err := et.requestAs(ctx, et.userToken, http.MethodGet, "/v1/events?owner_id="+adminGopherID, "", nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to open the stream : %v", tests.Failed, testID, err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusForbidden {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 403 for the response : %v", tests.Failed, testID, resp.StatusCode)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 403 for the response.", tests.Success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen products of the user and of others change.", testID)
+		{
+			resp, err := et.request(ctx, http.MethodPost, "/v1/products", `{"name": "Gold Bars", "cost": 100, "quantity": 1}`, nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a product : %v", tests.Failed, testID, err)
+			}
+			var prd struct {
+				ID string `json:"id"`
+			}
+			err = json.NewDecoder(resp.Body).Decode(&prd)
+			resp.Body.Close()
+			if err != nil || resp.StatusCode != http.StatusCreated {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a product : %v %v", tests.Failed, testID, resp.StatusCode, err)
+			}
+
+			stream, err := et.requestAs(ctx, et.userToken, http.MethodGet, "/v1/events", "", nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to open the stream : %v", tests.Failed, testID, err)
+			}
+			defer stream.Body.Close()
+
+			if stream.StatusCode != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 for the response : %v", tests.Failed, testID, stream.StatusCode)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 200 for the response.", tests.Success, testID)
+
+			et.change(t, http.MethodPost, "/v1/products/"+prd.ID+"/sales", `{"quantity": 1, "paid": 100}`)
+			et.change(t, http.MethodPut, "/v1/products/"+mcDonaldsToysID, `{"quantity": 122}`)
+
+			typ, e, err := next(bufio.NewScanner(stream.Body))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read an event : %v", tests.Failed, testID, err)
+			}
+			if typ != event.TypeProductUpdated || e.ProductID != mcDonaldsToysID || e.OwnerID != userGopherID {
+				t.Fatalf("\t%s\tTest %d:\tShould only receive the events of own products : %s %+v", tests.Failed, testID, typ, e)
+			}
+			t.Logf("\t%s\tTest %d:\tShould only receive the events of own products.", tests.Success, testID)
+		}
+	}
+}
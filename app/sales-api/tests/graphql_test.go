@@ -0,0 +1,264 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/google/go-cmp/cmp"
+	"github.com/tullo/service/app/sales-api/handlers"
+	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/data/product"
+	"github.com/tullo/service/business/data/sale"
+	"github.com/tullo/service/business/data/tests"
+)
+
+// GraphQLTests holds methods for each GraphQL subtest.
+type GraphQLTests struct {
+	app       http.Handler
+	product   product.Store
+	sale      sale.Store
+	userToken string
+}
+
+// TestGraphQL runs a series of tests to exercise the GraphQL endpoint against
+// the seeded data.
+func TestGraphQL(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
+	defer cancel()
+
+	test := tests.NewIntegration(t, ctx)
+	t.Cleanup(test.Teardown)
+
+	shutdown := make(chan os.Signal, 1)
+	tests := GraphQLTests{
+		app: handlers.API(handlers.APIConfig{
+			Build:          "develop",
+			Shutdown:       shutdown,
+			Log:            test.Log,
+			DB:             test.DB,
+			Auth:           test.Auth,
+			IdempotencyTTL: time.Hour,
+		}),
+		product:   product.NewStore(test.Log, test.DB, nil),
+		sale:      sale.NewStore(test.Log, test.DB, nil),
+		userToken: test.Token("user@example.com", "gophers"),
+	}
+
+	t.Run("postGraphQL401", tests.postGraphQL401)
+	t.Run("queryProducts200", tests.queryProducts200)
+	t.Run("queryUsersForbidden", tests.queryUsersForbidden)
+	t.Run("queryLargeAmounts200", tests.queryLargeAmounts200)
+}
+
+// graphql executes a query authenticated by the token, if any.
+func (gt *GraphQLTests) graphql(query string, token string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"query": query})
+	r := httptest.NewRequest(http.MethodPost, "/v1/graphql", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	gt.app.ServeHTTP(w, r)
+	return w
+}
+
+// postGraphQL401 validates queries require authentication.
+func (gt *GraphQLTests) postGraphQL401(t *testing.T) {
+	w := gt.graphql(`{ products { id } }`, "")
+
+	t.Log("Given the need to validate the GraphQL endpoint requires authentication.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen querying without a token.", testID)
+		{
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 401 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 401 for the response.", tests.Success, testID)
+		}
+	}
+}
+
+// queryProducts200 validates products are returned with their sales and
+// owners.
+func (gt *GraphQLTests) queryProducts200(t *testing.T) {
+	w := gt.graphql(`{ products(rows: 10) { name sold owner { name } sales { paid } } }`, gt.userToken)
+
+	t.Log("Given the need to query products with their relations.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen querying the seeded products.", testID)
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 200 for the response.", tests.Success, testID)
+
+			type product struct {
+				Name  string `json:"name"`
+				Sold  int    `json:"sold"`
+				Owner struct {
+					Name string `json:"name"`
+				} `json:"owner"`
+				Sales []struct {
+					Paid int `json:"paid"`
+				} `json:"sales"`
+			}
+			var got struct {
+				Data struct {
+					Products []product `json:"products"`
+				} `json:"data"`
+				Errors []interface{} `json:"errors"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", tests.Failed, testID, err)
+			}
+			if len(got.Errors) > 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould get no errors : %v", tests.Failed, testID, got.Errors)
+			}
+
+			products := got.Data.Products
+			sort.Slice(products, func(i, j int) bool { return products[i].Name < products[j].Name })
+
+			summary := make([]string, len(products))
+			for i, p := range products {
+				paid := 0
+				for _, s := range p.Sales {
+					paid += s.Paid
+				}
+				summary[i] = strings.Join([]string{p.Name, p.Owner.Name, strconv.Itoa(p.Sold), strconv.Itoa(len(p.Sales)), strconv.Itoa(paid)}, "|")
+			}
+
+			exp := []string{
+				"Comic Books|User Gopher|7|2|350",
+				"McDonalds Toys|User Gopher|3|1|225",
+			}
+			if diff := cmp.Diff(exp, summary); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", tests.Failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the expected result.", tests.Success, testID)
+		}
+	}
+}
+
+// queryUsersForbidden validates only admins can list users.
+func (gt *GraphQLTests) queryUsersForbidden(t *testing.T) {
+	w := gt.graphql(`{ users { id } }`, gt.userToken)
+
+	t.Log("Given the need to validate users can't list other users.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen querying users with the user role.", testID)
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 200 for the response.", tests.Success, testID)
+
+			var got struct {
+				Errors []struct {
+					Extensions struct {
+						Code string `json:"code"`
+					} `json:"extensions"`
+				} `json:"errors"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", tests.Failed, testID, err)
+			}
+			if len(got.Errors) != 1 || got.Errors[0].Extensions.Code != "forbidden" {
+				t.Fatalf("\t%s\tTest %d:\tShould get a forbidden error : %+v", tests.Failed, testID, got.Errors)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get a forbidden error.", tests.Success, testID)
+		}
+	}
+}
+
+// queryLargeAmounts200 validates amounts beyond the 32 bits of Int are
+// returned without wrapping.
+func (gt *GraphQLTests) queryLargeAmounts200(t *testing.T) {
+	ctx := context.Background()
+	traceID := "00000000-0000-0000-0000-000000000000"
+	now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	claims := auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "service project",
+			Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+			Audience:  jwt.ClaimStrings{"students"},
+			ExpiresAt: jwt.At(now.Add(time.Hour)),
+			IssuedAt:  jwt.At(now),
+		},
+		Roles: []string{auth.RoleAdmin},
+	}
+
+	const maxCents = 1<<31 - 1
+
+	np := product.NewProduct{
+		Name:     "Gold Bars",
+		Cost:     maxCents,
+		Quantity: 2,
+	}
+	prd, err := gt.product.Create(ctx, traceID, claims, np, now)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a product : %s.", tests.Failed, err)
+	}
+	for range 2 {
+		if _, err := gt.sale.AddSale(ctx, traceID, sale.NewSale{Quantity: 1, Paid: maxCents}, prd.ID, now); err != nil {
+			t.Fatalf("\t%s\tShould be able to add a sale : %s.", tests.Failed, err)
+		}
+	}
+
+	w := gt.graphql(`{ product(id: "`+prd.ID+`") { cost sold revenue sales { paid } } }`, gt.userToken)
+
+	t.Log("Given the need to query amounts in cents beyond 32 bits.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the revenue of a product exceeds 2^31 cents.", testID)
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 200 for the response.", tests.Success, testID)
+
+			var got struct {
+				Data struct {
+					Product struct {
+						Cost    int64 `json:"cost"`
+						Sold    int64 `json:"sold"`
+						Revenue int64 `json:"revenue"`
+						Sales   []struct {
+							Paid int64 `json:"paid"`
+						} `json:"sales"`
+					} `json:"product"`
+				} `json:"data"`
+				Errors []interface{} `json:"errors"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", tests.Failed, testID, err)
+			}
+			if len(got.Errors) > 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould get no errors : %v", tests.Failed, testID, got.Errors)
+			}
+
+			p := got.Data.Product
+			if p.Cost != maxCents || p.Sold != 2 || p.Revenue != 2*maxCents {
+				t.Fatalf("\t%s\tTest %d:\tShould get the amounts unwrapped : got cost %d sold %d revenue %d.", tests.Failed, testID, p.Cost, p.Sold, p.Revenue)
+			}
+			if len(p.Sales) != 2 || p.Sales[0].Paid != maxCents {
+				t.Fatalf("\t%s\tTest %d:\tShould get the paid amounts unwrapped : %+v", tests.Failed, testID, p.Sales)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the amounts unwrapped.", tests.Success, testID)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tullo/service/app/sales-api/handlers"
+	"github.com/tullo/service/business/data/tests"
+	"github.com/tullo/service/foundation/logger"
+)
+
+// TestMetrics validates the request metrics are reported by route pattern,
+// method and status. It doesn't need a database.
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	api := handlers.API(handlers.APIConfig{
+		Build:    "develop",
+		Shutdown: make(chan os.Signal, 1),
+		Log:      logger.New(io.Discard, "TEST", slog.LevelInfo),
+		Metrics:  reg,
+	})
+
+	t.Log("Given the need to monitor the requests.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen serving requests to a route.", testID)
+		{
+			for _, id := range []string{"a", "b"} {
+				r := httptest.NewRequest(http.MethodGet, "/v1/products/"+id, nil)
+				w := httptest.NewRecorder()
+				api.ServeHTTP(w, r)
+
+				if w.Code != http.StatusUnauthorized {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 401 for the response : %v", tests.Failed, testID, w.Code)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 401 for the responses.", tests.Success, testID)
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+			api.ServeHTTP(httptest.NewRecorder(), r)
+
+			mfs, err := reg.Gather()
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to gather the metrics : %v", tests.Failed, testID, err)
+			}
+
+			requests := map[string]float64{}
+			for _, mf := range mfs {
+				if mf.GetName() != "http_requests_total" {
+					continue
+				}
+				for _, m := range mf.GetMetric() {
+					labels := map[string]string{}
+					for _, lp := range m.GetLabel() {
+						labels[lp.GetName()] = lp.GetValue()
+					}
+					requests[labels["method"]+" "+labels["route"]+" "+labels["status"]] = m.GetCounter().GetValue()
+				}
+			}
+
+			exp := map[string]float64{
+				"GET /v1/products/{id} 401": 2,
+				"GET /v1/openapi.json 200":  1,
+			}
+			for k, v := range exp {
+				if requests[k] != v {
+					t.Fatalf("\t%s\tTest %d:\tShould count %v requests for %s : %v", tests.Failed, testID, v, k, requests)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould count the requests by route pattern, method and status.", tests.Success, testID)
+
+			if n := testutil.CollectAndCount(reg, "http_request_duration_seconds"); n != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould observe the latency of both series : %d", tests.Failed, testID, n)
+			}
+			t.Logf("\t%s\tTest %d:\tShould observe the latency of both series.", tests.Success, testID)
+
+			if n := testutil.CollectAndCount(reg, "http_requests_in_flight"); n != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould track the requests in flight of both series : %d", tests.Failed, testID, n)
+			}
+			t.Logf("\t%s\tTest %d:\tShould track the requests in flight of both series.", tests.Success, testID)
+		}
+	}
+}
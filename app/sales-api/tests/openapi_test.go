@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tullo/service/app/sales-api/handlers"
+	"github.com/tullo/service/business/data/tests"
+	"github.com/tullo/service/foundation/logger"
+	"github.com/tullo/service/foundation/openapi"
+	"github.com/tullo/service/foundation/web"
+)
+
+// TestOpenAPI validates every route of the API is documented and the
+// OpenAPI document is served. It doesn't need a database.
+func TestOpenAPI(t *testing.T) {
+	api := handlers.API(handlers.APIConfig{
+		Build:    "develop",
+		Shutdown: make(chan os.Signal, 1),
+		Log:      logger.New(io.Discard, "TEST", slog.LevelInfo),
+	})
+
+	t.Log("Given the need to document the API.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen checking the registered routes.", testID)
+		{
+			app, ok := api.(*web.App)
+			if !ok {
+				t.Fatalf("\t%s\tTest %d:\tShould get a web.App : %T", tests.Failed, testID, api)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get a web.App.", tests.Success, testID)
+
+			if routes := app.Undocumented(); len(routes) > 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould have documented all routes : %v", tests.Failed, testID, routes)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have documented all routes.", tests.Success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen fetching the OpenAPI document.", testID)
+		{
+			r := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 200 for the response.", tests.Success, testID)
+
+			var doc openapi.Document
+			if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", tests.Failed, testID, err)
+			}
+			if doc.OpenAPI != openapi.Version {
+				t.Fatalf("\t%s\tTest %d:\tShould get OpenAPI version %s : %s", tests.Failed, testID, openapi.Version, doc.OpenAPI)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get OpenAPI version %s.", tests.Success, testID, openapi.Version)
+
+			if _, ok := doc.Paths["/v1/products/{id}"]["patch"]; !ok {
+				t.Fatalf("\t%s\tTest %d:\tShould document the patch product operation.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould document the patch product operation.", tests.Success, testID)
+
+			np, ok := doc.Components.Schemas["product.NewProduct"]
+			if !ok {
+				t.Fatalf("\t%s\tTest %d:\tShould document the new product schema.", tests.Failed, testID)
+			}
+			if diff := cmp.Diff([]string{"name", "cost"}, np.Required); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get the required fields of a new product. Diff:\n%s", tests.Failed, testID, diff)
+			}
+			if minimum := np.Properties["cost"].Minimum; minimum == nil || *minimum != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould constrain the cost of a new product to cents : %v", tests.Failed, testID, minimum)
+			}
+			t.Logf("\t%s\tTest %d:\tShould turn the validation tags of a new product into constraints.", tests.Success, testID)
+		}
+	}
+}
@@ -45,7 +45,14 @@ func TestProducts(t *testing.T) {
 
 	shutdown := make(chan os.Signal, 1)
 	tests := ProductTests{
-		app:       handlers.API("develop", shutdown, test.Log, test.DB, test.Auth),
+		app: handlers.API(handlers.APIConfig{
+			Build:          "develop",
+			Shutdown:       shutdown,
+			Log:            test.Log,
+			DB:             test.DB,
+			Auth:           test.Auth,
+			IdempotencyTTL: time.Hour,
+		}),
 		userToken: test.Token("admin@example.com", "gophers"),
 	}
 
@@ -54,9 +61,11 @@ func TestProducts(t *testing.T) {
 	t.Run("postProduct401", tests.postProduct401)
 	t.Run("getProduct404", tests.getProduct404)
 	t.Run("getProduct400", tests.getProduct400)
+	t.Run("getProductSales400", tests.getProductSales400)
 	t.Run("deleteProductNotFound", tests.deleteProductNotFound)
 	t.Run("putProduct404", tests.putProduct404)
 	t.Run("getProducts200", tests.getProducts200)
+	t.Run("getProductsPage200", tests.getProductsPage200)
 	t.Run("crudProductAdmin", tests.crudProductAdmin)
 
 	// USER role
@@ -87,16 +96,20 @@ func (pt *ProductTests) postProduct400(t *testing.T) {
 			t.Logf("\t%s\tTest %d:\tShould receive a status code of 400 for the response.", tests.Success, testID)
 
 			// Inspect the response.
-			var got web.ErrorResponse
+			var got web.ProblemDetails
 			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
 				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response to an error type : %v", tests.Failed, testID, err)
 			}
 			t.Logf("\t%s\tTest %d:\tShould be able to unmarshal the response to an error type.", tests.Success, testID)
 
 			// Define what we expect to see.
-			exp := web.ErrorResponse{
-				Error: "field validation error",
-				Fields: []web.FieldError{
+			exp := web.ProblemDetails{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "field validation error",
+				Instance: "/v1/products",
+				Errors: []web.FieldError{
 					{Field: "name", Error: "name is a required field"},
 					{Field: "cost", Error: "cost is a required field"},
 					{Field: "quantity", Error: "quantity must be 1 or greater"},
@@ -109,7 +122,10 @@ func (pt *ProductTests) postProduct400(t *testing.T) {
 				return a.Field < b.Field
 			})
 
-			if diff := cmp.Diff(exp, got, sorter); diff != "" {
+			// The trace id is generated per request.
+			ignore := cmpopts.IgnoreFields(web.ProblemDetails{}, "TraceID")
+
+			if diff := cmp.Diff(exp, got, sorter, ignore); diff != "" {
 				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", tests.Failed, testID, diff)
 			}
 			t.Logf("\t%s\tTest %d:\tShould get the expected result.", tests.Success, testID)
@@ -151,6 +167,40 @@ func (pt *ProductTests) postProduct401(t *testing.T) {
 	}
 }
 
+// getProductSales400 validates the sales requests of a malformed product id.
+func (pt *ProductTests) getProductSales400(t *testing.T) {
+	id := "not-a-uuid"
+
+	t.Log("Given the need to validate getting sales with a malformed product id.")
+	{
+		for testID, path := range []string{"/v1/products/" + id + "/sales", "/v2/products/" + id + "/sales", "/v2/products/" + id + "/sales?count=true"} {
+			t.Logf("\tTest %d:\tWhen using %s.", testID, path)
+			{
+				r := httptest.NewRequest(http.MethodGet, path, nil)
+				w := httptest.NewRecorder()
+
+				r.Header.Set("Authorization", "Bearer "+pt.userToken)
+
+				pt.app.ServeHTTP(w, r)
+
+				if w.Code != http.StatusBadRequest {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 400 for the response : %v", tests.Failed, testID, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of 400 for the response.", tests.Success, testID)
+
+				var got web.ProblemDetails
+				if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response to an error type : %v", tests.Failed, testID, err)
+				}
+				if got.Code != "invalid_id" {
+					t.Fatalf("\t%s\tTest %d:\tShould get the invalid_id code : %q", tests.Failed, testID, got.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get the invalid_id code.", tests.Success, testID)
+			}
+		}
+	}
+}
+
 // getProduct400 validates a product request for a malformed id.
 func (pt *ProductTests) getProduct400(t *testing.T) {
 	id := "12345"
@@ -172,12 +222,25 @@ func (pt *ProductTests) getProduct400(t *testing.T) {
 			}
 			t.Logf("\t%s\tTest %d:\tShould receive a status code of 400 for the response.", tests.Success, testID)
 
-			got := w.Body.String()
-			exp := `{"error":"ID is not in its proper form"}`
-			if exp != got {
-				t.Logf("\t\tTest %d:\tGot : %v", testID, got)
-				t.Logf("\t\tTest %d:\tExp: %v", testID, exp)
-				t.Fatalf("\t%s\tTest %d:\tShould get the expected result.", tests.Failed, testID)
+			var got web.ProblemDetails
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response to an error type : %v", tests.Failed, testID, err)
+			}
+
+			exp := web.ProblemDetails{
+				Type:     "/problems/invalid-id",
+				Title:    "Invalid identifier",
+				Status:   http.StatusBadRequest,
+				Detail:   "ID is not in its proper form",
+				Instance: "/v1/products/" + id,
+				Code:     "invalid_id",
+			}
+
+			// The trace id is generated per request.
+			ignore := cmpopts.IgnoreFields(web.ProblemDetails{}, "TraceID")
+
+			if diff := cmp.Diff(exp, got, ignore); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", tests.Failed, testID, diff)
 			}
 			t.Logf("\t%s\tTest %d:\tShould get the expected result.", tests.Success, testID)
 		}
@@ -256,6 +319,56 @@ func (pt *ProductTests) getProducts200(t *testing.T) {
 	}
 }
 
+// getProductsPage200 validates a page of products is wrapped in an envelope
+// with pagination metadata.
+func (pt *ProductTests) getProductsPage200(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v2/products?rows=1&count=true", nil)
+	w := httptest.NewRecorder()
+
+	r.Header.Set("Authorization", "Bearer "+pt.userToken)
+
+	pt.app.ServeHTTP(w, r)
+
+	t.Log("Given the need to page through the products that exist.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen retrieving the first page of products.", testID)
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 200 for the response.", tests.Success, testID)
+
+			var got struct {
+				Data        []product.Info `json:"data"`
+				Page        int            `json:"page"`
+				RowsPerPage int            `json:"rows_per_page"`
+				Total       int            `json:"total"`
+				Links       web.Links      `json:"links"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", tests.Failed, testID, err)
+			}
+
+			// Define what we wanted to receive. Two products are seeded.
+			exp := web.Links{
+				Self:  "/v2/products?count=true&page=1&rows=1",
+				First: "/v2/products?count=true&page=1&rows=1",
+				Next:  "/v2/products?count=true&page=2&rows=1",
+				Last:  "/v2/products?count=true&page=2&rows=1",
+			}
+
+			if len(got.Data) != 1 || got.Page != 1 || got.RowsPerPage != 1 || got.Total != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould get the first of two pages : %d rows, page %d, %d rows per page, %d total", tests.Failed, testID, len(got.Data), got.Page, got.RowsPerPage, got.Total)
+			}
+			if diff := cmp.Diff(exp, got.Links); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get the expected links. Diff:\n%s", tests.Failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the expected result.", tests.Success, testID)
+		}
+	}
+}
+
 // deleteProductNotFound validates deleting a product that does not exist is not a failure.
 func (pt *ProductTests) deleteProductNotFound(t *testing.T) {
 	id := "112262f1-1a77-4374-9f22-39e575aa6348"
@@ -329,9 +442,15 @@ func (pt *ProductTests) crudProductAdmin(t *testing.T) {
 
 	pt.getProduct200(t, p.ID)
 	pt.putProduct204(t, p.ID)
+	pt.patchProduct204(t, p.ID)
 
 	pt.postProductSale201(t, p.ID)
+	pt.postProductSale400(t, p.ID)
 	pt.getProductSales200(t, p.ID)
+	pt.getProductSparse200(t, p.ID)
+	pt.postProductSaleIdempotent(t, p.ID)
+	pt.getExport200(t, "/v1/products/export", p.ID, "id")
+	pt.getExport200(t, "/v1/sales/export", p.ID, "product_id")
 }
 
 // crudProductUser performs tests for produtct creation and update
@@ -463,6 +582,58 @@ func (pt *ProductTests) getProduct200(t *testing.T, id string) {
 	}
 }
 
+// getProductSparse200 validates getting a product trimmed to some fields
+// with its sales and owner embedded.
+func (pt *ProductTests) getProductSparse200(t *testing.T, id string) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/products/"+id+"?fields=id,name,cost&include=sales,owner", nil)
+	w := httptest.NewRecorder()
+
+	r.Header.Set("Authorization", "Bearer "+pt.userToken)
+
+	pt.app.ServeHTTP(w, r)
+
+	t.Log("Given the need to validate getting a sparse product with its relations.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen using the product %s.", testID, id)
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 200 for the response.", tests.Success, testID)
+
+			var got map[string]json.RawMessage
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", tests.Failed, testID, err)
+			}
+
+			keys := make([]string, 0, len(got))
+			for k := range got {
+				keys = append(keys, k)
+			}
+			exp := []string{"cost", "id", "name", "owner", "sales"}
+			if diff := cmp.Diff(exp, keys, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould only get the requested fields. Diff:\n%s", tests.Failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould only get the requested fields.", tests.Success, testID)
+
+			var sales []sale.Info
+			if err := json.Unmarshal(got["sales"], &sales); err != nil || len(sales) == 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould embed the sales of the product : %v", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould embed the sales of the product.", tests.Success, testID)
+
+			var owner struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(got["owner"], &owner); err != nil || owner.Name != "Admin Gopher" {
+				t.Fatalf("\t%s\tTest %d:\tShould embed the owner of the product : %s %v", tests.Failed, testID, got["owner"], err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould embed the owner of the product.", tests.Success, testID)
+		}
+	}
+}
+
 // putProduct204 validates updating a product that does exist.
 func (pt *ProductTests) putProduct204(t *testing.T, id string) {
 	body := `{"name": "Graphic Novels", "cost": 100}`
@@ -508,6 +679,50 @@ func (pt *ProductTests) putProduct204(t *testing.T, id string) {
 	}
 }
 
+// patchProduct204 validates updating a product with a JSON Patch document.
+func (pt *ProductTests) patchProduct204(t *testing.T, id string) {
+	body := `[
+		{"op": "test", "path": "/name", "value": "Graphic Novels"},
+		{"op": "replace", "path": "/quantity", "value": 40}
+	]`
+	r := httptest.NewRequest(http.MethodPatch, "/v1/products/"+id, strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	r.Header.Set("Authorization", "Bearer "+pt.userToken)
+	r.Header.Set("Content-Type", web.JSONPatchContentType)
+
+	pt.app.ServeHTTP(w, r)
+
+	t.Log("Given the need to patch a product with the products endpoint.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen using a JSON Patch document.", testID)
+		{
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 204 for the response : %v %s", tests.Failed, testID, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 204 for the response.", tests.Success, testID)
+
+			r = httptest.NewRequest(http.MethodGet, "/v1/products/"+id, nil)
+			w = httptest.NewRecorder()
+
+			r.Header.Set("Authorization", "Bearer "+pt.userToken)
+
+			pt.app.ServeHTTP(w, r)
+
+			var ru product.Info
+			if err := json.NewDecoder(w.Body).Decode(&ru); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", tests.Failed, testID, err)
+			}
+
+			if ru.Name != "Graphic Novels" || ru.Cost != 100 || ru.Quantity != 40 {
+				t.Fatalf("\t%s\tTest %d:\tShould see only the patched Quantity : %+v", tests.Failed, testID, ru)
+			}
+			t.Logf("\t%s\tTest %d:\tShould see only the patched Quantity.", tests.Success, testID)
+		}
+	}
+}
+
 // postProductSale403 validates that creating sales
 // with role=USER is forbidden.
 func (pt *ProductTests) postProductSale403(t *testing.T, id string) {
@@ -601,6 +816,57 @@ func (pt *ProductTests) postProductSale201(t *testing.T, id string) {
 	}
 }
 
+// postProductSale400 validates a sale can't be recorded when the amount paid
+// exceeds twice the list price of the units sold.
+func (pt *ProductTests) postProductSale400(t *testing.T, id string) {
+	body := `{"quantity": 1, "paid": 1000}`
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/products/"+id+"/sales", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	r.Header.Set("Authorization", "Bearer "+pt.userToken)
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+
+	pt.app.ServeHTTP(w, r)
+
+	t.Log("Given the need to validate the amount paid for a sale.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen paying more than twice the list price.", testID)
+		{
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 400 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 400 for the response.", tests.Success, testID)
+
+			var got web.ProblemDetails
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response to an error type : %v", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to unmarshal the response to an error type.", tests.Success, testID)
+
+			exp := web.ProblemDetails{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "Fehler bei der Feldvalidierung",
+				Instance: "/v1/products/" + id + "/sales",
+				Errors: []web.FieldError{
+					{Field: "paid", Error: "paid darf 200, den doppelten Listenpreis der verkauften Einheiten, nicht überschreiten"},
+				},
+			}
+
+			// The trace id is generated per request.
+			ignore := cmpopts.IgnoreFields(web.ProblemDetails{}, "TraceID")
+
+			if diff := cmp.Diff(exp, got, ignore); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", tests.Failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the expected result.", tests.Success, testID)
+		}
+	}
+}
+
 // getProductSales200 validates sales request for a product.
 func (pt *ProductTests) getProductSales200(t *testing.T, id string) {
 	r := httptest.NewRequest(http.MethodGet, "/v1/products/"+id+"/sales", nil)
@@ -662,3 +928,142 @@ func (pt *ProductTests) getProductSales200(t *testing.T, id string) {
 		}
 	}
 }
+
+// postProductSaleIdempotent validates a retried sale with the same
+// Idempotency-Key replays the first response instead of adding another sale,
+// but not to the sales of another product.
+func (pt *ProductTests) postProductSaleIdempotent(t *testing.T, id string) {
+	postTo := func(id string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/products/"+id+"/sales", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		r.Header.Set("Authorization", "Bearer "+pt.userToken)
+		r.Header.Set("Idempotency-Key", "1c2d6a5e-sale-retry")
+
+		pt.app.ServeHTTP(w, r)
+		return w
+	}
+	post := func(body string) *httptest.ResponseRecorder {
+		return postTo(id, body)
+	}
+
+	other := pt.postProduct201(t)
+	defer pt.deleteProduct204(t, other.ID)
+
+	t.Log("Given the need to retry creating a product sale.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen using the same idempotency key twice.", testID)
+		{
+			body := `{"quantity": 2, "paid": 50}`
+
+			w := post(body)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 201 for the first response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 201 for the first response.", tests.Success, testID)
+
+			var first sale.Info
+			if err := json.NewDecoder(w.Body).Decode(&first); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", tests.Failed, testID, err)
+			}
+
+			w = post(body)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 201 for the replayed response : %v", tests.Failed, testID, w.Code)
+			}
+			if w.Header().Get("Idempotent-Replayed") != "true" {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a replayed response.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a replayed response.", tests.Success, testID)
+
+			var replayed sale.Info
+			if err := json.NewDecoder(w.Body).Decode(&replayed); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", tests.Failed, testID, err)
+			}
+
+			if diff := cmp.Diff(first, replayed); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get back the same sale. Diff:\n%s", tests.Failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get back the same sale.", tests.Success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen reusing the idempotency key with a different body.", testID)
+		{
+			w := post(`{"quantity": 5, "paid": 90}`)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 422 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 422 for the response.", tests.Success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen reusing the idempotency key for a sale of another product.", testID)
+		{
+			w := postTo(other.ID, `{"quantity": 2, "paid": 50}`)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 201 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			if w.Header().Get("Idempotent-Replayed") != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould not receive a replayed response.", tests.Failed, testID)
+			}
+
+			var s sale.Info
+			if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", tests.Failed, testID, err)
+			}
+			if s.ProductID != other.ID {
+				t.Fatalf("\t%s\tTest %d:\tShould create a sale of the other product : got %v", tests.Failed, testID, s.ProductID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould create a sale of the other product.", tests.Success, testID)
+		}
+	}
+}
+
+// getExport200 validates an export streams its rows as newline delimited
+// JSON, including one whose key holds the product id.
+func (pt *ProductTests) getExport200(t *testing.T, path string, id string, key string) {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+
+	r.Header.Set("Authorization", "Bearer "+pt.userToken)
+
+	pt.app.ServeHTTP(w, r)
+
+	t.Logf("Given the need to export everything with %s.", path)
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen looking for the product %s.", testID, id)
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 for the response : %v", tests.Failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 200 for the response.", tests.Success, testID)
+
+			if got := w.Header().Get("Content-Type"); got != web.NDJSONContentType {
+				t.Fatalf("\t%s\tTest %d:\tShould receive content type %q : %q", tests.Failed, testID, web.NDJSONContentType, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive content type %q.", tests.Success, testID, web.NDJSONContentType)
+
+			var found bool
+			dec := json.NewDecoder(w.Body)
+			for dec.More() {
+				var row map[string]interface{}
+				if err := dec.Decode(&row); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal every row : %v", tests.Failed, testID, err)
+				}
+				if _, ok := row["status"]; ok {
+					t.Fatalf("\t%s\tTest %d:\tShould not end with an error document : %v", tests.Failed, testID, row)
+				}
+				found = found || row[key] == id
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to unmarshal every row.", tests.Success, testID)
+
+			if !found {
+				t.Fatalf("\t%s\tTest %d:\tShould find a row of the product.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould find a row of the product.", tests.Success, testID)
+		}
+	}
+}
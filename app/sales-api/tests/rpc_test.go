@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tullo/service/app/sales-api/rpc"
+	"github.com/tullo/service/app/sales-api/rpc/salespb"
+	"github.com/tullo/service/business/data/tests"
+	"github.com/tullo/service/business/event"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// RPCTests holds methods for each gRPC subtest.
+type RPCTests struct {
+	conn      *grpc.ClientConn
+	userToken string
+}
+
+// TestRPC runs a series of tests to exercise the gRPC services against the
+// seeded data.
+func TestRPC(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
+	defer cancel()
+
+	test := tests.NewIntegration(t, ctx)
+	t.Cleanup(test.Teardown)
+
+	srv := rpc.NewServer(rpc.Config{
+		Log:    test.Log,
+		DB:     test.DB,
+		Auth:   test.Auth,
+		Events: event.NewBroker(16),
+	})
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	dial := func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(dial),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dialing gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	tests := RPCTests{
+		conn:      conn,
+		userToken: test.Token("user@example.com", "gophers"),
+	}
+
+	t.Run("listProductsUnauthenticated", tests.listProductsUnauthenticated)
+	t.Run("listProducts", tests.listProducts)
+	t.Run("listUsersPermissionDenied", tests.listUsersPermissionDenied)
+}
+
+// withToken returns a context carrying the token as authorization metadata.
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// listProductsUnauthenticated validates calls require authentication.
+func (rt *RPCTests) listProductsUnauthenticated(t *testing.T) {
+	client := salespb.NewProductServiceClient(rt.conn)
+	_, err := client.ListProducts(context.Background(), &salespb.ListProductsRequest{})
+
+	t.Log("Given the need to validate the gRPC services require authentication.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen calling without a token.", testID)
+		{
+			if got := status.Code(err); got != codes.Unauthenticated {
+				t.Fatalf("\t%s\tTest %d:\tShould receive an Unauthenticated code : %v", tests.Failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive an Unauthenticated code.", tests.Success, testID)
+		}
+	}
+}
+
+// listProducts validates the seeded products are listed.
+func (rt *RPCTests) listProducts(t *testing.T) {
+	client := salespb.NewProductServiceClient(rt.conn)
+	resp, err := client.ListProducts(withToken(rt.userToken), &salespb.ListProductsRequest{
+		Page: &salespb.Page{Page: 1, Rows: 10},
+	})
+
+	t.Log("Given the need to list products over gRPC.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen listing the seeded products.", testID)
+		{
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to list products : %v", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to list products.", tests.Success, testID)
+
+			var names []string
+			for _, p := range resp.GetProducts() {
+				names = append(names, p.GetName())
+			}
+			sort.Strings(names)
+
+			exp := []string{"Comic Books", "McDonalds Toys"}
+			if diff := cmp.Diff(exp, names); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", tests.Failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the expected result.", tests.Success, testID)
+		}
+	}
+}
+
+// listUsersPermissionDenied validates only admins can list users.
+func (rt *RPCTests) listUsersPermissionDenied(t *testing.T) {
+	client := salespb.NewUserServiceClient(rt.conn)
+	_, err := client.ListUsers(withToken(rt.userToken), &salespb.ListUsersRequest{})
+
+	t.Log("Given the need to validate users can't list other users.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen listing users with the user role.", testID)
+		{
+			if got := status.Code(err); got != codes.PermissionDenied {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a PermissionDenied code : %v", tests.Failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a PermissionDenied code.", tests.Success, testID)
+		}
+	}
+}
@@ -19,8 +19,8 @@ func Test_Sales(t *testing.T) {
 	log, db, teardown := tests.NewUnit(t, ctx)
 	defer teardown()
 
-	p := product.NewStore(log, db)
-	s := sale.NewStore(log, db)
+	p := product.NewStore(log, db, nil)
+	s := sale.NewStore(log, db, nil)
 
 	t.Log("Given the need to work with product Sales records.")
 
@@ -39,7 +39,14 @@ func TestUsers(t *testing.T) {
 
 	shutdown := make(chan os.Signal, 1)
 	tests := UserTests{
-		app:        handlers.API("develop", shutdown, test.Log, test.DB, test.Auth),
+		app: handlers.API(handlers.APIConfig{
+			Build:          "develop",
+			Shutdown:       shutdown,
+			Log:            test.Log,
+			DB:             test.DB,
+			Auth:           test.Auth,
+			IdempotencyTTL: time.Hour,
+		}),
 		kid:        test.KID,
 		userToken:  test.Token("user@example.com", "gophers"),
 		adminToken: test.Token("admin@example.com", "gophers"),
@@ -140,16 +147,20 @@ func (ut *UserTests) postUser400(t *testing.T) {
 			t.Logf("\t%s\tTest %d:\tShould receive a status code of 400 for the response.", tests.Success, testID)
 
 			// Inspect the response.
-			var got web.ErrorResponse
+			var got web.ProblemDetails
 			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
 				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response to an error type : %v", tests.Failed, testID, err)
 			}
 			t.Logf("\t%s\tTest %d:\tShould be able to unmarshal the response to an error type.", tests.Success, testID)
 
 			// Define what we expect to see.
-			exp := web.ErrorResponse{
-				Error: "field validation error",
-				Fields: []web.FieldError{
+			exp := web.ProblemDetails{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "field validation error",
+				Instance: "/v1/users",
+				Errors: []web.FieldError{
 					{Field: "name", Error: "name is a required field"},
 					{Field: "email", Error: "email is a required field"},
 					{Field: "roles", Error: "roles is a required field"},
@@ -163,7 +174,10 @@ func (ut *UserTests) postUser400(t *testing.T) {
 				return a.Field < b.Field
 			})
 
-			if diff := cmp.Diff(exp, got, sorter); diff != "" {
+			// The trace id is generated per request.
+			ignore := cmpopts.IgnoreFields(web.ProblemDetails{}, "TraceID")
+
+			if diff := cmp.Diff(exp, got, sorter, ignore); diff != "" {
 				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", tests.Failed, testID, diff)
 			}
 			t.Logf("\t%s\tTest %d:\tShould get the expected result.", tests.Success, testID)
@@ -248,12 +262,25 @@ func (ut *UserTests) getUser400(t *testing.T) {
 			}
 			t.Logf("\t%s\tTest %d:\tShould receive a status code of 400 for the response.", tests.Success, testID)
 
-			got := w.Body.String()
-			exp := `{"error":"ID is not in its proper form"}`
-			if exp != got {
-				t.Logf("\t\tTest %d:\tGot : %v", testID, got)
-				t.Logf("\t\tTest %d:\tWant: %v", testID, exp)
-				t.Fatalf("\t%s\tTest %d:\tShould get the expected result.", tests.Failed, testID)
+			var got web.ProblemDetails
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response to an error type : %v", tests.Failed, testID, err)
+			}
+
+			exp := web.ProblemDetails{
+				Type:     "/problems/invalid-id",
+				Title:    "Invalid identifier",
+				Status:   http.StatusBadRequest,
+				Detail:   "ID is not in its proper form",
+				Instance: "/v1/users/" + id,
+				Code:     "invalid_id",
+			}
+
+			// The trace id is generated per request.
+			ignore := cmpopts.IgnoreFields(web.ProblemDetails{}, "TraceID")
+
+			if diff := cmp.Diff(exp, got, ignore); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", tests.Failed, testID, diff)
 			}
 			t.Logf("\t%s\tTest %d:\tShould get the expected result.", tests.Success, testID)
 		}
@@ -279,12 +306,25 @@ func (ut *UserTests) getUser403(t *testing.T) {
 			}
 			t.Logf("\t%s\tTest %d:\tShould receive a status code of 403 for the response.", tests.Success, testID)
 
-			got := w.Body.String()
-			exp := `{"error":"attempted action is not allowed"}`
-			if exp != got {
-				t.Log("Got :", got)
-				t.Log("Exp:", exp)
-				t.Fatalf("\t%s\tTest %d:\tShould get the expected result.", tests.Failed, testID)
+			var got web.ProblemDetails
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response to an error type : %v", tests.Failed, testID, err)
+			}
+
+			exp := web.ProblemDetails{
+				Type:     "/problems/forbidden",
+				Title:    "Action not allowed",
+				Status:   http.StatusForbidden,
+				Detail:   "attempted action is not allowed",
+				Instance: "/v1/users/" + tests.AdminID,
+				Code:     "forbidden",
+			}
+
+			// The trace id is generated per request.
+			ignore := cmpopts.IgnoreFields(web.ProblemDetails{}, "TraceID")
+
+			if diff := cmp.Diff(exp, got, ignore); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", tests.Failed, testID, diff)
 			}
 			t.Logf("\t%s\tTest %d:\tShould get the expected result.", tests.Success, testID)
 		}
@@ -413,6 +453,7 @@ func (ut *UserTests) crudUser(t *testing.T) {
 
 	ut.getUser200(t, nu.ID)
 	ut.putUser204(t, nu.ID)
+	ut.patchUser204(t, nu.ID)
 	ut.putUser403(t, nu.ID)
 }
 
@@ -623,6 +664,67 @@ func (ut *UserTests) putUser204(t *testing.T, id string) {
 	}
 }
 
+// patchUser204 validates adding a role to a user with a JSON Patch document.
+func (ut *UserTests) patchUser204(t *testing.T, id string) {
+	body := `[{"op": "add", "path": "/roles/-", "value": "USER"}]`
+
+	r := httptest.NewRequest(http.MethodPatch, "/v1/users/"+id, strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	r.Header.Set("Authorization", "Bearer "+ut.adminToken)
+	r.Header.Set("Content-Type", web.JSONPatchContentType)
+
+	ut.app.ServeHTTP(w, r)
+
+	t.Log("Given the need to patch a user with the users endpoint.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen adding a role.", testID)
+		{
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 204 for the response : %v %s", tests.Failed, testID, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 204 for the response.", tests.Success, testID)
+
+			r = httptest.NewRequest(http.MethodGet, "/v1/users/"+id, nil)
+			w = httptest.NewRecorder()
+
+			r.Header.Set("Authorization", "Bearer "+ut.adminToken)
+
+			ut.app.ServeHTTP(w, r)
+
+			var ru user.Info
+			if err := json.NewDecoder(w.Body).Decode(&ru); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", tests.Failed, testID, err)
+			}
+
+			exp := []string{auth.RoleAdmin, auth.RoleUser}
+			if diff := cmp.Diff(exp, ru.Roles); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould see the added role. Diff:\n%s", tests.Failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould see the added role.", tests.Success, testID)
+		}
+	}
+
+	t.Logf("\tTest %d:\tWhen adding an unknown role.", 1)
+	{
+		body := `{"roles": ["ADMIN", "ROOT"]}`
+
+		r := httptest.NewRequest(http.MethodPatch, "/v1/users/"+id, strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		r.Header.Set("Authorization", "Bearer "+ut.adminToken)
+		r.Header.Set("Content-Type", web.MergePatchContentType)
+
+		ut.app.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 400 for the response : %v", tests.Failed, 1, w.Code)
+		}
+		t.Logf("\t%s\tTest %d:\tShould receive a status code of 400 for the response.", tests.Success, 1)
+	}
+}
+
 // putUser403 validates that a user can't modify users unless they are an admin.
 func (ut *UserTests) putUser403(t *testing.T, id string) {
 	body := `{"name": "Andreas Amstutz"}`
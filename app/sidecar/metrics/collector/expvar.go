@@ -17,8 +17,9 @@ type Expvar struct {
 	client http.Client
 }
 
-// New creates a Expvar for collection metrics.
-func New(host string) (*Expvar, error) {
+// New creates a Expvar for collection metrics. Scrapes taking longer than
+// the timeout fail.
+func New(host string, timeout time.Duration) (*Expvar, error) {
 	tr := http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
@@ -37,7 +38,7 @@ func New(host string) (*Expvar, error) {
 		tr:   &tr,
 		client: http.Client{
 			Transport: &tr,
-			Timeout:   1 * time.Second,
+			Timeout:   timeout,
 		},
 	}
 
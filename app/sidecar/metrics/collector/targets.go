@@ -0,0 +1,208 @@
+package collector
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Target is a service metrics are collected from.
+type Target struct {
+	Name     string
+	URL      string
+	Labels   map[string]string
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+// Instance returns the host and port of the target.
+func (t Target) Instance() string {
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return t.URL
+	}
+	return u.Host
+}
+
+// NewTarget constructs a target named after the host of the URL, like
+// sales-api for http://sales-api:4000/debug/vars.
+func NewTarget(rawURL string, timeout time.Duration, interval time.Duration) (Target, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Target{}, errors.Wrapf(err, "parsing target url %q", rawURL)
+	}
+	if u.Host == "" {
+		return Target{}, errors.Errorf("target url %q has no host", rawURL)
+	}
+
+	t := Target{
+		Name:     u.Hostname(),
+		URL:      rawURL,
+		Timeout:  timeout,
+		Interval: interval,
+	}
+
+	return t, nil
+}
+
+// ReadTargets reads the targets of a JSON file like:
+//
+//	[
+//	  {"name": "sales-api", "url": "http://sales-api:4000/debug/vars", "labels": {"team": "sales"}},
+//	  {"url": "http://search:4000/debug/vars", "timeout": "2s", "interval": "30s"}
+//	]
+//
+// Targets without a name are named after their host. The timeout and
+// interval default to those given.
+func ReadTargets(path string, timeout time.Duration, interval time.Duration) ([]Target, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading targets")
+	}
+
+	var docs []struct {
+		Name     string            `json:"name"`
+		URL      string            `json:"url"`
+		Labels   map[string]string `json:"labels"`
+		Timeout  string            `json:"timeout"`
+		Interval string            `json:"interval"`
+	}
+	if err := json.Unmarshal(b, &docs); err != nil {
+		return nil, errors.Wrap(err, "decoding targets")
+	}
+
+	targets := make([]Target, 0, len(docs))
+	names := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		t, err := NewTarget(doc.URL, timeout, interval)
+		if err != nil {
+			return nil, err
+		}
+		if doc.Name != "" {
+			t.Name = doc.Name
+		}
+		t.Labels = doc.Labels
+
+		if doc.Timeout != "" {
+			if t.Timeout, err = time.ParseDuration(doc.Timeout); err != nil {
+				return nil, errors.Wrapf(err, "parsing timeout of target %q", t.Name)
+			}
+		}
+		if doc.Interval != "" {
+			if t.Interval, err = time.ParseDuration(doc.Interval); err != nil {
+				return nil, errors.Wrapf(err, "parsing interval of target %q", t.Name)
+			}
+		}
+		if t.Interval <= 0 {
+			return nil, errors.Errorf("target %q needs a positive interval", t.Name)
+		}
+
+		if names[t.Name] {
+			return nil, errors.Errorf("target %q listed twice", t.Name)
+		}
+		names[t.Name] = true
+
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// =============================================================================
+
+// TargetFile watches a file of targets, see ReadTargets.
+type TargetFile struct {
+	log      *slog.Logger
+	path     string
+	timeout  time.Duration
+	interval time.Duration
+	modTime  time.Time
+	size     int64
+	wg       sync.WaitGroup
+	shutdown chan struct{}
+}
+
+// WatchTargets reads the targets of the file and checks it for changes
+// every poll. The targets are passed to fn again each time it changes.
+// A change which can't be read is logged and the targets are kept.
+func WatchTargets(log *slog.Logger, path string, timeout time.Duration, interval time.Duration, poll time.Duration, fn func([]Target)) (*TargetFile, []Target, error) {
+	if poll <= 0 {
+		return nil, nil, errors.Errorf("targets of %q need a positive poll interval", path)
+	}
+
+	tf := TargetFile{
+		log:      log,
+		path:     path,
+		timeout:  timeout,
+		interval: interval,
+		shutdown: make(chan struct{}),
+	}
+
+	targets, err := tf.read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tf.wg.Add(1)
+	go func() {
+		defer tf.wg.Done()
+
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fi, err := os.Stat(tf.path)
+				if err != nil {
+					tf.log.Error("targets", "path", tf.path, "error", err)
+					continue
+				}
+				if fi.ModTime().Equal(tf.modTime) && fi.Size() == tf.size {
+					continue
+				}
+
+				targets, err := tf.read()
+				if err != nil {
+					tf.log.Error("targets", "path", tf.path, "error", err)
+					continue
+				}
+				tf.log.Info("targets", "status", "reloaded", "path", tf.path, "targets", len(targets))
+				fn(targets)
+
+			case <-tf.shutdown:
+				return
+			}
+		}
+	}()
+
+	return &tf, targets, nil
+}
+
+// Stop stops watching the file.
+func (tf *TargetFile) Stop() {
+	close(tf.shutdown)
+	tf.wg.Wait()
+}
+
+// read reads the targets and remembers the version of the file read.
+func (tf *TargetFile) read() ([]Target, error) {
+	fi, err := os.Stat(tf.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading targets")
+	}
+
+	targets, err := ReadTargets(tf.path, tf.timeout, tf.interval)
+	if err != nil {
+		return nil, err
+	}
+
+	// A file failing to read is tried again on the next poll.
+	tf.modTime, tf.size = fi.ModTime(), fi.Size()
+	return targets, nil
+}
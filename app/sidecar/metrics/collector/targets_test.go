@@ -0,0 +1,185 @@
+package collector_test
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tullo/service/app/sidecar/metrics/collector"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestReadTargets(t *testing.T) {
+	tt := []struct {
+		name string
+		json string
+		exp  []collector.Target
+	}{
+		{
+			"defaults",
+			`[{"url": "http://sales-api:4000/debug/vars"}]`,
+			[]collector.Target{
+				{Name: "sales-api", URL: "http://sales-api:4000/debug/vars", Timeout: time.Second, Interval: 10 * time.Second},
+			},
+		},
+		{
+			"overrides",
+			`[{"name": "sales", "url": "http://sales-api:4000/debug/vars", "labels": {"team": "sales"}, "timeout": "2s", "interval": "30s"}]`,
+			[]collector.Target{
+				{Name: "sales", URL: "http://sales-api:4000/debug/vars", Labels: map[string]string{"team": "sales"}, Timeout: 2 * time.Second, Interval: 30 * time.Second},
+			},
+		},
+		{
+			"several targets",
+			`[{"url": "http://sales-api:4000/debug/vars"}, {"url": "http://search:4000/debug/vars"}]`,
+			[]collector.Target{
+				{Name: "sales-api", URL: "http://sales-api:4000/debug/vars", Timeout: time.Second, Interval: 10 * time.Second},
+				{Name: "search", URL: "http://search:4000/debug/vars", Timeout: time.Second, Interval: 10 * time.Second},
+			},
+		},
+	}
+
+	failures := []struct {
+		name string
+		json string
+	}{
+		{"invalid JSON", `[{"url": }]`},
+		{"a URL without a host", `[{"url": "sales-api"}]`},
+		{"an invalid timeout", `[{"url": "http://sales-api:4000/debug/vars", "timeout": "2"}]`},
+		{"an invalid interval", `[{"url": "http://sales-api:4000/debug/vars", "interval": "soon"}]`},
+		{"a zero interval", `[{"url": "http://sales-api:4000/debug/vars", "interval": "0s"}]`},
+		{"a name listed twice", `[{"url": "http://sales-api:4000/debug/vars"}, {"url": "http://sales-api:5000/debug/vars"}]`},
+	}
+
+	dir := t.TempDir()
+
+	t.Log("Given the need to read the targets of a file.")
+	{
+		for testID, tc := range tt {
+			t.Logf("\tTest %d:\tWhen reading %s.", testID, tc.name)
+			{
+				path := filepath.Join(dir, "targets.json")
+				if err := os.WriteFile(path, []byte(tc.json), 0o644); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to write the file : %s.", failed, testID, err)
+				}
+
+				targets, err := collector.ReadTargets(path, time.Second, 10*time.Second)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to read the targets : %s.", failed, testID, err)
+				}
+				if !reflect.DeepEqual(targets, tc.exp) {
+					t.Fatalf("\t%s\tTest %d:\tShould get the targets : got %+v want %+v.", failed, testID, targets, tc.exp)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get the targets.", success, testID)
+			}
+		}
+
+		for i, tc := range failures {
+			testID := len(tt) + i
+			t.Logf("\tTest %d:\tWhen reading %s.", testID, tc.name)
+			{
+				path := filepath.Join(dir, "targets.json")
+				if err := os.WriteFile(path, []byte(tc.json), 0o644); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to write the file : %s.", failed, testID, err)
+				}
+
+				if _, err := collector.ReadTargets(path, time.Second, 10*time.Second); err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT be able to read the targets.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould NOT be able to read the targets.", success, testID)
+			}
+		}
+	}
+}
+
+func TestWatchTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.json")
+	write := func(json string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(json), 0o644); err != nil {
+			t.Fatalf("\t%s\tShould be able to write the file : %s.", failed, err)
+		}
+	}
+
+	write(`[{"url": "http://sales-api:4000/debug/vars"}]`)
+
+	reloads := make(chan []collector.Target, 10)
+	log := slog.New(slog.DiscardHandler)
+	tf, targets, err := collector.WatchTargets(log, path, time.Second, 10*time.Second, 10*time.Millisecond, func(targets []collector.Target) {
+		reloads <- targets
+	})
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to watch the file : %s.", failed, err)
+	}
+	t.Cleanup(tf.Stop)
+
+	t.Log("Given the need to reload the targets when their file changes.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen starting to watch the file.", testID)
+		{
+			if len(targets) != 1 || targets[0].Name != "sales-api" {
+				t.Fatalf("\t%s\tTest %d:\tShould get the targets of the file : got %+v.", failed, testID, targets)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the targets of the file.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen the file changes.", testID)
+		{
+			write(`[{"url": "http://sales-api:4000/debug/vars"}, {"url": "http://search:4000/debug/vars"}]`)
+
+			select {
+			case targets := <-reloads:
+				if len(targets) != 2 || targets[1].Name != "search" {
+					t.Fatalf("\t%s\tTest %d:\tShould get the new targets : got %+v.", failed, testID, targets)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("\t%s\tTest %d:\tShould get the new targets.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the new targets.", success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen the file can't be read.", testID)
+		{
+			write(`[{"url": }]`)
+
+			select {
+			case targets := <-reloads:
+				t.Fatalf("\t%s\tTest %d:\tShould keep the targets : got %+v.", failed, testID, targets)
+			case <-time.After(100 * time.Millisecond):
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep the targets.", success, testID)
+
+			write(`[{"url": "http://search:4000/debug/vars"}]`)
+
+			select {
+			case targets := <-reloads:
+				if len(targets) != 1 || targets[0].Name != "search" {
+					t.Fatalf("\t%s\tTest %d:\tShould get the targets once fixed : got %+v.", failed, testID, targets)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("\t%s\tTest %d:\tShould get the targets once fixed.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the targets once fixed.", success, testID)
+		}
+
+		testID = 3
+		t.Logf("\tTest %d:\tWhen the poll interval isn't positive.", testID)
+		{
+			if _, _, err := collector.WatchTargets(log, path, time.Second, 10*time.Second, 0, func([]collector.Target) {}); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould refuse to watch the file.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould refuse to watch the file.", success, testID)
+		}
+	}
+}
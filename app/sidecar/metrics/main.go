@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
@@ -13,50 +13,110 @@ import (
 	"github.com/pkg/errors"
 	"github.com/tullo/conf"
 	"github.com/tullo/service/app/sidecar/metrics/collector"
+	"github.com/tullo/service/app/sidecar/metrics/processor"
 	"github.com/tullo/service/app/sidecar/metrics/publisher"
+	"github.com/tullo/service/app/sidecar/metrics/publisher/datadog"
 	"github.com/tullo/service/app/sidecar/metrics/publisher/expvar"
+	"github.com/tullo/service/app/sidecar/metrics/publisher/otlp"
+	"github.com/tullo/service/app/sidecar/metrics/publisher/remotewrite"
+	"github.com/tullo/service/app/sidecar/metrics/publisher/spool"
+	"github.com/tullo/service/app/sidecar/metrics/publisher/statsd"
+	"github.com/tullo/service/foundation/logger"
 )
 
 // build is the git version of this program. It is set using build flags in the makefile.
 var build = "develop"
 
 func main() {
-	log := log.New(os.Stdout, "METRICS : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
 
-	if err := run(log); err != nil {
-		log.Println("main: error:", err)
+	// The level is set once the configuration is parsed.
+	var level slog.LevelVar
+	log := logger.New(os.Stdout, "metrics", &level)
+
+	if err := run(log, &level); err != nil {
+		log.Error("startup", "error", err)
 		os.Exit(1)
 	}
 }
 
-func run(log *log.Logger) error {
+// config holds the configuration of the sidecar.
+type config struct {
+	conf.Version
+	Log struct {
+		Level string `conf:"default:info"`
+	}
+	Web struct {
+		DebugHost       string        `conf:"default:0.0.0.0:4001"`
+		ReadTimeout     time.Duration `conf:"default:5s"`
+		WriteTimeout    time.Duration `conf:"default:5s"`
+		ShutdownTimeout time.Duration `conf:"default:5s"`
+	}
+	Expvar struct {
+		Host            string        `conf:"default:0.0.0.0:3001"`
+		Route           string        `conf:"default:/metrics"`
+		ReadTimeout     time.Duration `conf:"default:5s"`
+		WriteTimeout    time.Duration `conf:"default:5s"`
+		ShutdownTimeout time.Duration `conf:"default:5s"`
+	}
+	Collect struct {
+		// A ;-separated list of the URLs scraped when there's no Targets
+		// file.
+		From    []string      `conf:"default:http://sales-api:4000/debug/vars"`
+		Targets string        `conf:""`
+		Timeout time.Duration `conf:"default:1s"`
+		// How often the Targets file is checked for changes.
+		Reload time.Duration `conf:"default:10s"`
+	}
+	Process struct {
+		// The lists are ;-separated, the rules are documented by
+		// processor.Config.
+		Counters []string `conf:"default:requests;errors;memstats.NumGC;memstats.Mallocs;memstats.Frees;memstats.TotalAlloc"`
+		Include  []string `conf:""`
+		Exclude  []string `conf:""`
+		Rename   []string `conf:""`
+	}
+	Publish struct {
+		// A ;-separated list of console, datadog, statsd,
+		// prometheus-remote-write and otlp.
+		To       []string      `conf:"default:console"`
+		Interval time.Duration `conf:"default:5s"`
+	}
+	Spool struct {
+		// The scrapes a publisher fails to deliver are kept in Dir, spooling
+		// is turned off when it's empty. The console isn't spooled.
+		Dir        string        `conf:"default:/tmp/metrics-spool"`
+		MaxBytes   int64         `conf:"default:67108864"`
+		MinBackoff time.Duration `conf:"default:1s"`
+		MaxBackoff time.Duration `conf:"default:1m"`
+	}
+	Datadog struct {
+		APIKey  string        `conf:"mask"`
+		Host    string        `conf:"default:https://api.datadoghq.com/api/v1/series"`
+		Timeout time.Duration `conf:"default:1s"`
+	}
+	Statsd struct {
+		Addr    string        `conf:"default:localhost:8125"`
+		Prefix  string        `conf:"default:sales"`
+		Timeout time.Duration `conf:"default:1s"`
+	}
+	RemoteWrite struct {
+		URL     string        `conf:"default:http://prometheus:9090/api/v1/write"`
+		Job     string        `conf:"default:sales-api"`
+		Timeout time.Duration `conf:"default:5s"`
+	}
+	OTLP struct {
+		Endpoint    string        `conf:"default:http://otel-collector:4318/v1/metrics"`
+		ServiceName string        `conf:"default:sales-api"`
+		Timeout     time.Duration `conf:"default:5s"`
+	}
+}
+
+func run(log *slog.Logger, level *slog.LevelVar) error {
 
 	// =========================================================================
 	// Configuration
 
-	var cfg struct {
-		conf.Version
-		Web struct {
-			DebugHost       string        `conf:"default:0.0.0.0:4001"`
-			ReadTimeout     time.Duration `conf:"default:5s"`
-			WriteTimeout    time.Duration `conf:"default:5s"`
-			ShutdownTimeout time.Duration `conf:"default:5s"`
-		}
-		Expvar struct {
-			Host            string        `conf:"default:0.0.0.0:3001"`
-			Route           string        `conf:"default:/metrics"`
-			ReadTimeout     time.Duration `conf:"default:5s"`
-			WriteTimeout    time.Duration `conf:"default:5s"`
-			ShutdownTimeout time.Duration `conf:"default:5s"`
-		}
-		Collect struct {
-			From string `conf:"default:http://sales-api:4000/debug/vars"`
-		}
-		Publish struct {
-			To       string        `conf:"default:console"`
-			Interval time.Duration `conf:"default:5s"`
-		}
-	}
+	var cfg config
 	cfg.Version.Version = build
 	cfg.Version.Description = "copyright information here"
 
@@ -80,21 +140,29 @@ func run(log *log.Logger) error {
 		return errors.Wrap(err, "parsing config")
 	}
 
+	lvl, err := logger.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return errors.Wrap(err, "parsing config")
+	}
+	level.Set(lvl)
+
 	out, err := conf.String(&cfg)
 	if err != nil {
 		return errors.Wrap(err, "generating config for output")
 	}
-	log.Printf("main: Config :\n%v\n", out)
+	log.Info("startup", "config", out)
 
 	// =========================================================================
 	// Start Debug Service. Not concerned with shutting this down when the
 	// application is being shutdown.
 	//
 	// /debug/pprof - Added to the default mux by the net/http/pprof package.
+	// /debug/vars - Added to the default mux by the expvar package, with the
+	// spool and drop counters.
 	go func() {
-		log.Printf("main: Debug Listening %s", cfg.Web.DebugHost)
+		log.Info("startup", "status", "debug router started", "host", cfg.Web.DebugHost)
 		if err := http.ListenAndServe(cfg.Web.DebugHost, http.DefaultServeMux); err != nil {
-			log.Printf("main: Debug Listener closed: %v", err)
+			log.Error("shutdown", "status", "debug router closed", "host", cfg.Web.DebugHost, "error", err)
 		}
 	}()
 
@@ -107,23 +175,75 @@ func run(log *log.Logger) error {
 	// =========================================================================
 	// Start collectors and publishers
 
-	// Initialize to allow for the collection of metrics.
-	collector, err := collector.New(cfg.Collect.From)
-	if err != nil {
-		return errors.Wrap(err, "starting collector")
+	// Run each publisher on its own goroutine, so a slow one can't hold up
+	// the others. The expvar publisher only keeps the data in memory. The
+	// scrapes a publisher fails to deliver are spooled to disk and replayed
+	// once it recovers.
+	publishers := []publisher.Publisher{exp.Publish}
+	for _, to := range cfg.Publish.To {
+		pub, err := newPublisher(log, to, &cfg)
+		if err != nil {
+			return errors.Wrapf(err, "starting %s publisher", to)
+		}
+
+		if to != publisher.TypeConsole && cfg.Spool.Dir != "" {
+			sp, err := spool.New(log, to, pub, spool.Config{
+				Dir:        cfg.Spool.Dir,
+				MaxBytes:   cfg.Spool.MaxBytes,
+				MinBackoff: cfg.Spool.MinBackoff,
+				MaxBackoff: cfg.Spool.MaxBackoff,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "starting %s spool", to)
+			}
+			defer sp.Stop()
+			pub = sp.Publish
+		}
+
+		isolated := publisher.NewIsolated(log, to, pub)
+		defer isolated.Stop()
+		publishers = append(publishers, isolated.Publish)
 	}
 
-	// Create a stdout publisher.
-	// TODO: Respect the cfg.publish.to config option.
-	stdout := publisher.NewStdout(log)
+	// Flatten the scraped data and derive the rates of the counters.
+	proc, err := processor.New(processor.Config{
+		Counters: cfg.Process.Counters,
+		Include:  cfg.Process.Include,
+		Exclude:  cfg.Process.Exclude,
+		Rename:   cfg.Process.Rename,
+	})
+	if err != nil {
+		return errors.Wrap(err, "parsing processing rules")
+	}
 
 	// Start the publisher to collect/publish metrics.
-	publish, err := publisher.New(log, collector, cfg.Publish.Interval, exp.Publish, stdout.Publish)
+	targets, err := staticTargets(&cfg)
+	if err != nil {
+		return errors.Wrap(err, "parsing targets")
+	}
+	publish, err := publisher.New(log, targets, proc, publishers...)
 	if err != nil {
 		return errors.Wrap(err, "starting publisher")
 	}
 	defer publish.Stop()
 
+	// Scrape the targets of the file instead, when there's one.
+	if cfg.Collect.Targets != "" {
+		tf, targets, err := collector.WatchTargets(log, cfg.Collect.Targets, cfg.Collect.Timeout, cfg.Publish.Interval, cfg.Collect.Reload, func(targets []collector.Target) {
+			if err := publish.Update(targets); err != nil {
+				log.Error("targets", "path", cfg.Collect.Targets, "error", err)
+			}
+		})
+		if err != nil {
+			return errors.Wrap(err, "watching targets")
+		}
+		defer tf.Stop()
+
+		if err := publish.Update(targets); err != nil {
+			return errors.Wrap(err, "starting publisher")
+		}
+	}
+
 	// =========================================================================
 	// Shutdown
 
@@ -133,6 +253,67 @@ func run(log *log.Logger) error {
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 	<-shutdown
 
-	log.Println("main: Start shutdown...")
+	log.Info("shutdown", "status", "shutdown started")
 	return nil
 }
+
+// staticTargets returns the targets of the URLs to collect from.
+func staticTargets(cfg *config) ([]collector.Target, error) {
+	if cfg.Collect.Targets != "" {
+		return nil, nil
+	}
+
+	targets := make([]collector.Target, 0, len(cfg.Collect.From))
+	names := make(map[string]bool, len(cfg.Collect.From))
+	for _, from := range cfg.Collect.From {
+		t, err := collector.NewTarget(from, cfg.Collect.Timeout, cfg.Publish.Interval)
+		if err != nil {
+			return nil, err
+		}
+		if t.Interval <= 0 {
+			return nil, errors.Errorf("target %q needs a positive interval", t.Name)
+		}
+
+		// Targets on the same host are told apart by their port.
+		if names[t.Name] {
+			t.Name = t.Instance()
+		}
+		if names[t.Name] {
+			return nil, errors.Errorf("target %q listed twice", t.Name)
+		}
+		names[t.Name] = true
+
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// newPublisher constructs the publisher of a type.
+func newPublisher(log *slog.Logger, typ string, cfg *config) (publisher.Publisher, error) {
+	switch typ {
+	case publisher.TypeConsole:
+		return publisher.NewStdout(log).Publish, nil
+
+	case publisher.TypeDatadog:
+		if cfg.Datadog.APIKey == "" {
+			return nil, errors.New("datadog api key missing")
+		}
+		return datadog.New(log, cfg.Datadog.APIKey, cfg.Datadog.Host, cfg.Datadog.Timeout).Publish, nil
+
+	case publisher.TypeStatsd:
+		return statsd.New(log, cfg.Statsd.Addr, cfg.Statsd.Prefix, cfg.Statsd.Timeout).Publish, nil
+
+	case publisher.TypeRemoteWrite:
+		return remotewrite.New(log, cfg.RemoteWrite.URL, cfg.RemoteWrite.Job, cfg.RemoteWrite.Timeout).Publish, nil
+
+	case publisher.TypeOTLP:
+		o, err := otlp.New(log, cfg.OTLP.Endpoint, cfg.OTLP.ServiceName, cfg.OTLP.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		return o.Publish, nil
+	}
+
+	return nil, errors.Errorf("unknown publisher type %q", typ)
+}
@@ -0,0 +1,211 @@
+// Package processor prepares the scraped metrics before they're published.
+package processor
+
+import (
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
+)
+
+// Config holds the rules of the processing. The names of the metrics are
+// matched against shell patterns, like memstats.* or *.rate.
+type Config struct {
+
+	// Counters are the metrics whose delta since the previous scrape is
+	// published as <name>.delta, and its rate per second as <name>.rate.
+	Counters []string
+
+	// Only the metrics matching one of the Include patterns are published,
+	// all of them when there's none. Those matching one of the Exclude
+	// patterns are dropped.
+	Include []string
+	Exclude []string
+
+	// Rename holds rules like memstats=go.mem, renaming a metric and those
+	// nested within it. The first matching rule applies.
+	Rename []string
+}
+
+// rename replaces the name of a metric, or the prefix of those nested
+// within it.
+type rename struct {
+	from string
+	to   string
+}
+
+// sample is the value of a counter at a time.
+type sample struct {
+	value float64
+	time  time.Time
+}
+
+// Processor flattens the scraped data, derives the deltas and rates of the
+// counters and applies the include, exclude and rename rules.
+type Processor struct {
+	counters []string
+	include  []string
+	exclude  []string
+	renames  []rename
+
+	mu   sync.Mutex
+	last map[string]map[string]sample
+}
+
+// New constructs a processor applying the rules of the config.
+func New(cfg Config) (*Processor, error) {
+	p := Processor{
+		counters: cfg.Counters,
+		include:  cfg.Include,
+		exclude:  cfg.Exclude,
+		last:     make(map[string]map[string]sample),
+	}
+
+	patterns := [][]string{cfg.Counters, cfg.Include, cfg.Exclude}
+	for _, list := range patterns {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, errors.Wrapf(err, "parsing pattern %q", pattern)
+			}
+		}
+	}
+
+	for _, rule := range cfg.Rename {
+		from, to, ok := strings.Cut(rule, "=")
+		if !ok || from == "" || to == "" {
+			return nil, errors.Errorf("rename rule %q isn't like from=to", rule)
+		}
+		p.renames = append(p.renames, rename{from: from, to: to})
+	}
+
+	return &p, nil
+}
+
+// Process returns the scrape with its data processed. The data of the
+// scrape given isn't changed.
+func (p *Processor) Process(scrape publisher.Scrape) publisher.Scrape {
+	data := make(map[string]interface{}, len(scrape.Data))
+	flatten(data, "", scrape.Data)
+
+	p.derive(data, scrape.Target, scrape.Time)
+
+	out := make(map[string]interface{}, len(data))
+	for name, v := range data {
+		if !p.wanted(name) {
+			continue
+		}
+		out[p.rename(name)] = v
+	}
+
+	scrape.Data = out
+	return scrape
+}
+
+// Forget drops the counters kept for the target, like when it stopped being
+// scraped.
+func (p *Processor) Forget(target string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.last, target)
+}
+
+// derive adds the deltas and rates of the counters since the previous
+// scrape of the target. There are none on the first scrape, nor when the
+// counter went down because the target restarted.
+func (p *Processor) derive(data map[string]interface{}, target string, now time.Time) {
+	if len(p.counters) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	last, ok := p.last[target]
+	if !ok {
+		last = make(map[string]sample)
+		p.last[target] = last
+	}
+
+	// The derived metrics are added once all are computed, so counter
+	// patterns like memstats.* don't match them as well.
+	derived := make(map[string]interface{})
+	for name, v := range data {
+		value, ok := v.(float64)
+		if !ok || !match(p.counters, name) {
+			continue
+		}
+
+		prev, ok := last[name]
+		last[name] = sample{value: value, time: now}
+		if !ok || value < prev.value {
+			continue
+		}
+
+		delta := value - prev.value
+		derived[name+".delta"] = delta
+		if secs := now.Sub(prev.time).Seconds(); secs > 0 {
+			derived[name+".rate"] = delta / secs
+		}
+	}
+
+	for name, v := range derived {
+		data[name] = v
+	}
+}
+
+// wanted reports whether the metric passes the include and exclude rules.
+func (p *Processor) wanted(name string) bool {
+	if len(p.include) > 0 && !match(p.include, name) {
+		return false
+	}
+	return !match(p.exclude, name)
+}
+
+// rename applies the first rename rule matching the metric.
+func (p *Processor) rename(name string) string {
+	for _, r := range p.renames {
+		if name == r.from {
+			return r.to
+		}
+		if strings.HasPrefix(name, r.from+".") {
+			return r.to + strings.TrimPrefix(name, r.from)
+		}
+	}
+	return name
+}
+
+// =============================================================================
+
+// flatten copies the values of the nested objects of src into dst, under
+// dotted names like memstats.Alloc. Lists, like cmdline or
+// memstats.BySize, are dropped.
+func flatten(dst map[string]interface{}, prefix string, src map[string]interface{}) {
+	for k, v := range src {
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+
+		switch v := v.(type) {
+		case map[string]interface{}:
+			flatten(dst, name, v)
+		case []interface{}:
+		default:
+			dst[name] = v
+		}
+	}
+}
+
+// match reports whether the name matches one of the patterns.
+func match(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
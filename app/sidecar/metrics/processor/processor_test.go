@@ -0,0 +1,175 @@
+package processor_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tullo/service/app/sidecar/metrics/processor"
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// scrape is the data of a scrape of the sales-api, taken secs after the
+// first one.
+type scrape struct {
+	secs int
+	data map[string]interface{}
+}
+
+func TestProcess(t *testing.T) {
+	tt := []struct {
+		name    string
+		cfg     processor.Config
+		scrapes []scrape
+		exp     map[string]interface{}
+	}{
+		{
+			"a single scrape",
+			processor.Config{},
+			[]scrape{
+				{0, map[string]interface{}{"goroutines": 8.0, "memstats": map[string]interface{}{"Alloc": 1.0, "BySize": []interface{}{1.0}}}},
+			},
+			map[string]interface{}{"goroutines": 8.0, "memstats.Alloc": 1.0},
+		},
+		{
+			"two scrapes of a counter",
+			processor.Config{Counters: []string{"requests"}},
+			[]scrape{
+				{0, map[string]interface{}{"requests": 10.0}},
+				{10, map[string]interface{}{"requests": 30.0}},
+			},
+			map[string]interface{}{"requests": 30.0, "requests.delta": 20.0, "requests.rate": 2.0},
+		},
+		{
+			"scrapes of counters matched by a pattern",
+			processor.Config{Counters: []string{"memstats.*"}},
+			[]scrape{
+				{0, map[string]interface{}{"memstats": map[string]interface{}{"NumGC": 1.0, "Frees": 10.0}}},
+				{2, map[string]interface{}{"memstats": map[string]interface{}{"NumGC": 3.0, "Frees": 20.0}}},
+				{4, map[string]interface{}{"memstats": map[string]interface{}{"NumGC": 5.0, "Frees": 40.0}}},
+			},
+			map[string]interface{}{
+				"memstats.NumGC": 5.0, "memstats.NumGC.delta": 2.0, "memstats.NumGC.rate": 1.0,
+				"memstats.Frees": 40.0, "memstats.Frees.delta": 20.0, "memstats.Frees.rate": 10.0,
+			},
+		},
+		{
+			"a counter which went down",
+			processor.Config{Counters: []string{"requests"}},
+			[]scrape{
+				{0, map[string]interface{}{"requests": 10.0}},
+				{10, map[string]interface{}{"requests": 30.0}},
+				{20, map[string]interface{}{"requests": 5.0}},
+			},
+			map[string]interface{}{"requests": 5.0},
+		},
+		{
+			"include and exclude patterns",
+			processor.Config{Include: []string{"memstats.*", "goroutines"}, Exclude: []string{"memstats.Frees"}},
+			[]scrape{
+				{0, map[string]interface{}{"goroutines": 8.0, "errors": 1.0, "memstats": map[string]interface{}{"Alloc": 1.0, "Frees": 2.0}}},
+			},
+			map[string]interface{}{"goroutines": 8.0, "memstats.Alloc": 1.0},
+		},
+		{
+			"rename rules",
+			processor.Config{Rename: []string{"memstats=go.mem", "goroutines=go.goroutines"}},
+			[]scrape{
+				{0, map[string]interface{}{"goroutines": 8.0, "memstatsx": 3.0, "memstats": map[string]interface{}{"Alloc": 1.0, "GC": map[string]interface{}{"Num": 2.0}}}},
+			},
+			map[string]interface{}{"go.goroutines": 8.0, "memstatsx": 3.0, "go.mem.Alloc": 1.0, "go.mem.GC.Num": 2.0},
+		},
+		{
+			"rules applied to the derived metrics",
+			processor.Config{Counters: []string{"requests"}, Exclude: []string{"*.delta"}, Rename: []string{"requests=http.requests"}},
+			[]scrape{
+				{0, map[string]interface{}{"requests": 10.0}},
+				{5, map[string]interface{}{"requests": 20.0}},
+			},
+			map[string]interface{}{"http.requests": 20.0, "http.requests.rate": 2.0},
+		},
+	}
+
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Log("Given the need to process the scraped metrics.")
+	{
+		for testID, tc := range tt {
+			t.Logf("\tTest %d:\tWhen processing %s.", testID, tc.name)
+			{
+				p, err := processor.New(tc.cfg)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to construct a processor : %s.", failed, testID, err)
+				}
+
+				var got publisher.Scrape
+				for _, s := range tc.scrapes {
+					got = p.Process(publisher.Scrape{
+						Target: "sales-api",
+						Time:   start.Add(time.Duration(s.secs) * time.Second),
+						Data:   s.data,
+					})
+				}
+
+				if !reflect.DeepEqual(got.Data, tc.exp) {
+					t.Fatalf("\t%s\tTest %d:\tShould get the metrics : got %v want %v.", failed, testID, got.Data, tc.exp)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get the metrics.", success, testID)
+			}
+		}
+	}
+}
+
+func TestForget(t *testing.T) {
+	t.Log("Given the need to forget the counters of a target.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a target is scraped again after being forgotten.", testID)
+		{
+			p, err := processor.New(processor.Config{Counters: []string{"requests"}})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct a processor : %s.", failed, testID, err)
+			}
+
+			now := time.Now()
+			p.Process(publisher.Scrape{Target: "sales-api", Time: now, Data: map[string]interface{}{"requests": 10.0}})
+			p.Forget("sales-api")
+
+			got := p.Process(publisher.Scrape{Target: "sales-api", Time: now.Add(time.Second), Data: map[string]interface{}{"requests": 20.0}})
+			if _, ok := got.Data["requests.delta"]; ok {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT get a delta : got %v.", failed, testID, got.Data)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT get a delta.", success, testID)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tt := []struct {
+		name string
+		cfg  processor.Config
+	}{
+		{"an invalid pattern", processor.Config{Include: []string{"memstats.["}}},
+		{"a rename rule without a target", processor.Config{Rename: []string{"memstats="}}},
+		{"a rename rule without a =", processor.Config{Rename: []string{"memstats"}}},
+	}
+
+	t.Log("Given the need to validate the rules of the processing.")
+	{
+		for testID, tc := range tt {
+			t.Logf("\tTest %d:\tWhen configuring %s.", testID, tc.name)
+			{
+				if _, err := processor.New(tc.cfg); err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT be able to construct a processor.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould NOT be able to construct a processor.", success, testID)
+			}
+		}
+	}
+}
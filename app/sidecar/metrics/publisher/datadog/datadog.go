@@ -5,23 +5,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"sort"
 	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
 )
 
 // Datadog provides the ability to publish metrics to Datadog.
 type Datadog struct {
-	log    *log.Logger
+	log    *slog.Logger
 	apiKey string
 	host   string
 	tr     *http.Transport
 	client http.Client
 }
 
-// New initializes Datadog access for publishing metrics.
-func New(log *log.Logger, apiKey string, host string) *Datadog {
+// New initializes Datadog access for publishing metrics. Requests taking
+// longer than the timeout are canceled.
+func New(log *slog.Logger, apiKey string, host string, timeout time.Duration) *Datadog {
 	tr := http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
@@ -42,7 +47,7 @@ func New(log *log.Logger, apiKey string, host string) *Datadog {
 		tr:     &tr,
 		client: http.Client{
 			Transport: &tr,
-			Timeout:   1 * time.Second,
+			Timeout:   timeout,
 		},
 	}
 
@@ -50,23 +55,23 @@ func New(log *log.Logger, apiKey string, host string) *Datadog {
 }
 
 // Publish handles the processing of metrics for deliver to the DataDog.
-func (d *Datadog) Publish(data map[string]interface{}) {
-	doc, err := marshal(d.log, data)
+func (d *Datadog) Publish(scrape publisher.Scrape) error {
+	doc, err := marshal(d.log, scrape)
 	if err != nil {
-		d.log.Println("datadog.publish :", err)
-		return
+		return errors.Wrap(err, "datadog")
 	}
 
 	if err := send(d, doc); err != nil {
-		d.log.Println("datadog.publish :", err)
-		return
+		return errors.Wrap(err, "datadog")
 	}
 
-	log.Println("datadog.publish : published :", string(doc))
+	d.log.Debug("datadog.publish", "status", "published", "series", json.RawMessage(doc))
+	return nil
 }
 
-// marshal converts the data map to datadog JSON document.
-func marshal(log *log.Logger, data map[string]interface{}) ([]byte, error) {
+// marshal converts the scraped data to datadog JSON document. The series
+// are tagged with the environment and the tags of the scrape.
+func marshal(log *slog.Logger, scrape publisher.Scrape) ([]byte, error) {
 	/*
 		{ "series" : [
 				{
@@ -88,6 +93,7 @@ func marshal(log *log.Logger, data map[string]interface{}) ([]byte, error) {
 	*/
 
 	// Extract the base keys/values.
+	data := scrape.Data
 	mType := "gauge"
 	host, ok := data["host"].(string)
 	if !ok {
@@ -97,7 +103,11 @@ func marshal(log *log.Logger, data map[string]interface{}) ([]byte, error) {
 	if host != "localhost" {
 		env = "prod"
 	}
-	envTag := "environment:" + env
+	tags := []string{"environment:" + env}
+	for k, v := range scrape.Tags() {
+		tags = append(tags, k+":"+v)
+	}
+	sort.Strings(tags[1:])
 
 	// Define the Datadog data format.
 	type series struct {
@@ -112,15 +122,16 @@ func marshal(log *log.Logger, data map[string]interface{}) ([]byte, error) {
 	var doc struct {
 		Series []series `json:"series"`
 	}
+	now := scrape.Time.Unix()
 	for key, value := range data {
 		switch value.(type) {
 		case int, float64:
 			doc.Series = append(doc.Series, series{
 				Metric: env + "." + key,
-				Points: [][]interface{}{{"$currenttime", value}},
+				Points: [][]interface{}{{now, value}},
 				Type:   mType,
 				Host:   host,
-				Tags:   []string{envTag},
+				Tags:   tags,
 			})
 		}
 	}
@@ -128,7 +139,7 @@ func marshal(log *log.Logger, data map[string]interface{}) ([]byte, error) {
 	// Convert the data into JSON.
 	out, err := json.MarshalIndent(doc, "", "    ")
 	if err != nil {
-		log.Println("datadog.publish : marshaling :", err)
+		log.Error("datadog.publish", "status", "marshaling", "error", err)
 		return nil, err
 	}
 
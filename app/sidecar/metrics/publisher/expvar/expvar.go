@@ -3,27 +3,29 @@ package expvar
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
 )
 
 // Expvar provides our basic publishing.
 type Expvar struct {
-	log    *log.Logger
+	log    *slog.Logger
 	server http.Server
-	data   map[string]interface{}
+	data   map[string]map[string]interface{}
 	mu     sync.Mutex
 }
 
 // New starts a service for consuming the raw expvar stats.
-func New(log *log.Logger, host string, route string, readTimeout, writeTimeout time.Duration) *Expvar {
+func New(log *slog.Logger, host string, route string, readTimeout, writeTimeout time.Duration) *Expvar {
 	mux := chi.NewRouter()
 	exp := Expvar{
-		log: log,
+		log:  log,
+		data: make(map[string]map[string]interface{}),
 		server: http.Server{
 			Addr:           host,
 			Handler:        mux,
@@ -40,9 +42,9 @@ func New(log *log.Logger, host string, route string, readTimeout, writeTimeout t
 	mux.MethodFunc(http.MethodGet, route, exp.handler)
 
 	go func() {
-		log.Println("expvar : API Listening", host)
-		if err := exp.server.ListenAndServe(); err != nil {
-			log.Println("expvar : ERROR :", err)
+		log.Info("expvar", "status", "api router started", "host", host)
+		if err := exp.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("expvar", "status", "api router closed", "error", err)
 		}
 	}()
 
@@ -51,8 +53,8 @@ func New(log *log.Logger, host string, route string, readTimeout, writeTimeout t
 
 // Stop shuts down the service.
 func (exp *Expvar) Stop(shutdownTimeout time.Duration) {
-	exp.log.Println("expvar : Start shutdown...")
-	defer exp.log.Println("expvar : Completed")
+	exp.log.Info("expvar", "status", "shutdown started")
+	defer exp.log.Info("expvar", "status", "shutdown complete")
 
 	// Create context for Shutdown call.
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
@@ -60,43 +62,58 @@ func (exp *Expvar) Stop(shutdownTimeout time.Duration) {
 
 	// Asking listener to shutdown and load shed.
 	if err := exp.server.Shutdown(ctx); err != nil {
-		exp.log.Printf("expvar : Graceful shutdown did not complete in %v : %v", shutdownTimeout, err)
+		exp.log.Error("expvar", "status", "graceful shutdown did not complete", "timeout", shutdownTimeout, "error", err)
 		if err := exp.server.Close(); err != nil {
-			exp.log.Fatalf("expvar : Could not stop http server: %v", err)
+			exp.log.Error("expvar", "status", "could not stop http server", "error", err)
 		}
 	}
 }
 
-// Publish is called by the publisher goroutine and saves the raw stats.
-func (exp *Expvar) Publish(data map[string]interface{}) {
+// Publish is called by the publisher goroutines and saves the stats of
+// the target.
+func (exp *Expvar) Publish(scrape publisher.Scrape) error {
 	exp.mu.Lock()
 	{
-		exp.data = data
+		exp.data[scrape.Target] = scrape.Data
 	}
 	exp.mu.Unlock()
 
-	exp.log.Printf("expvar.publish : saved stats")
+	exp.log.Debug("expvar.publish", "status", "saved stats", "target", scrape.Target)
+
+	return nil
 }
 
-// handler is what consumers call to get the raw stats.
+// handler is what consumers call to get the stats, keyed by target. The
+// stats of a single target are returned when it's named by the target query
+// parameter.
 func (exp *Expvar) handler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	//w.WriteHeader(http.StatusOK)
 
-	var data map[string]interface{}
+	var data interface{}
+	target := r.URL.Query().Get("target")
 	exp.mu.Lock()
 	{
-		data = exp.data
+		if target != "" {
+			data = exp.data[target]
+		} else {
+			all := make(map[string]map[string]interface{}, len(exp.data))
+			for k, v := range exp.data {
+				all[k] = v
+			}
+			data = all
+		}
 	}
 	exp.mu.Unlock()
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		exp.log.Println("expvar : ERROR :", err)
+		exp.log.Error("expvar", "status", "encoding stats", "error", err)
 	}
 
-	log.Printf("expvar : (%d) : %s %s -> %s",
-		http.StatusOK,
-		r.Method, r.URL.Path,
-		r.RemoteAddr,
+	exp.log.Info("expvar", "status", "request completed",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"remote_addr", r.RemoteAddr,
+		"code", http.StatusOK,
 	)
 }
@@ -0,0 +1,106 @@
+package otlp
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+)
+
+// OTLP provides the ability to publish metrics to an OpenTelemetry
+// Collector over OTLP/HTTP.
+type OTLP struct {
+	log      *slog.Logger
+	timeout  time.Duration
+	exporter *otlpmetrichttp.Exporter
+	resource *resource.Resource
+}
+
+// New initializes OTLP access for publishing metrics. The endpoint is a URL
+// like http://otel-collector:4318/v1/metrics, the OTEL_EXPORTER_OTLP_*
+// environment variables are used when it's empty. The metrics are reported
+// as those of the service.
+func New(log *slog.Logger, endpoint string, service string, timeout time.Duration) (*OTLP, error) {
+	opts := []otlpmetrichttp.Option{otlpmetrichttp.WithTimeout(timeout)}
+	if endpoint != "" {
+		opts = append(opts, otlpmetrichttp.WithEndpointURL(endpoint))
+	}
+
+	exp, err := otlpmetrichttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating exporter")
+	}
+
+	o := OTLP{
+		log:      log,
+		timeout:  timeout,
+		exporter: exp,
+		resource: resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(service)),
+	}
+
+	return &o, nil
+}
+
+// Publish sends the numbers of the scraped data as gauges with the tags of
+// the scrape as attributes.
+func (o *OTLP) Publish(scrape publisher.Scrape) error {
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	rm := o.marshal(publisher.Numbers(scrape.Data), attributes(scrape.Tags()), scrape.Time)
+	if err := o.exporter.Export(ctx, &rm); err != nil {
+		return errors.Wrap(err, "otlp")
+	}
+
+	o.log.Debug("otlp.publish", "status", "published")
+	return nil
+}
+
+// marshal converts the numbers to gauges of the time.
+func (o *OTLP) marshal(nums map[string]float64, attrs attribute.Set, now time.Time) metricdata.ResourceMetrics {
+	keys := make([]string, 0, len(nums))
+	for k := range nums {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	metrics := make([]metricdata.Metrics, 0, len(keys))
+	for _, k := range keys {
+		metrics = append(metrics, metricdata.Metrics{
+			Name: k,
+			Data: metricdata.Gauge[float64]{
+				DataPoints: []metricdata.DataPoint[float64]{
+					{Attributes: attrs, Time: now, Value: nums[k]},
+				},
+			},
+		})
+	}
+
+	return metricdata.ResourceMetrics{
+		Resource: o.resource,
+		ScopeMetrics: []metricdata.ScopeMetrics{
+			{
+				Scope:   instrumentation.Scope{Name: "github.com/tullo/service/app/sidecar/metrics"},
+				Metrics: metrics,
+			},
+		},
+	}
+}
+
+// attributes converts the tags to a set of attributes.
+func attributes(tags map[string]string) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(tags))
+	for k, v := range tags {
+		kvs = append(kvs, attribute.String(k, v))
+	}
+	return attribute.NewSet(kvs...)
+}
@@ -0,0 +1,115 @@
+package otlp_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
+	"github.com/tullo/service/app/sidecar/metrics/publisher/otlp"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestOTLP(t *testing.T) {
+	log := slog.New(slog.DiscardHandler)
+
+	t.Log("Given the need to publish metrics to an OpenTelemetry Collector.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen publishing a scrape.", testID)
+		{
+			bodies := make(chan []byte, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				bodies <- body
+				w.Header().Set("Content-Type", "application/x-protobuf")
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			o, err := otlp.New(log, srv.URL+"/v1/metrics", "sales-api", time.Second)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create the publisher : %s.", failed, testID, err)
+			}
+
+			now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+			scrape := publisher.Scrape{
+				Target:   "sales-api",
+				Instance: "sales-api:4000",
+				Time:     now,
+				Data: map[string]interface{}{
+					"goroutines":     8.0,
+					"memstats.Alloc": 1024.0,
+					"cmdline":        []interface{}{"sales-api"},
+				},
+			}
+			if err := o.Publish(scrape); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to publish the scrape : %s.", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to publish the scrape.", success, testID)
+
+			var req collectorpb.ExportMetricsServiceRequest
+			if err := proto.Unmarshal(<-bodies, &req); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to decode the request : %s.", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to decode the request.", success, testID)
+
+			rm := req.GetResourceMetrics()
+			if len(rm) != 1 || len(rm[0].GetScopeMetrics()) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould send the metrics of one scope : got %v.", failed, testID, rm)
+			}
+
+			var service string
+			for _, kv := range rm[0].GetResource().GetAttributes() {
+				if kv.GetKey() == "service.name" {
+					service = kv.GetValue().GetStringValue()
+				}
+			}
+			if service != "sales-api" {
+				t.Fatalf("\t%s\tTest %d:\tShould report the metrics of the service : got %q.", failed, testID, service)
+			}
+			t.Logf("\t%s\tTest %d:\tShould report the metrics of the service.", success, testID)
+
+			got := make(map[string]float64)
+			for _, m := range rm[0].GetScopeMetrics()[0].GetMetrics() {
+				points := m.GetGauge().GetDataPoints()
+				if len(points) != 1 {
+					t.Fatalf("\t%s\tTest %d:\tShould send a gauge point for %q : got %d.", failed, testID, m.GetName(), len(points))
+				}
+				p := points[0]
+				if p.GetTimeUnixNano() != uint64(now.UnixNano()) {
+					t.Fatalf("\t%s\tTest %d:\tShould send the time of the scrape for %q : got %d.", failed, testID, m.GetName(), p.GetTimeUnixNano())
+				}
+
+				attrs := make(map[string]string)
+				for _, kv := range p.GetAttributes() {
+					attrs[kv.GetKey()] = kv.GetValue().GetStringValue()
+				}
+				if exp := map[string]string{"target": "sales-api", "instance": "sales-api:4000"}; !reflect.DeepEqual(attrs, exp) {
+					t.Fatalf("\t%s\tTest %d:\tShould tag %q with the scrape : got %v.", failed, testID, m.GetName(), attrs)
+				}
+				got[m.GetName()] = p.GetAsDouble()
+			}
+
+			if exp := map[string]float64{"goroutines": 8, "memstats.Alloc": 1024}; !reflect.DeepEqual(got, exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould send the numbers as gauges : got %v want %v.", failed, testID, got, exp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould send the numbers as tagged gauges.", success, testID)
+		}
+	}
+}
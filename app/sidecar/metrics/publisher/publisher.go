@@ -2,15 +2,25 @@ package publisher
 
 import (
 	"encoding/json"
-	"log"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/app/sidecar/metrics/collector"
 )
 
 // Set of possible publisher types.
 const (
-	TypeStdout  = "stdout"
-	TypeDatadog = "datadog"
+	TypeConsole     = "console"
+	TypeDatadog     = "datadog"
+	TypeStatsd      = "statsd"
+	TypeRemoteWrite = "prometheus-remote-write"
+	TypeOTLP        = "otlp"
 )
 
 // =============================================================================
@@ -21,94 +31,350 @@ type Collector interface {
 	Collect() (map[string]interface{}, error)
 }
 
+// Processor defines a contract a processor must support so the scraped
+// data can be prepared before it's published.
+type Processor interface {
+	Process(Scrape) Scrape
+	Forget(target string)
+}
+
 // =============================================================================
 
-// Publisher defines a handler function that will be called on each interval.
-type Publisher func(map[string]interface{})
+// Scrape is the data collected from a target at a time.
+type Scrape struct {
+	Target   string
+	Instance string
+	Labels   map[string]string
+	Time     time.Time
+	Data     map[string]interface{}
+}
+
+// Tags returns the labels of the target along with its target and
+// instance tags, which every published metric carries.
+func (s Scrape) Tags() map[string]string {
+	tags := make(map[string]string, len(s.Labels)+2)
+	for k, v := range s.Labels {
+		tags[k] = v
+	}
+	tags["target"] = s.Target
+	tags["instance"] = s.Instance
+	return tags
+}
+
+// Publisher defines a handler function that will be called for each scrape.
+// It fails when the scrape couldn't be delivered.
+type Publisher func(Scrape) error
 
-// Publish provides the ability to receive metrics on an interval.
+// Publish provides the ability to collect metrics from a set of targets,
+// each on its own interval, and publish them.
 type Publish struct {
-	log       *log.Logger
-	collector Collector
+	log       *slog.Logger
+	processor Processor
 	publisher []Publisher
 	wg        sync.WaitGroup
-	timer     *time.Timer
+
+	mu       sync.Mutex
+	scrapers map[string]*scraper
+}
+
+// scraper collects the metrics of a target on its interval.
+type scraper struct {
+	target    collector.Target
+	collector Collector
 	shutdown  chan struct{}
+	done      chan struct{}
 }
 
-// New creates a Publish for consuming and publishing metrics.
-func New(log *log.Logger, collector Collector, interval time.Duration, publisher ...Publisher) (*Publish, error) {
+// New creates a Publish for consuming and publishing the metrics of the
+// targets. The scrapes are processed before they're published.
+func New(log *slog.Logger, targets []collector.Target, processor Processor, publisher ...Publisher) (*Publish, error) {
 	p := Publish{
 		log:       log,
-		collector: collector,
+		processor: processor,
 		publisher: publisher,
-		timer:     time.NewTimer(interval),
-		shutdown:  make(chan struct{}),
+		scrapers:  make(map[string]*scraper),
+	}
+
+	if err := p.Update(targets); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Update replaces the targets being scraped. Targets which didn't change
+// keep being scraped on their interval.
+func (p *Publish) Update(targets []collector.Target) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	keep := make(map[string]collector.Target, len(targets))
+	for _, t := range targets {
+		keep[t.Name] = t
+	}
+
+	for name, s := range p.scrapers {
+		if t, ok := keep[name]; ok && reflect.DeepEqual(t, s.target) {
+			delete(keep, name)
+			continue
+		}
+		close(s.shutdown)
+		<-s.done
+		delete(p.scrapers, name)
+		p.processor.Forget(name)
+		p.log.Info("publish", "status", "target removed", "target", name)
 	}
 
+	for name, t := range keep {
+		c, err := collector.New(t.URL, t.Timeout)
+		if err != nil {
+			return errors.Wrapf(err, "starting collector of target %q", name)
+		}
+
+		s := scraper{
+			target:    t,
+			collector: c,
+			shutdown:  make(chan struct{}),
+			done:      make(chan struct{}),
+		}
+		p.scrapers[name] = &s
+		p.start(&s)
+		p.log.Info("publish", "status", "target added", "target", name, "url", t.URL, "interval", t.Interval)
+	}
+
+	return nil
+}
+
+// Stop is used to shutdown the goroutines collecting metrics.
+func (p *Publish) Stop() {
+	p.mu.Lock()
+	for _, s := range p.scrapers {
+		close(s.shutdown)
+	}
+	p.scrapers = make(map[string]*scraper)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+// start runs the goroutine scraping the target.
+func (p *Publish) start(s *scraper) {
 	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
+		defer close(s.done)
+
+		timer := time.NewTimer(s.target.Interval)
+		defer timer.Stop()
+
 		for {
-			p.timer.Reset(interval)
 			select {
-			case <-p.timer.C:
-				p.update()
-			case <-p.shutdown:
+			case <-timer.C:
+				p.update(s)
+				timer.Reset(s.target.Interval)
+			case <-s.shutdown:
 				return
 			}
 		}
 	}()
-
-	return &p, nil
-}
-
-// Stop is used to shutdown the goroutine collecting metrics.
-func (p *Publish) Stop() {
-	close(p.shutdown)
-	p.wg.Wait()
 }
 
-// update pulls the metrics and publishes them to the specified system.
-func (p *Publish) update() {
-	data, err := p.collector.Collect()
+// update pulls the metrics of the target and publishes them to the
+// specified systems.
+func (p *Publish) update(s *scraper) {
+	data, err := s.collector.Collect()
 	if err != nil {
-		p.log.Println(err)
+		p.log.Error("collecting metrics", "target", s.target.Name, "error", err)
 		return
 	}
 
+	scrape := p.processor.Process(Scrape{
+		Target:   s.target.Name,
+		Instance: s.target.Instance(),
+		Labels:   s.target.Labels,
+		Time:     time.Now(),
+		Data:     data,
+	})
+
 	for _, pub := range p.publisher {
-		pub(data)
+		if err := pub(scrape); err != nil {
+			p.log.Error("publish", "target", scrape.Target, "error", err)
+		}
+	}
+}
+
+// =============================================================================
+
+// dropped counts the scrapes each isolated publisher had to skip because
+// it was still busy with an earlier one.
+var dropped = expvar.NewMap("publisher_dropped")
+
+// Isolated runs a publisher on its own goroutine, so a slow or failing
+// publisher can't hold up the others. While it's busy only the latest scrape
+// of each target is kept waiting, older ones are dropped.
+type Isolated struct {
+	log    *slog.Logger
+	name   string
+	pub    Publisher
+	wg     sync.WaitGroup
+	signal chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]Scrape
+	order   []string
+	stopped bool
+}
+
+// NewIsolated starts the goroutine running the publisher.
+func NewIsolated(log *slog.Logger, name string, pub Publisher) *Isolated {
+	i := Isolated{
+		log:     log,
+		name:    name,
+		pub:     pub,
+		signal:  make(chan struct{}, 1),
+		pending: make(map[string]Scrape),
+	}
+
+	i.wg.Add(1)
+	go func() {
+		defer i.wg.Done()
+		for range i.signal {
+			for {
+				scrape, ok := i.next()
+				if !ok {
+					break
+				}
+				i.publish(scrape)
+			}
+		}
+	}()
+
+	return &i
+}
+
+// Publish hands a copy of the scrape to the goroutine without waiting for
+// it. The failures of the publisher are logged by the goroutine.
+func (i *Isolated) Publish(scrape Scrape) error {
+	data := make(map[string]interface{}, len(scrape.Data))
+	for k, v := range scrape.Data {
+		data[k] = v
+	}
+	scrape.Data = data
+
+	i.mu.Lock()
+	if i.stopped {
+		i.mu.Unlock()
+		return nil
+	}
+	if _, ok := i.pending[scrape.Target]; ok {
+		dropped.Add(i.name, 1)
+		i.log.Warn("publish", "publisher", i.name, "target", scrape.Target, "status", "busy, dropped metrics")
+	} else {
+		i.order = append(i.order, scrape.Target)
+	}
+	i.pending[scrape.Target] = scrape
+
+	select {
+	case i.signal <- struct{}{}:
+	default:
+	}
+	i.mu.Unlock()
+
+	return nil
+}
+
+// Stop waits for the scrapes waiting to be published and stops the
+// goroutine.
+func (i *Isolated) Stop() {
+	i.mu.Lock()
+	i.stopped = true
+	close(i.signal)
+	i.mu.Unlock()
+
+	i.wg.Wait()
+}
+
+// next takes the scrape waiting the longest.
+func (i *Isolated) next() (Scrape, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if len(i.order) == 0 {
+		return Scrape{}, false
+	}
+	target := i.order[0]
+	i.order = i.order[1:]
+
+	scrape := i.pending[target]
+	delete(i.pending, target)
+	return scrape, true
+}
+
+// publish runs the publisher, recovering from its panics.
+func (i *Isolated) publish(scrape Scrape) {
+	defer func() {
+		if r := recover(); r != nil {
+			i.log.Error("publish", "publisher", i.name, "target", scrape.Target, "error", fmt.Sprintf("panic: %v", r))
+		}
+	}()
+
+	if err := i.pub(scrape); err != nil {
+		i.log.Error("publish", "publisher", i.name, "target", scrape.Target, "error", err)
+	}
+}
+
+// Numbers returns the values of the data which are numbers.
+func Numbers(data map[string]interface{}) map[string]float64 {
+	nums := make(map[string]float64, len(data))
+	for k, v := range data {
+		switch n := v.(type) {
+		case float64:
+			nums[k] = n
+		case int:
+			nums[k] = float64(n)
+		case int64:
+			nums[k] = float64(n)
+		}
 	}
+	return nums
 }
 
 // =============================================================================
 
 // Stdout provide our basic publishing.
 type Stdout struct {
-	log *log.Logger
+	log *slog.Logger
 }
 
 // NewStdout initializes stdout for publishing metrics.
-func NewStdout(log *log.Logger) *Stdout {
+func NewStdout(log *slog.Logger) *Stdout {
 	return &Stdout{log}
 }
 
-// Publish publishers for writing to stdout.
-func (s *Stdout) Publish(data map[string]interface{}) {
-	// Add heap value into the data set.
-	memStats, ok := data["memstats"].(map[string]interface{})
-	if ok {
-		data["heap"] = memStats["Alloc"]
+// Publish publishers for writing to stdout. Only the heap of the memory
+// stats is written.
+func (s *Stdout) Publish(scrape Scrape) error {
+	data := make(map[string]interface{}, len(scrape.Data))
+	for k, v := range scrape.Data {
+		if k == "cmdline" || k == "memstats" || strings.HasPrefix(k, "memstats.") {
+			continue
+		}
+		data[k] = v
 	}
 
-	// Remove unnecessary keys.
-	delete(data, "memstats")
-	delete(data, "cmdline")
+	// Add heap value into the data set, the memory stats may be flattened.
+	if heap, ok := scrape.Data["memstats.Alloc"]; ok {
+		data["heap"] = heap
+	}
+	if memStats, ok := scrape.Data["memstats"].(map[string]interface{}); ok {
+		data["heap"] = memStats["Alloc"]
+	}
 
-	out, err := json.MarshalIndent(data, "", "    ")
+	out, err := json.Marshal(data)
 	if err != nil {
-		return
+		return errors.Wrap(err, "marshaling")
 	}
-	s.log.Println("STDOUT :\n", string(out))
+	s.log.Info("stdout", "target", scrape.Target, "instance", scrape.Instance, "metrics", json.RawMessage(out))
+
+	return nil
 }
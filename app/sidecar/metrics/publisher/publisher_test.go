@@ -0,0 +1,149 @@
+package publisher_test
+
+import (
+	"expvar"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// recorder is a publisher recording the scrapes it got, which blocks on the
+// first one until it's released.
+type recorder struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+
+	mu     sync.Mutex
+	scrape []string
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+// publish implements publisher.Publisher.
+func (r *recorder) publish(scrape publisher.Scrape) error {
+	r.once.Do(func() {
+		close(r.started)
+		<-r.release
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scrape = append(r.scrape, scrape.Target+"/"+scrape.Data["id"].(string))
+	return nil
+}
+
+// scrapes returns the scrapes published so far.
+func (r *recorder) scrapes() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.scrape...)
+}
+
+// scrape returns a scrape of the target which is identified by the id.
+func scrape(target string, id string) publisher.Scrape {
+	return publisher.Scrape{
+		Target: target,
+		Time:   time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Data:   map[string]interface{}{"id": id},
+	}
+}
+
+// dropped returns the scrapes the publisher dropped. The map outlives the
+// publishers, so counters are compared to their value before.
+func dropped(name string) int64 {
+	v, ok := expvar.Get("publisher_dropped").(*expvar.Map).Get(name).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return v.Value()
+}
+
+func TestIsolated(t *testing.T) {
+	log := slog.New(slog.DiscardHandler)
+
+	t.Log("Given the need to keep a busy publisher from holding up the others.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen scrapes arrive while the publisher is busy.", testID)
+		{
+			before := dropped("busy")
+
+			r := newRecorder()
+			iso := publisher.NewIsolated(log, "busy", r.publish)
+
+			if err := iso.Publish(scrape("a", "1")); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to publish the scrape : %s.", failed, testID, err)
+			}
+			select {
+			case <-r.started:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("\t%s\tTest %d:\tShould start publishing in time.", failed, testID)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				for _, s := range []publisher.Scrape{scrape("a", "2"), scrape("b", "1"), scrape("a", "3")} {
+					iso.Publish(s)
+				}
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("\t%s\tTest %d:\tShould not wait for the busy publisher.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not wait for the busy publisher.", success, testID)
+
+			if got := dropped("busy") - before; got != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould count the dropped scrape : got %d.", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould count the dropped scrape.", success, testID)
+
+			close(r.release)
+			iso.Stop()
+
+			if got, exp := r.scrapes(), []string{"a/1", "a/3", "b/1"}; !reflect.DeepEqual(got, exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould publish the latest scrape of each target : got %v want %v.", failed, testID, got, exp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould publish the latest scrape of each target.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen the publisher panics.", testID)
+		{
+			r := newRecorder()
+			close(r.release)
+			iso := publisher.NewIsolated(log, "panics", func(s publisher.Scrape) error {
+				if s.Target == "bad" {
+					panic("bad scrape")
+				}
+				return r.publish(s)
+			})
+
+			iso.Publish(scrape("bad", "1"))
+			iso.Publish(scrape("good", "1"))
+			iso.Stop()
+
+			if got, exp := r.scrapes(), []string{"good/1"}; !reflect.DeepEqual(got, exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould keep publishing : got %v want %v.", failed, testID, got, exp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep publishing.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/pkg/errors"
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// RemoteWrite provides the ability to publish metrics to an endpoint of the
+// Prometheus remote write protocol, like Prometheus, Mimir or Thanos.
+type RemoteWrite struct {
+	log     *slog.Logger
+	url     string
+	job     string
+	timeout time.Duration
+	client  http.Client
+}
+
+// New initializes remote write access for publishing metrics. Every series
+// is labelled with the job and the tags of its scrape.
+func New(log *slog.Logger, url string, job string, timeout time.Duration) *RemoteWrite {
+	return &RemoteWrite{
+		log:     log,
+		url:     url,
+		job:     job,
+		timeout: timeout,
+		client: http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Publish sends the numbers of the scraped data as samples of the time of
+// the scrape.
+func (rw *RemoteWrite) Publish(scrape publisher.Scrape) error {
+	labels := scrape.Tags()
+	labels["job"] = rw.job
+	req := marshal(labels, publisher.Numbers(scrape.Data), scrape.Time)
+
+	if err := rw.send(snappy.Encode(nil, req)); err != nil {
+		return errors.Wrap(err, "remotewrite")
+	}
+
+	rw.log.Debug("remotewrite.publish", "status", "published")
+	return nil
+}
+
+// send posts a compressed write request.
+func (rw *RemoteWrite) send(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rw.timeout)
+	defer cancel()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, rw.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/x-protobuf")
+	r.Header.Set("Content-Encoding", "snappy")
+	r.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := rw.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		out, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("status[%d] : %s", resp.StatusCode, out)
+	}
+
+	return nil
+}
+
+// marshal encodes a WriteRequest with one sample per number:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func marshal(labels map[string]string, nums map[string]float64, now time.Time) []byte {
+	keys := make([]string, 0, len(nums))
+	for k := range nums {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// The labels must be sorted by name, __name__ sorts first.
+	names := make([]string, 0, len(labels))
+	values := make(map[string]string, len(labels))
+	for k, v := range labels {
+		name := labelName(k)
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = v
+	}
+	sort.Strings(names)
+
+	var common []byte
+	for _, name := range names {
+		common = protowire.AppendTag(common, 1, protowire.BytesType)
+		common = protowire.AppendBytes(common, label(name, values[name]))
+	}
+
+	var req []byte
+	for _, k := range keys {
+		var ts []byte
+
+		ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, label("__name__", metricName(k)))
+		ts = append(ts, common...)
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(nums[k]))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(now.UnixMilli()))
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sample)
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+
+	return req
+}
+
+// label encodes a Label.
+func label(name string, value string) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, name)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, value)
+	return b
+}
+
+// metricName replaces the characters not allowed in metric names by _.
+// Metric names match [a-zA-Z_:][a-zA-Z0-9_:]*.
+func metricName(key string) string {
+	return sanitize(key, true)
+}
+
+// labelName replaces the characters not allowed in label names by _.
+// Label names match [a-zA-Z_][a-zA-Z0-9_]*, colons aren't allowed.
+func labelName(key string) string {
+	return sanitize(key, false)
+}
+
+// sanitize replaces the characters not allowed in a name by _ and prefixes
+// the names starting with a digit by _.
+func sanitize(key string, colon bool) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r == ':' && colon:
+			return r
+		}
+		return '_'
+	}, key)
+
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,211 @@
+package remotewrite_test
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
+	"github.com/tullo/service/app/sidecar/metrics/publisher/remotewrite"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// label is a decoded Label of a series.
+type label struct {
+	name  string
+	value string
+}
+
+// series is a decoded TimeSeries with a single sample.
+type series struct {
+	labels    []label
+	value     float64
+	timestamp int64
+}
+
+// fields walks the fields of a message, failing the test when it can't be
+// parsed.
+func fields(t *testing.T, b []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) int) {
+	t.Helper()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("\t%s\tShould be able to parse a tag : %s.", failed, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		n = fn(num, typ, b)
+		if n < 0 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			t.Fatalf("\t%s\tShould be able to parse field %d : %s.", failed, num, protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+}
+
+// bytesField consumes a length delimited field.
+func bytesField(t *testing.T, typ protowire.Type, b []byte) ([]byte, int) {
+	t.Helper()
+	if typ != protowire.BytesType {
+		t.Fatalf("\t%s\tShould be a length delimited field : got type %d.", failed, typ)
+	}
+	return protowire.ConsumeBytes(b)
+}
+
+// decode parses a WriteRequest.
+func decode(t *testing.T, req []byte) []series {
+	t.Helper()
+
+	var out []series
+	fields(t, req, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		if num != 1 {
+			return -1
+		}
+		ts, n := bytesField(t, typ, b)
+
+		var s series
+		fields(t, ts, func(num protowire.Number, typ protowire.Type, b []byte) int {
+			msg, n := bytesField(t, typ, b)
+			switch num {
+			case 1:
+				var l label
+				fields(t, msg, func(num protowire.Number, typ protowire.Type, b []byte) int {
+					v, n := bytesField(t, typ, b)
+					switch num {
+					case 1:
+						l.name = string(v)
+					case 2:
+						l.value = string(v)
+					}
+					return n
+				})
+				s.labels = append(s.labels, l)
+			case 2:
+				fields(t, msg, func(num protowire.Number, typ protowire.Type, b []byte) int {
+					switch {
+					case num == 1 && typ == protowire.Fixed64Type:
+						v, n := protowire.ConsumeFixed64(b)
+						s.value = math.Float64frombits(v)
+						return n
+					case num == 2 && typ == protowire.VarintType:
+						v, n := protowire.ConsumeVarint(b)
+						s.timestamp = int64(v)
+						return n
+					}
+					return -1
+				})
+			}
+			return n
+		})
+		out = append(out, s)
+		return n
+	})
+
+	return out
+}
+
+func TestRemoteWrite(t *testing.T) {
+	log := slog.New(slog.DiscardHandler)
+
+	t.Log("Given the need to publish metrics over the remote write protocol.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen publishing a scrape.", testID)
+		{
+			bodies := make(chan []byte, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Encoding") != "snappy" {
+					http.Error(w, "not snappy", http.StatusBadRequest)
+					return
+				}
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				bodies <- body
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer srv.Close()
+
+			now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+			scrape := publisher.Scrape{
+				Target:   "sales-api",
+				Instance: "sales-api:4000",
+				Labels:   map[string]string{"team.name": "sales", "1zone": "eu:1"},
+				Time:     now,
+				Data: map[string]interface{}{
+					"goroutines":     8.0,
+					"memstats.Alloc": 1024.0,
+					"2xx":            3.0,
+					"cmdline":        []interface{}{"sales-api"},
+				},
+			}
+
+			rw := remotewrite.New(log, srv.URL, "sidecar", time.Second)
+			if err := rw.Publish(scrape); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to publish the scrape : %s.", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to publish the scrape.", success, testID)
+
+			req, err := snappy.Decode(nil, <-bodies)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to decompress the request : %s.", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to decompress the request.", success, testID)
+
+			common := []label{
+				{"_1zone", "eu:1"},
+				{"instance", "sales-api:4000"},
+				{"job", "sidecar"},
+				{"target", "sales-api"},
+				{"team_name", "sales"},
+			}
+			with := func(name string) []label {
+				return append([]label{{"__name__", name}}, common...)
+			}
+			exp := []series{
+				{labels: with("_2xx"), value: 3, timestamp: now.UnixMilli()},
+				{labels: with("goroutines"), value: 8, timestamp: now.UnixMilli()},
+				{labels: with("memstats_Alloc"), value: 1024, timestamp: now.UnixMilli()},
+			}
+
+			got := decode(t, req)
+			if !reflect.DeepEqual(got, exp) {
+				t.Logf("\t\tTest %d:\tgot: %+v", testID, got)
+				t.Logf("\t\tTest %d:\texp: %+v", testID, exp)
+				t.Fatalf("\t%s\tTest %d:\tShould send a series per number with valid names.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould send a series per number with valid names.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen the endpoint rejects the request.", testID)
+		{
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "out of order sample", http.StatusBadRequest)
+			}))
+			defer srv.Close()
+
+			rw := remotewrite.New(log, srv.URL, "sidecar", time.Second)
+			if err := rw.Publish(publisher.Scrape{Target: "sales-api", Data: map[string]interface{}{"goroutines": 8.0}}); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould fail to publish the scrape.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould fail to publish the scrape.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+// Package spool buffers the scrapes a publisher failed to deliver on disk,
+// and replays them once it recovers.
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
+)
+
+// The state of the spools, keyed by the name of their publisher.
+var (
+	sizes   = expvar.NewMap("spool_bytes")
+	entries = expvar.NewMap("spool_entries")
+	dropped = expvar.NewMap("spool_dropped")
+)
+
+// Config holds the settings of a spool.
+type Config struct {
+
+	// The scrapes are appended to <Dir>/<name>.wal, the offset of the oldest
+	// one not delivered yet is kept in <Dir>/<name>.pos.
+	Dir string
+
+	// MaxBytes bounds the size of the scrapes waiting. The oldest ones are
+	// dropped to make room for new ones.
+	MaxBytes int64
+
+	// The replay of the scrapes waits MinBackoff after a failure, doubling
+	// up to MaxBackoff while the publisher keeps failing.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Spool delivers the scrapes to a publisher, keeping those it fails to
+// deliver in a write-ahead file. The file is replayed in order, so while
+// it isn't empty new scrapes are appended to it as well.
+type Spool struct {
+	log  *slog.Logger
+	name string
+	pub  publisher.Publisher
+	cfg  Config
+
+	wg       sync.WaitGroup
+	signal   chan struct{}
+	shutdown chan struct{}
+
+	mu      sync.Mutex
+	wal     *os.File
+	posPath string
+	offset  int64
+	size    int64
+	count   int64
+
+	// base counts the bytes removed from the start of the spool by
+	// truncations and compactions, so base+offset keeps growing as entries
+	// are consumed. The replay tells by it whether the entry it delivered
+	// was dropped meanwhile, or only moved.
+	base int64
+}
+
+// New opens the spool of the publisher, replaying the scrapes left by an
+// earlier run.
+func New(log *slog.Logger, name string, pub publisher.Publisher, cfg Config) (*Spool, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "creating spool dir")
+	}
+
+	walPath := filepath.Join(cfg.Dir, name+".wal")
+	wal, err := os.OpenFile(walPath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening spool")
+	}
+
+	s := Spool{
+		log:      log,
+		name:     name,
+		pub:      pub,
+		cfg:      cfg,
+		signal:   make(chan struct{}, 1),
+		shutdown: make(chan struct{}),
+		wal:      wal,
+		posPath:  filepath.Join(cfg.Dir, name+".pos"),
+	}
+
+	if err := s.load(); err != nil {
+		wal.Close()
+		return nil, err
+	}
+	if s.count > 0 {
+		log.Info("spool", "publisher", name, "status", "replaying", "entries", s.count)
+		s.signal <- struct{}{}
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.replay()
+	}()
+
+	return &s, nil
+}
+
+// Publish delivers the scrape when nothing is waiting in the spool, and
+// spools it when that fails or scrapes are waiting. It only fails when the
+// scrape can't be spooled.
+func (s *Spool) Publish(scrape publisher.Scrape) error {
+	s.mu.Lock()
+	waiting := s.count > 0
+	s.mu.Unlock()
+
+	if !waiting {
+		err := s.send(scrape)
+		if err == nil {
+			return nil
+		}
+		s.log.Warn("spool", "publisher", s.name, "target", scrape.Target, "status", "spooling", "error", err)
+	}
+
+	entry, err := json.Marshal(scrape)
+	if err != nil {
+		return errors.Wrap(err, "marshaling scrape")
+	}
+
+	s.mu.Lock()
+	err = s.append(append(entry, '\n'))
+	s.mu.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "spooling")
+	}
+
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Stop stops the replay and closes the spool. The scrapes waiting are
+// replayed by the next run.
+func (s *Spool) Stop() {
+	close(s.shutdown)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.wal.Close(); err != nil {
+		s.log.Error("spool", "publisher", s.name, "status", "closing", "error", err)
+	}
+}
+
+// replay delivers the scrapes waiting, oldest first, backing off while the
+// publisher fails.
+func (s *Spool) replay() {
+	backoff := s.cfg.MinBackoff
+	for {
+		select {
+		case <-s.signal:
+		case <-s.shutdown:
+			return
+		}
+
+		for {
+			err := s.replayOne()
+			if err == io.EOF {
+				backoff = s.cfg.MinBackoff
+				break
+			}
+
+			if err != nil {
+				s.log.Warn("spool", "publisher", s.name, "status", "replay failed", "backoff", backoff, "error", err)
+
+				timer := time.NewTimer(backoff)
+				select {
+				case <-timer.C:
+				case <-s.shutdown:
+					timer.Stop()
+					return
+				}
+
+				backoff *= 2
+				if backoff > s.cfg.MaxBackoff {
+					backoff = s.cfg.MaxBackoff
+				}
+				continue
+			}
+			backoff = s.cfg.MinBackoff
+
+			select {
+			case <-s.shutdown:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// replayOne delivers the oldest scrape waiting. It returns io.EOF when
+// there's none.
+func (s *Spool) replayOne() error {
+	s.mu.Lock()
+	pos := s.base + s.offset
+	entry, err := s.read(s.offset)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var scrape publisher.Scrape
+	if err := json.Unmarshal(entry, &scrape); err != nil {
+
+		// A torn write of a crash can't be replayed, skip it.
+		s.log.Error("spool", "publisher", s.name, "status", "skipping entry", "error", err)
+	} else if err := s.send(scrape); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The entry was dropped for room meanwhile.
+	if s.base+s.offset != pos {
+		return nil
+	}
+	return s.consume(int64(len(entry)))
+}
+
+// send delivers the scrape, recovering from panics of the publisher.
+func (s *Spool) send(scrape publisher.Scrape) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return s.pub(scrape)
+}
+
+// =============================================================================
+
+// load reads the offset of the oldest scrape waiting and counts those
+// waiting.
+func (s *Spool) load() error {
+	fi, err := s.wal.Stat()
+	if err != nil {
+		return errors.Wrap(err, "reading spool")
+	}
+	s.size = fi.Size()
+
+	if b, err := os.ReadFile(s.posPath); err == nil {
+		if s.offset, err = strconv.ParseInt(string(bytes.TrimSpace(b)), 10, 64); err != nil {
+			return errors.Wrap(err, "parsing spool offset")
+		}
+	} else if !os.IsNotExist(err) {
+		return errors.Wrap(err, "reading spool offset")
+	}
+	if s.offset > s.size {
+		s.offset = s.size
+	}
+
+	// Count the entries, truncating a torn write of a crash.
+	end := s.offset
+	r := bufio.NewReader(io.NewSectionReader(s.wal, s.offset, s.size-s.offset))
+	for {
+		entry, err := r.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		end += int64(len(entry))
+		s.count++
+	}
+	if end < s.size {
+		if err := s.wal.Truncate(end); err != nil {
+			return errors.Wrap(err, "truncating spool")
+		}
+		s.size = end
+	}
+
+	s.report()
+	return nil
+}
+
+// append writes the entry to the end of the spool, dropping the oldest
+// entries when it doesn't fit.
+func (s *Spool) append(entry []byte) error {
+	n := int64(len(entry))
+	if n > s.cfg.MaxBytes {
+		dropped.Add(s.name, 1)
+		return errors.New("scrape larger than the spool")
+	}
+
+	for s.size-s.offset+n > s.cfg.MaxBytes {
+		old, err := s.read(s.offset)
+		if err != nil {
+			return err
+		}
+		if err := s.consume(int64(len(old))); err != nil {
+			return err
+		}
+		dropped.Add(s.name, 1)
+	}
+
+	// Reclaim the space of the entries delivered before growing past the
+	// bound.
+	if s.size+n > s.cfg.MaxBytes {
+		if err := s.compact(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.wal.WriteAt(entry, s.size); err != nil {
+		return errors.Wrap(err, "writing spool")
+	}
+	if err := s.wal.Sync(); err != nil {
+		return errors.Wrap(err, "syncing spool")
+	}
+
+	s.size += n
+	s.count++
+	s.report()
+	return nil
+}
+
+// read returns the entry at the offset, including its newline. It returns
+// io.EOF when there's none.
+func (s *Spool) read(offset int64) ([]byte, error) {
+	if offset >= s.size {
+		return nil, io.EOF
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(s.wal, offset, s.size-offset))
+	entry, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, errors.Wrap(err, "reading spool")
+	}
+	return entry, nil
+}
+
+// consume moves the offset past an entry. The spool is truncated once all
+// of its entries are consumed.
+func (s *Spool) consume(n int64) error {
+	s.offset += n
+	s.count--
+
+	if s.offset >= s.size {
+		if err := s.wal.Truncate(0); err != nil {
+			return errors.Wrap(err, "truncating spool")
+		}
+		s.base += s.offset
+		s.offset, s.size, s.count = 0, 0, 0
+	}
+
+	s.report()
+	return s.savePos(s.offset)
+}
+
+// compact moves the entries waiting to the start of the spool.
+func (s *Spool) compact() error {
+	if s.offset == 0 {
+		return nil
+	}
+
+	live := make([]byte, s.size-s.offset)
+	if _, err := s.wal.ReadAt(live, s.offset); err != nil {
+		return errors.Wrap(err, "reading spool")
+	}
+
+	// Write the entries to a new file first, and point the offset at its
+	// start before it replaces the spool. A crash in between leaves the
+	// old spool read from its start, replaying the entries delivered since
+	// the last compaction again rather than losing any.
+	tmpPath := s.wal.Name() + ".tmp"
+	if err := writeSynced(tmpPath, live); err != nil {
+		return errors.Wrap(err, "compacting spool")
+	}
+	if err := s.savePos(0); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.wal.Name()); err != nil {
+		return errors.Wrap(err, "compacting spool")
+	}
+
+	wal, err := os.OpenFile(s.wal.Name(), os.O_RDWR, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "opening spool")
+	}
+	s.wal.Close()
+	s.wal = wal
+
+	s.base += s.offset
+	s.offset, s.size = 0, int64(len(live))
+	s.report()
+	return nil
+}
+
+// savePos persists the offset of the oldest entry waiting.
+func (s *Spool) savePos(offset int64) error {
+	tmpPath := s.posPath + ".tmp"
+	if err := writeSynced(tmpPath, []byte(strconv.FormatInt(offset, 10))); err != nil {
+		return errors.Wrap(err, "saving spool offset")
+	}
+	if err := os.Rename(tmpPath, s.posPath); err != nil {
+		return errors.Wrap(err, "saving spool offset")
+	}
+	return nil
+}
+
+// writeSynced writes the file and flushes it to disk, so it's complete
+// once renamed.
+func writeSynced(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// report publishes the size of the spool.
+func (s *Spool) report() {
+	size := new(expvar.Int)
+	size.Set(s.size - s.offset)
+	sizes.Set(s.name, size)
+
+	count := new(expvar.Int)
+	count.Set(s.count)
+	entries.Set(s.name, count)
+}
@@ -0,0 +1,409 @@
+package spool_test
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
+	"github.com/tullo/service/app/sidecar/metrics/publisher/spool"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// receiver is a publisher failing until it accepts the scrapes of a target.
+type receiver struct {
+	mu        sync.Mutex
+	accept    func(target string) bool
+	targets   []string
+	delivered chan string
+}
+
+func newReceiver(accept func(target string) bool) *receiver {
+	return &receiver{
+		accept:    accept,
+		delivered: make(chan string, 100),
+	}
+}
+
+// publish implements publisher.Publisher.
+func (r *receiver) publish(scrape publisher.Scrape) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.accept(scrape.Target) {
+		return errors.New("unavailable")
+	}
+	r.targets = append(r.targets, scrape.Target)
+	r.delivered <- scrape.Target
+	return nil
+}
+
+// setAccept changes the scrapes accepted.
+func (r *receiver) setAccept(accept func(target string) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.accept = accept
+}
+
+// wait waits for n scrapes to be delivered and returns the targets of
+// those delivered so far.
+func (r *receiver) wait(t *testing.T, n int) []string {
+	t.Helper()
+	for range n {
+		select {
+		case <-r.delivered:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("\t%s\tShould deliver %d scrapes in time.", failed, n)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.targets...)
+}
+
+func acceptAll(string) bool  { return true }
+func acceptNone(string) bool { return false }
+
+// scrape returns a scrape of the target. Targets of the same length have
+// entries of the same size.
+func scrape(target string) publisher.Scrape {
+	return publisher.Scrape{
+		Target: target,
+		Time:   time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Data:   map[string]interface{}{"goroutines": 8.0},
+	}
+}
+
+// entry returns the spooled form of the scrape.
+func entry(t *testing.T, target string) []byte {
+	t.Helper()
+	b, err := json.Marshal(scrape(target))
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to marshal a scrape : %s.", failed, err)
+	}
+	return append(b, '\n')
+}
+
+// stat returns the value published for the spool in the expvar map. The
+// maps outlive the spools, so counters are compared to their value before.
+func stat(name string, key string) int64 {
+	v, ok := expvar.Get(name).(*expvar.Map).Get(key).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return v.Value()
+}
+
+// settle waits for the replay to consume the delivered scrapes, leaving
+// the entries of the spool at the count.
+func settle(t *testing.T, key string, count int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for stat("spool_entries", key) != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("\t%s\tShould get %d entries in time : got %d.", failed, count, stat("spool_entries", key))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func config(dir string, maxBytes int64) spool.Config {
+	return spool.Config{
+		Dir:        dir,
+		MaxBytes:   maxBytes,
+		MinBackoff: 5 * time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+	}
+}
+
+func TestSpool(t *testing.T) {
+	log := slog.New(slog.DiscardHandler)
+
+	t.Log("Given the need to spool the scrapes a publisher failed to deliver.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the publisher recovers.", testID)
+		{
+			r := newReceiver(acceptNone)
+			sp, err := spool.New(log, "order", r.publish, config(t.TempDir(), 1<<20))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to open the spool : %s.", failed, testID, err)
+			}
+			defer sp.Stop()
+
+			for _, target := range []string{"a", "b", "c"} {
+				if err := sp.Publish(scrape(target)); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to spool the scrape : %s.", failed, testID, err)
+				}
+			}
+			if got := stat("spool_entries", "order"); got != 3 {
+				t.Fatalf("\t%s\tTest %d:\tShould spool the scrapes : got %d entries.", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould spool the scrapes.", success, testID)
+
+			r.setAccept(acceptAll)
+			if got, exp := r.wait(t, 3), []string{"a", "b", "c"}; !reflect.DeepEqual(got, exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould replay the scrapes in order : got %v want %v.", failed, testID, got, exp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould replay the scrapes in order.", success, testID)
+
+			if err := sp.Publish(scrape("d")); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to publish the scrape : %s.", failed, testID, err)
+			}
+			if got := r.wait(t, 1); got[len(got)-1] != "d" {
+				t.Fatalf("\t%s\tTest %d:\tShould deliver new scrapes : got %v.", failed, testID, got)
+			}
+			settle(t, "order", 0)
+			t.Logf("\t%s\tTest %d:\tShould deliver new scrapes and empty the spool.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen the spool is full.", testID)
+		{
+			dir := t.TempDir()
+			n := int64(len(entry(t, "a")))
+
+			drops := stat("spool_dropped", "full")
+
+			r := newReceiver(acceptNone)
+			sp, err := spool.New(log, "full", r.publish, config(dir, 2*n))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to open the spool : %s.", failed, testID, err)
+			}
+
+			for _, target := range []string{"a", "b", "c", "d"} {
+				if err := sp.Publish(scrape(target)); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to spool the scrape : %s.", failed, testID, err)
+				}
+			}
+			sp.Stop()
+
+			if got := stat("spool_dropped", "full") - drops; got != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould drop the oldest scrapes : got %d dropped.", failed, testID, got)
+			}
+			if got := stat("spool_entries", "full"); got != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould keep the newest scrapes : got %d entries.", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould drop the oldest scrapes.", success, testID)
+
+			fi, err := os.Stat(filepath.Join(dir, "full.wal"))
+			if err != nil || fi.Size() > 2*n {
+				t.Fatalf("\t%s\tTest %d:\tShould keep the spool within its bound : %v %v.", failed, testID, fi, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep the spool within its bound.", success, testID)
+
+			r = newReceiver(acceptAll)
+			sp, err = spool.New(log, "full", r.publish, config(dir, 2*n))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to reopen the spool : %s.", failed, testID, err)
+			}
+			defer sp.Stop()
+
+			if got, exp := r.wait(t, 2), []string{"c", "d"}; !reflect.DeepEqual(got, exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould replay the newest scrapes : got %v want %v.", failed, testID, got, exp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould replay the newest scrapes.", success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen the spool needs the room of delivered scrapes.", testID)
+		{
+			dir := t.TempDir()
+			n := int64(len(entry(t, "a")))
+
+			drops := stat("spool_dropped", "compact")
+
+			r := newReceiver(acceptNone)
+			sp, err := spool.New(log, "compact", r.publish, config(dir, 3*n))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to open the spool : %s.", failed, testID, err)
+			}
+
+			for _, target := range []string{"a", "b", "c"} {
+				if err := sp.Publish(scrape(target)); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to spool the scrape : %s.", failed, testID, err)
+				}
+			}
+
+			// Only the oldest scrape gets through, the others keep waiting.
+			r.setAccept(func(target string) bool { return target == "a" })
+			r.wait(t, 1)
+			settle(t, "compact", 2)
+
+			if err := sp.Publish(scrape("d")); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to spool the scrape : %s.", failed, testID, err)
+			}
+			sp.Stop()
+
+			if got := stat("spool_dropped", "compact") - drops; got != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT drop scrapes : got %d dropped.", failed, testID, got)
+			}
+			fi, err := os.Stat(filepath.Join(dir, "compact.wal"))
+			if err != nil || fi.Size() != 3*n {
+				t.Fatalf("\t%s\tTest %d:\tShould move the waiting scrapes to the start : %v %v.", failed, testID, fi, err)
+			}
+			if pos, err := os.ReadFile(filepath.Join(dir, "compact.pos")); err != nil || string(pos) != "0" {
+				t.Fatalf("\t%s\tTest %d:\tShould persist the offset of the moved scrapes : %q %v.", failed, testID, pos, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould move the waiting scrapes to the start.", success, testID)
+
+			r = newReceiver(acceptAll)
+			sp, err = spool.New(log, "compact", r.publish, config(dir, 3*n))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to reopen the spool : %s.", failed, testID, err)
+			}
+			defer sp.Stop()
+
+			if got, exp := r.wait(t, 3), []string{"b", "c", "d"}; !reflect.DeepEqual(got, exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould replay the waiting scrapes : got %v want %v.", failed, testID, got, exp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould replay the waiting scrapes.", success, testID)
+		}
+
+		testID = 3
+		t.Logf("\tTest %d:\tWhen the spool compacts while a scrape is being delivered.", testID)
+		{
+			dir := t.TempDir()
+			n := int64(len(entry(t, "a")))
+
+			drops := stat("spool_dropped", "inflight")
+
+			r := newReceiver(acceptNone)
+			sp, err := spool.New(log, "inflight", r.publish, config(dir, 3*n))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to open the spool : %s.", failed, testID, err)
+			}
+			defer sp.Stop()
+
+			for _, target := range []string{"x", "a", "b"} {
+				if err := sp.Publish(scrape(target)); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to spool the scrape : %s.", failed, testID, err)
+				}
+			}
+
+			// The delivery of a is held until c is spooled, which compacts the
+			// spool as x was delivered already.
+			sending := make(chan struct{})
+			release := make(chan struct{})
+			r.setAccept(func(target string) bool {
+				if target == "a" {
+					select {
+					case <-release:
+					default:
+						close(sending)
+						<-release
+					}
+				}
+				return true
+			})
+
+			select {
+			case <-sending:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("\t%s\tTest %d:\tShould start delivering a.", failed, testID)
+			}
+			if err := sp.Publish(scrape("c")); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to spool the scrape : %s.", failed, testID, err)
+			}
+			close(release)
+
+			if got, exp := r.wait(t, 4), []string{"x", "a", "b", "c"}; !reflect.DeepEqual(got, exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould deliver every scrape once : got %v want %v.", failed, testID, got, exp)
+			}
+			settle(t, "inflight", 0)
+
+			r.mu.Lock()
+			got := len(r.targets)
+			r.mu.Unlock()
+			if got != 4 {
+				t.Fatalf("\t%s\tTest %d:\tShould deliver every scrape once : got %v.", failed, testID, r.targets)
+			}
+			if got := stat("spool_dropped", "inflight") - drops; got != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT drop scrapes : got %d dropped.", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould deliver every scrape once.", success, testID)
+		}
+	}
+}
+
+func TestSpoolRestart(t *testing.T) {
+	log := slog.New(slog.DiscardHandler)
+
+	t.Log("Given the need to replay the scrapes left by an earlier run.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the last entry was torn by a crash.", testID)
+		{
+			dir := t.TempDir()
+
+			wal := append(entry(t, "a"), entry(t, "b")...)
+			torn := append(wal, entry(t, "c")[:10]...)
+			if err := os.WriteFile(filepath.Join(dir, "torn.wal"), torn, 0o644); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to write the spool : %s.", failed, testID, err)
+			}
+
+			r := newReceiver(acceptNone)
+			sp, err := spool.New(log, "torn", r.publish, config(dir, 1<<20))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to open the spool : %s.", failed, testID, err)
+			}
+
+			fi, err := os.Stat(filepath.Join(dir, "torn.wal"))
+			if err != nil || fi.Size() != int64(len(wal)) {
+				t.Fatalf("\t%s\tTest %d:\tShould truncate the torn entry : %v %v.", failed, testID, fi, err)
+			}
+			if got := stat("spool_entries", "torn"); got != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould count the whole entries : got %d.", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould truncate the torn entry.", success, testID)
+
+			r.setAccept(acceptAll)
+			if got, exp := r.wait(t, 2), []string{"a", "b"}; !reflect.DeepEqual(got, exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould replay the whole entries : got %v want %v.", failed, testID, got, exp)
+			}
+			sp.Stop()
+			t.Logf("\t%s\tTest %d:\tShould replay the whole entries.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen some entries were delivered already.", testID)
+		{
+			dir := t.TempDir()
+
+			wal := append(append(entry(t, "a"), entry(t, "b")...), entry(t, "c")...)
+			if err := os.WriteFile(filepath.Join(dir, "pos.wal"), wal, 0o644); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to write the spool : %s.", failed, testID, err)
+			}
+			offset := strconv.Itoa(len(entry(t, "a")))
+			if err := os.WriteFile(filepath.Join(dir, "pos.pos"), []byte(offset), 0o644); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to write the offset : %s.", failed, testID, err)
+			}
+
+			r := newReceiver(acceptAll)
+			sp, err := spool.New(log, "pos", r.publish, config(dir, 1<<20))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to open the spool : %s.", failed, testID, err)
+			}
+			defer sp.Stop()
+
+			if got, exp := r.wait(t, 2), []string{"b", "c"}; !reflect.DeepEqual(got, exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould replay from the offset : got %v want %v.", failed, testID, got, exp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould replay from the offset.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package statsd
+
+import (
+	"bytes"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
+)
+
+// maxPacketSize keeps the datagrams below the usual MTU of 1500 bytes.
+const maxPacketSize = 1432
+
+// Statsd provides the ability to publish metrics to a StatsD server.
+type Statsd struct {
+	log     *slog.Logger
+	addr    string
+	prefix  string
+	timeout time.Duration
+}
+
+// New initializes StatsD access for publishing metrics. The names of the
+// metrics are prefixed with prefix and a dot when it's set. The tags of the
+// scrapes are sent in the DogStatsD format.
+func New(log *slog.Logger, addr string, prefix string, timeout time.Duration) *Statsd {
+	return &Statsd{
+		log:     log,
+		addr:    addr,
+		prefix:  prefix,
+		timeout: timeout,
+	}
+}
+
+// Publish sends the numbers of the scraped data as gauges.
+func (s *Statsd) Publish(scrape publisher.Scrape) error {
+	if err := s.send(publisher.Numbers(scrape.Data), tags(scrape.Tags())); err != nil {
+		return errors.Wrap(err, "statsd")
+	}
+
+	s.log.Debug("statsd.publish", "status", "published")
+	return nil
+}
+
+// send writes the gauges to the server, as many per datagram as fit.
+func (s *Statsd) send(nums map[string]float64, tags string) error {
+	conn, err := net.DialTimeout("udp", s.addr, s.timeout)
+	if err != nil {
+		return errors.Wrap(err, "dialing")
+	}
+	defer conn.Close()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil {
+		return errors.Wrap(err, "setting deadline")
+	}
+
+	keys := make([]string, 0, len(nums))
+	for k := range nums {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var packet bytes.Buffer
+	for _, k := range keys {
+		line := s.line(k, nums[k], tags)
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxPacketSize {
+			if _, err := conn.Write(packet.Bytes()); err != nil {
+				return errors.Wrap(err, "writing")
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+
+	if packet.Len() > 0 {
+		if _, err := conn.Write(packet.Bytes()); err != nil {
+			return errors.Wrap(err, "writing")
+		}
+	}
+
+	return nil
+}
+
+// line formats a gauge like "sales.goroutines:12|g|#target:sales-api".
+func (s *Statsd) line(key string, value float64, tags string) string {
+	name := key
+	if s.prefix != "" {
+		name = s.prefix + "." + key
+	}
+	return name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|g" + tags
+}
+
+// tags formats the tags like "|#instance:sales-api:4000,target:sales-api".
+func tags(t map[string]string) string {
+	if len(t) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(t))
+	for k, v := range t {
+		pairs = append(pairs, k+":"+v)
+	}
+	sort.Strings(pairs)
+
+	return "|#" + strings.Join(pairs, ",")
+}
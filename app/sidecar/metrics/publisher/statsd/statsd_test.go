@@ -0,0 +1,124 @@
+package statsd_test
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tullo/service/app/sidecar/metrics/publisher"
+	"github.com/tullo/service/app/sidecar/metrics/publisher/statsd"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// maxPacketSize mirrors the limit of the datagrams sent by the publisher.
+const maxPacketSize = 1432
+
+// receive reads datagrams until the lines arrived or the deadline passed.
+func receive(t *testing.T, conn net.PacketConn, lines int) [][]byte {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("\t%s\tShould be able to set the deadline : %s.", failed, err)
+	}
+
+	var packets [][]byte
+	buf := make([]byte, 64<<10)
+	for got := 0; got < lines; {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("\t%s\tShould receive %d lines : got %d : %s.", failed, lines, got, err)
+		}
+		packets = append(packets, append([]byte(nil), buf[:n]...))
+		got += strings.Count(string(buf[:n]), "\n") + 1
+	}
+	return packets
+}
+
+func TestStatsd(t *testing.T) {
+	log := slog.New(slog.DiscardHandler)
+
+	t.Log("Given the need to publish metrics to a StatsD server.")
+	{
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to listen for datagrams : %s.", failed, err)
+		}
+		defer conn.Close()
+
+		st := statsd.New(log, conn.LocalAddr().String(), "sales", time.Second)
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen publishing a scrape.", testID)
+		{
+			scrape := publisher.Scrape{
+				Target:   "sales-api",
+				Instance: "sales-api:4000",
+				Labels:   map[string]string{"team": "sales"},
+				Data: map[string]interface{}{
+					"goroutines": 8.0,
+					"cmdline":    []interface{}{"sales-api"},
+				},
+			}
+			if err := st.Publish(scrape); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to publish the scrape : %s.", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to publish the scrape.", success, testID)
+
+			packets := receive(t, conn, 1)
+			exp := "sales.goroutines:8|g|#instance:sales-api:4000,target:sales-api,team:sales"
+			if got := string(packets[0]); got != exp {
+				t.Logf("\t\tTest %d:\tgot: %s", testID, got)
+				t.Logf("\t\tTest %d:\texp: %s", testID, exp)
+				t.Fatalf("\t%s\tTest %d:\tShould send the numbers as tagged gauges.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould send the numbers as tagged gauges.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen the gauges don't fit in a single datagram.", testID)
+		{
+			data := make(map[string]interface{})
+			var exp []string
+			for i := range 200 {
+				key := fmt.Sprintf("memstats.BySize.%03d.Mallocs", i)
+				data[key] = float64(i)
+				exp = append(exp, fmt.Sprintf("sales.%s:%d|g|#instance:,target:sales-api", key, i))
+			}
+
+			if err := st.Publish(publisher.Scrape{Target: "sales-api", Data: data}); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to publish the scrape : %s.", failed, testID, err)
+			}
+
+			packets := receive(t, conn, len(exp))
+			if len(packets) < 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould split the gauges over datagrams : got %d.", failed, testID, len(packets))
+			}
+
+			var got []string
+			for _, p := range packets {
+				if len(p) > maxPacketSize {
+					t.Fatalf("\t%s\tTest %d:\tShould keep datagrams within %d bytes : got %d.", failed, testID, maxPacketSize, len(p))
+				}
+				got = append(got, strings.Split(string(p), "\n")...)
+			}
+			t.Logf("\t%s\tTest %d:\tShould split the gauges over %d datagrams within %d bytes.", success, testID, len(packets), maxPacketSize)
+
+			sort.Strings(got)
+			if strings.Join(got, "\n") != strings.Join(exp, "\n") {
+				t.Logf("\t\tTest %d:\tgot: %d lines", testID, len(got))
+				t.Logf("\t\tTest %d:\texp: %d lines", testID, len(exp))
+				t.Fatalf("\t%s\tTest %d:\tShould send every gauge once.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould send every gauge once.", success, testID)
+		}
+	}
+}
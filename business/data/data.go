@@ -1,24 +1,136 @@
 package data
 
-import "errors"
+import "net/http"
+
+// Error is a business error. Besides its message it carries the problem type
+// URI, title, status code and machine-readable code used to report it to
+// clients as an RFC 7807 problem.
+type Error struct {
+	Type   string // URI reference identifying the problem type.
+	Title  string // Short, human-readable summary of the problem type.
+	Status int    // HTTP status code for the problem type.
+	Code   string // Machine-readable error code.
+	msg    string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.msg
+}
 
 // Set of error variables.
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound = &Error{
+		Type:   "/problems/not-found",
+		Title:  "Resource not found",
+		Status: http.StatusNotFound,
+		Code:   "not_found",
+		msg:    "not found",
+	}
 
 	// ErrInvalidID occurs when an ID is not in a valid form.
-	ErrInvalidID = errors.New("ID is not in its proper form")
+	ErrInvalidID = &Error{
+		Type:   "/problems/invalid-id",
+		Title:  "Invalid identifier",
+		Status: http.StatusBadRequest,
+		Code:   "invalid_id",
+		msg:    "ID is not in its proper form",
+	}
 
 	// ErrForbidden occurs when a user tries to do something
 	// that is forbidden to them according to our access
 	// control policies.
-	ErrForbidden = errors.New("attempted action is not allowed")
+	ErrForbidden = &Error{
+		Type:   "/problems/forbidden",
+		Title:  "Action not allowed",
+		Status: http.StatusForbidden,
+		Code:   "forbidden",
+		msg:    "attempted action is not allowed",
+	}
 
 	// ErrAuthenticationFailure occurs when a user attempts
 	// to authenticate but anything goes wrong.
-	ErrAuthenticationFailure = errors.New("authentication failed")
+	ErrAuthenticationFailure = &Error{
+		Type:   "/problems/authentication-failed",
+		Title:  "Authentication failed",
+		Status: http.StatusUnauthorized,
+		Code:   "authentication_failed",
+		msg:    "authentication failed",
+	}
 
 	// ErrDuplicateEmail occurs when user creation failed
 	// b/c of an email address that's already in use.
-	ErrDuplicateEmail = errors.New("duplicate email")
+	ErrDuplicateEmail = &Error{
+		Type:   "/problems/duplicate-email",
+		Title:  "Email address already in use",
+		Status: http.StatusConflict,
+		Code:   "duplicate_email",
+		msg:    "duplicate email",
+	}
+
+	// ErrDeliveryNotFailed occurs when a webhook delivery is replayed
+	// which hasn't failed.
+	ErrDeliveryNotFailed = &Error{
+		Type:   "/problems/delivery-not-failed",
+		Title:  "Delivery has not failed",
+		Status: http.StatusConflict,
+		Code:   "delivery_not_failed",
+		msg:    "only failed deliveries can be replayed",
+	}
 )
+
+// Errors is the registry of all business errors.
+var Errors = []*Error{
+	ErrNotFound,
+	ErrInvalidID,
+	ErrForbidden,
+	ErrAuthenticationFailure,
+	ErrDuplicateEmail,
+	ErrDeliveryNotFailed,
+}
+
+// translations holds the title and message of the business errors in the
+// supported languages other than English, keyed by error code.
+var translations = map[string]map[string][2]string{
+	"de": {
+		"not_found":             {"Ressource nicht gefunden", "nicht gefunden"},
+		"invalid_id":            {"Ungültige Kennung", "ID hat nicht das richtige Format"},
+		"forbidden":             {"Aktion nicht erlaubt", "die versuchte Aktion ist nicht erlaubt"},
+		"authentication_failed": {"Authentifizierung fehlgeschlagen", "Authentifizierung fehlgeschlagen"},
+		"duplicate_email":       {"E-Mail-Adresse wird bereits verwendet", "doppelte E-Mail-Adresse"},
+		"delivery_not_failed":   {"Zustellung ist nicht fehlgeschlagen", "nur fehlgeschlagene Zustellungen können wiederholt werden"},
+	},
+	"da": {
+		"not_found":             {"Ressourcen blev ikke fundet", "ikke fundet"},
+		"invalid_id":            {"Ugyldigt id", "id har ikke det korrekte format"},
+		"forbidden":             {"Handlingen er ikke tilladt", "den forsøgte handling er ikke tilladt"},
+		"authentication_failed": {"Godkendelse mislykkedes", "godkendelse mislykkedes"},
+		"duplicate_email":       {"E-mailadressen er allerede i brug", "e-mailadressen findes allerede"},
+		"delivery_not_failed":   {"Leveringen er ikke mislykkedes", "kun mislykkede leveringer kan gentages"},
+	},
+	"es": {
+		"not_found":             {"Recurso no encontrado", "no encontrado"},
+		"invalid_id":            {"Identificador no válido", "el ID no tiene el formato correcto"},
+		"forbidden":             {"Acción no permitida", "la acción intentada no está permitida"},
+		"authentication_failed": {"Error de autenticación", "la autenticación ha fallado"},
+		"duplicate_email":       {"Dirección de correo ya en uso", "correo electrónico duplicado"},
+		"delivery_not_failed":   {"La entrega no ha fallado", "solo se pueden repetir las entregas fallidas"},
+	},
+	"fr": {
+		"not_found":             {"Ressource introuvable", "introuvable"},
+		"invalid_id":            {"Identifiant invalide", "l'ID n'a pas le bon format"},
+		"forbidden":             {"Action non autorisée", "l'action tentée n'est pas autorisée"},
+		"authentication_failed": {"Échec de l'authentification", "l'authentification a échoué"},
+		"duplicate_email":       {"Adresse e-mail déjà utilisée", "adresse e-mail en double"},
+		"delivery_not_failed":   {"La livraison n'a pas échoué", "seules les livraisons échouées peuvent être rejouées"},
+	},
+}
+
+// Translate returns the title and message of the error in the specified
+// language. It falls back to English if there is no translation.
+func (e *Error) Translate(lang string) (title string, msg string) {
+	if t, ok := translations[lang][e.Code]; ok {
+		return t[0], t[1]
+	}
+	return e.Title, e.msg
+}
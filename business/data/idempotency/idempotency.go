@@ -0,0 +1,142 @@
+// Package idempotency contains the storage of responses for requests made
+// with an Idempotency-Key header.
+package idempotency
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/pkg/errors"
+	"github.com/tullo/service/business/data"
+	"github.com/tullo/service/foundation/database"
+	"go.opentelemetry.io/otel"
+)
+
+const name = "idempotency"
+
+// Store manages the set of API's for idempotency key access.
+type Store struct {
+	log *slog.Logger
+	db  *database.DB
+}
+
+// NewStore constructs a Store for api access.
+func NewStore(log *slog.Logger, db *database.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Reserve claims a key for the given user and route. It returns true if the
+// key was not in use and has now been reserved. Otherwise it returns false
+// together with the information stored for the key. Expired keys are removed
+// before the reservation is attempted.
+func (s Store) Reserve(ctx context.Context, traceID string, userID, route, key, requestHash string, now time.Time, ttl time.Duration) (Info, bool, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.idempotency.reserve")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return Info{}, false, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const qd = `
+	DELETE FROM
+		idempotency_keys
+	WHERE
+		user_id = $1 AND route = $2 AND idempotency_key = $3 AND date_expires <= $4`
+
+	if _, err := conn.Exec(ctx, qd, userID, route, key, now.UTC()); err != nil {
+		return Info{}, false, errors.Wrap(err, "deleting expired idempotency key")
+	}
+
+	const qi = `
+	INSERT INTO idempotency_keys
+		(user_id, route, idempotency_key, request_hash, status_code, content_type, body, date_created, date_expires)
+	VALUES
+		($1, $2, $3, $4, 0, '', ''::BYTES, $5, $6)
+	ON CONFLICT (user_id, route, idempotency_key) DO NOTHING`
+
+	tag, err := conn.Exec(ctx, qi, userID, route, key, requestHash, now.UTC(), now.Add(ttl).UTC())
+	if err != nil {
+		return Info{}, false, errors.Wrap(err, "inserting idempotency key")
+	}
+	if tag.RowsAffected() == 1 {
+		return Info{}, true, nil
+	}
+
+	const qs = `
+	SELECT
+		*
+	FROM
+		idempotency_keys
+	WHERE
+		user_id = $1 AND route = $2 AND idempotency_key = $3`
+
+	var info Info
+	if err := pgxscan.Get(ctx, conn, &info, qs, userID, route, key); err != nil {
+		if pgxscan.NotFound(err) {
+			return Info{}, false, data.ErrNotFound
+		}
+		return Info{}, false, errors.Wrapf(err, "selecting idempotency key %q", key)
+	}
+
+	return info, false, nil
+}
+
+// Complete stores the response produced for a reserved key so it can be
+// replayed to later requests using the same key.
+func (s Store) Complete(ctx context.Context, traceID string, userID, route, key string, statusCode int, contentType string, body []byte) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.idempotency.complete")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	UPDATE
+		idempotency_keys
+	SET
+		"status_code" = $4,
+		"content_type" = $5,
+		"body" = $6
+	WHERE
+		user_id = $1 AND route = $2 AND idempotency_key = $3`
+
+	if _, err := conn.Exec(ctx, q, userID, route, key, statusCode, contentType, body); err != nil {
+		return errors.Wrapf(err, "storing response for idempotency key %q", key)
+	}
+
+	return nil
+}
+
+// Release removes a reserved key so the request can be retried with it.
+func (s Store) Release(ctx context.Context, traceID string, userID, route, key string) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.idempotency.release")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	DELETE FROM
+		idempotency_keys
+	WHERE
+		user_id = $1 AND route = $2 AND idempotency_key = $3`
+
+	if _, err := conn.Exec(ctx, q, userID, route, key); err != nil {
+		return errors.Wrapf(err, "releasing idempotency key %q", key)
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package idempotency
+
+import "time"
+
+// Info represents the stored outcome of a request made with an
+// Idempotency-Key. A StatusCode of zero means the original request is still
+// being processed.
+type Info struct {
+	UserID      string    `db:"user_id"`         // User the key is scoped to.
+	Route       string    `db:"route"`           // Method and path the key is scoped to.
+	Key         string    `db:"idempotency_key"` // Value of the Idempotency-Key header.
+	RequestHash string    `db:"request_hash"`    // Hash of the original request body.
+	StatusCode  int       `db:"status_code"`     // Status code of the stored response.
+	ContentType string    `db:"content_type"`    // Content type of the stored response.
+	Body        []byte    `db:"body"`            // Body of the stored response.
+	DateCreated time.Time `db:"date_created"`    // When the key was first used.
+	DateExpires time.Time `db:"date_expires"`    // When the key may be reused.
+}
+
+// Completed reports whether a response has been stored for the key.
+func (i Info) Completed() bool {
+	return i.StatusCode != 0
+}
@@ -0,0 +1,105 @@
+// Package outbox records the events produced by the stores in the
+// transaction making the change, so they are published if and only if the
+// change is committed.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+)
+
+const name = "outbox"
+
+// Set of event types.
+const (
+	TypeSaleCreated    = "sale.created"
+	TypeProductUpdated = "product.updated"
+	TypeProductDeleted = "product.deleted"
+)
+
+// Types lists all event types.
+var Types = []string{TypeSaleCreated, TypeProductUpdated, TypeProductDeleted}
+
+// Event represents a change to a product or one of its sales waiting to be
+// published.
+type Event struct {
+	ID          string          `db:"event_id" json:"id"`               // Unique identifier.
+	Type        string          `db:"event_type" json:"type"`           // Kind of change.
+	ProductID   string          `db:"product_id" json:"product_id"`     // ID of the product changed or sold.
+	OwnerID     string          `db:"owner_id" json:"owner_id"`         // ID of the user who created the product.
+	Data        json.RawMessage `db:"data" json:"data"`                 // The product or sale after the change.
+	DateCreated time.Time       `db:"date_created" json:"date_created"` // When the change was made.
+}
+
+// Notify is called by the stores with the event of a change once the
+// transaction making it committed.
+type Notify func(Event)
+
+// Add records an event in the transaction making the change and returns it.
+// The data is encoded as JSON.
+func Add(ctx context.Context, tx pgx.Tx, typ string, productID string, ownerID string, data interface{}, now time.Time) (Event, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.outbox.add")
+	defer span.End()
+
+	doc, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, errors.Wrap(err, "encoding event data")
+	}
+
+	e := Event{
+		ID:          uuid.New().String(),
+		Type:        typ,
+		ProductID:   productID,
+		OwnerID:     ownerID,
+		Data:        doc,
+		DateCreated: now.UTC(),
+	}
+
+	const q = `
+	INSERT INTO outbox
+		(event_id, event_type, product_id, owner_id, data, date_created)
+	VALUES
+		($1, $2, $3, $4, $5, $6)`
+
+	if _, err := tx.Exec(ctx, q, e.ID, e.Type, e.ProductID, e.OwnerID, e.Data, e.DateCreated); err != nil {
+		return Event{}, errors.Wrap(err, "inserting event")
+	}
+
+	return e, nil
+}
+
+// Take removes up to limit of the oldest events from the outbox and returns
+// them. The events are only removed once the transaction commits, so the
+// caller must commit after it has stored what it needs to publish them.
+func Take(ctx context.Context, tx pgx.Tx, limit int) ([]Event, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.outbox.take")
+	defer span.End()
+
+	const q = `
+	DELETE FROM
+		outbox
+	WHERE
+		event_id IN (SELECT event_id FROM outbox ORDER BY date_created, event_id LIMIT $1)
+	RETURNING
+		event_id, event_type, product_id, owner_id, data, date_created`
+
+	var events []Event
+	if err := pgxscan.Select(ctx, tx, &events, q, limit); err != nil {
+		return nil, errors.Wrap(err, "taking events")
+	}
+
+	// The order of the deleted rows isn't defined.
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].DateCreated.Before(events[j].DateCreated)
+	})
+
+	return events, nil
+}
@@ -18,7 +18,7 @@ type Info struct {
 // NewProduct is what we require from clients when adding a Product.
 type NewProduct struct {
 	Name     string `json:"name" validate:"required"`
-	Cost     int    `json:"cost" validate:"required,gte=0"`
+	Cost     int    `json:"cost" validate:"required,cents"`
 	Quantity int    `json:"quantity" validate:"gte=1"`
 }
 
@@ -30,6 +30,32 @@ type NewProduct struct {
 // we make exceptions around marshalling/unmarshalling.
 type UpdateProduct struct {
 	Name     *string `json:"name"`
-	Cost     *int    `json:"cost" validate:"omitempty,gte=0"`
+	Cost     *int    `json:"cost" validate:"omitempty,cents"`
 	Quantity *int    `json:"quantity" validate:"omitempty,gte=1"`
 }
+
+// PatchProduct is the document JSON patches of a Product are applied to. It
+// holds the fields clients may modify.
+type PatchProduct struct {
+	Name     string `json:"name" validate:"required"`
+	Cost     int    `json:"cost" validate:"cents"`
+	Quantity int    `json:"quantity" validate:"gte=1"`
+}
+
+// NewPatchProduct returns the patch document of an existing Product.
+func NewPatchProduct(prd Info) PatchProduct {
+	return PatchProduct{
+		Name:     prd.Name,
+		Cost:     prd.Cost,
+		Quantity: prd.Quantity,
+	}
+}
+
+// Update returns the update writing the patched document.
+func (pp PatchProduct) Update() UpdateProduct {
+	return UpdateProduct{
+		Name:     &pp.Name,
+		Cost:     &pp.Cost,
+		Quantity: &pp.Quantity,
+	}
+}
@@ -3,31 +3,41 @@ package product
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/pkg/errors"
 	"github.com/tullo/service/business/auth"
 	"github.com/tullo/service/business/data"
+	"github.com/tullo/service/business/data/outbox"
+	"github.com/tullo/service/business/validate"
 	"github.com/tullo/service/foundation/database"
 	"go.opentelemetry.io/otel"
 )
 
 const name = "product"
 
+// exportBatchSize is the number of rows fetched from the export cursor at once.
+const exportBatchSize = 100
+
 // Store manages the set of API's for product access.
 type Store struct {
-	log *log.Logger
-	db  *database.DB
+	log    *slog.Logger
+	db     *database.DB
+	notify outbox.Notify
 }
 
-// NewStore constructs a Store for api access.
-func NewStore(log *log.Logger, db *database.DB) Store {
+// NewStore constructs a Store for api access. The events of the committed
+// changes are passed to notify, when it's set.
+func NewStore(log *slog.Logger, db *database.DB, notify outbox.Notify) Store {
 	return Store{
-		log: log,
-		db:  db,
+		log:    log,
+		db:     db,
+		notify: notify,
 	}
 }
 
@@ -37,6 +47,10 @@ func (s Store) Create(ctx context.Context, traceID string, claims auth.Claims, n
 	ctx, span := otel.Tracer(name).Start(ctx, "business.data.product.create")
 	defer span.End()
 
+	if err := validate.Check(np); err != nil {
+		return Info{}, errors.Wrap(err, "validating data")
+	}
+
 	conn, err := s.db.Acquire(ctx)
 	if err != nil {
 		return Info{}, errors.Wrap(err, "acquire db connection")
@@ -72,6 +86,10 @@ func (s Store) Update(ctx context.Context, traceID string, claims auth.Claims, p
 	ctx, span := otel.Tracer(name).Start(ctx, "business.data.product.update")
 	defer span.End()
 
+	if err := validate.Check(up); err != nil {
+		return errors.Wrap(err, "validating data")
+	}
+
 	prd, err := s.QueryByID(ctx, traceID, productID)
 	if err != nil {
 		return err
@@ -111,9 +129,27 @@ func (s Store) Update(ctx context.Context, traceID string, claims auth.Claims, p
 	WHERE
 		product_id = $1`
 
-	if _, err = conn.Exec(ctx, q, productID, prd.Name, prd.Cost, prd.Quantity, prd.DateUpdated); err != nil {
+	// Record the event for the change in the same transaction.
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, q, productID, prd.Name, prd.Cost, prd.Quantity, prd.DateUpdated); err != nil {
 		return errors.Wrap(err, "updating product")
 	}
+	e, err := outbox.Add(ctx, tx, outbox.TypeProductUpdated, prd.ID, prd.UserID, prd, now)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Wrap(err, "commit transaction")
+	}
+	if s.notify != nil {
+		s.notify(e)
+	}
 
 	return nil
 }
@@ -138,15 +174,58 @@ func (s Store) Delete(ctx context.Context, traceID string, claims auth.Claims, p
 	}
 	defer conn.Release()
 
+	// Record the event for the change in the same transaction. The event
+	// carries the product as it was before it was deleted.
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	const qs = `
+	SELECT
+		p.*,
+		COALESCE(SUM(s.quantity), 0) AS sold,
+		COALESCE(SUM(s.paid), 0) AS revenue
+	FROM
+		products AS p
+	LEFT JOIN
+		sales AS s ON p.product_id = s.product_id
+	WHERE
+		p.product_id = $1
+	GROUP BY
+		p.product_id`
+
+	var prd Info
+	if err := pgxscan.Get(ctx, tx, &prd, qs, productID); err != nil {
+
+		// Deleting a product which doesn't exist succeeds without an event.
+		if pgxscan.NotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "selecting product %q", productID)
+	}
+
 	const q = `
 	DELETE FROM
 		products
 	WHERE
 		product_id = $1`
 
-	if _, err := conn.Exec(ctx, q, productID); err != nil {
+	if _, err := tx.Exec(ctx, q, productID); err != nil {
 		return errors.Wrapf(err, "deleting product %s", productID)
 	}
+	e, err := outbox.Add(ctx, tx, outbox.TypeProductDeleted, prd.ID, prd.UserID, prd, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Wrap(err, "commit transaction")
+	}
+	if s.notify != nil {
+		s.notify(e)
+	}
 
 	return nil
 }
@@ -193,6 +272,60 @@ func (s Store) Query(ctx context.Context, traceID string, pageNumber int, rowsPe
 	return products, nil
 }
 
+// QueryWithoutTotals gets a page of Products like Query but leaves Sold and
+// Revenue zero, which skips the aggregation over the sales.
+func (s Store) QueryWithoutTotals(ctx context.Context, traceID string, pageNumber int, rowsPerPage int) ([]Info, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.product.querywithouttotals")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	SELECT
+		p.*,
+		0 AS sold,
+		0 AS revenue
+	FROM
+		products AS p
+	ORDER BY
+		user_id
+	OFFSET $1 ROWS FETCH NEXT $2 ROWS ONLY`
+
+	offset := (pageNumber - 1) * rowsPerPage
+
+	products := make([]Info, 0, rowsPerPage)
+	if err := pgxscan.Select(ctx, conn, &products, q, offset, rowsPerPage); err != nil {
+		return nil, errors.Wrap(err, "query products")
+	}
+
+	return products, nil
+}
+
+// Count returns the number of products.
+func (s Store) Count(ctx context.Context, traceID string) (int, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.product.count")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `SELECT count(*) FROM products`
+
+	var count int
+	if err := conn.QueryRow(ctx, q).Scan(&count); err != nil {
+		return 0, errors.Wrap(err, "counting products")
+	}
+
+	return count, nil
+}
+
 // QueryByID finds the product identified by a given ID.
 func (s Store) QueryByID(ctx context.Context, traceID string, productID string) (Info, error) {
 	ctx, span := otel.Tracer(name).Start(ctx, "business.data.product.querybyid")
@@ -233,3 +366,98 @@ func (s Store) QueryByID(ctx context.Context, traceID string, productID string)
 
 	return prd, nil
 }
+
+// QueryByIDWithoutTotals finds a product like QueryByID but leaves Sold and
+// Revenue zero, which skips the aggregation over the sales.
+func (s Store) QueryByIDWithoutTotals(ctx context.Context, traceID string, productID string) (Info, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.product.querybyidwithouttotals")
+	defer span.End()
+
+	if _, err := uuid.Parse(productID); err != nil {
+		return Info{}, data.ErrInvalidID
+	}
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return Info{}, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	SELECT
+		p.*,
+		0 AS sold,
+		0 AS revenue
+	FROM
+		products AS p
+	WHERE
+		p.product_id = $1`
+
+	var prd Info
+	if err := pgxscan.Get(ctx, conn, &prd, q, productID); err != nil {
+		if pgxscan.NotFound(err) {
+			return Info{}, data.ErrNotFound
+		}
+
+		return Info{}, errors.Wrapf(err, "selecting product %q", productID)
+	}
+
+	return prd, nil
+}
+
+// Export streams all Products from the database to fn. The rows are read
+// through a server-side cursor in batches so the full result set is never
+// held in memory. It stops at the first error returned by fn or once the
+// context is canceled.
+func (s Store) Export(ctx context.Context, traceID string, fn func(Info) error) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.product.export")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return errors.Wrap(err, "begin export transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	const q = `
+	DECLARE product_export CURSOR FOR
+	SELECT
+		p.*,
+		COALESCE(SUM(s.quantity), 0) AS sold,
+		COALESCE(SUM(s.paid), 0) AS revenue
+	FROM
+		products AS p
+	LEFT JOIN
+		sales AS s ON p.product_id = s.product_id
+	GROUP BY
+		p.product_id
+	ORDER BY
+		p.product_id`
+
+	if _, err := tx.Exec(ctx, q); err != nil {
+		return errors.Wrap(err, "declaring product cursor")
+	}
+
+	fetch := fmt.Sprintf(`FETCH %d FROM product_export`, exportBatchSize)
+	for {
+		products := make([]Info, 0, exportBatchSize)
+		if err := pgxscan.Select(ctx, tx, &products, fetch); err != nil {
+			return errors.Wrap(err, "fetching products")
+		}
+		if len(products) == 0 {
+			return nil
+		}
+
+		for _, prd := range products {
+			if err := fn(prd); err != nil {
+				return err
+			}
+		}
+	}
+}
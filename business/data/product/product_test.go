@@ -10,6 +10,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/tullo/service/business/auth"
 	"github.com/tullo/service/business/data"
+	"github.com/tullo/service/business/data/outbox"
 	"github.com/tullo/service/business/data/product"
 	"github.com/tullo/service/business/data/schema"
 	"github.com/tullo/service/business/data/tests"
@@ -22,7 +23,11 @@ func TestProduct(t *testing.T) {
 	log, db, teardown := tests.NewUnit(t, ctx)
 	t.Cleanup(teardown)
 
-	p := product.NewStore(log, db)
+	// The events of the committed changes.
+	var notified []string
+	p := product.NewStore(log, db, func(e outbox.Event) {
+		notified = append(notified, e.Type)
+	})
 
 	t.Log("Given the need to work with Product records.")
 	{
@@ -129,6 +134,17 @@ func TestProduct(t *testing.T) {
 				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to retrieve deleted product : %s.", tests.Failed, testID, err)
 			}
 			t.Logf("\t%s\tTest %d:\tShould NOT be able to retrieve deleted product.", tests.Success, testID)
+
+			if err := p.Delete(ctx, traceID, claims, prd.ID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to delete a missing product : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to delete a missing product.", tests.Success, testID)
+
+			exp := []string{outbox.TypeProductUpdated, outbox.TypeProductUpdated, outbox.TypeProductDeleted}
+			if diff := cmp.Diff(exp, notified); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould notify the events of the committed changes. Diff:\n%s", tests.Failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould notify the events of the committed changes.", tests.Success, testID)
 		}
 	}
 }
@@ -150,7 +166,7 @@ func TestProductPaging(t *testing.T) {
 
 	schema.Seed(ctx, db)
 
-	p := product.NewStore(log, db)
+	p := product.NewStore(log, db, nil)
 
 	t.Log("Given the need to page through Product records.")
 	{
@@ -17,5 +17,5 @@ type Info struct {
 // NewSale is what we require from clients for recording new transactions.
 type NewSale struct {
 	Quantity int `json:"quantity" validate:"gte=0"`
-	Paid     int `json:"paid" validate:"gte=0"`
+	Paid     int `json:"paid" validate:"cents"`
 }
@@ -2,29 +2,80 @@ package sale
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
+	validator "github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/pkg/errors"
+	"github.com/tullo/service/business/data"
+	"github.com/tullo/service/business/data/outbox"
+	"github.com/tullo/service/business/validate"
 	"github.com/tullo/service/foundation/database"
 	"go.opentelemetry.io/otel"
 )
 
 const name = "sale"
 
+// exportBatchSize is the number of rows fetched from the export cursor at once.
+const exportBatchSize = 100
+
+// maxPaidFactor bounds the amount paid for a sale by a multiple of the list
+// price of the units sold. Some haggling is expected but larger amounts are
+// most likely typos.
+const maxPaidFactor = 2
+
+// pricedSale is a new sale together with the cost of the product sold. It is
+// used to validate the amount paid against the list price.
+type pricedSale struct {
+	Quantity int `json:"quantity"`
+	Paid     int `json:"paid"`
+	Cost     int `json:"cost"`
+}
+
+func init() {
+	validate.RegisterStructValidation(checkPaid, pricedSale{})
+
+	messages := map[string]string{
+		"en": "{0} must not exceed {1}, twice the list price of the units sold",
+		"de": "{0} darf {1}, den doppelten Listenpreis der verkauften Einheiten, nicht überschreiten",
+		"da": "{0} må ikke overstige {1}, det dobbelte af listeprisen for de solgte enheder",
+		"es": "{0} no debe superar {1}, el doble del precio de lista de las unidades vendidas",
+		"fr": "{0} ne doit pas dépasser {1}, le double du prix catalogue des unités vendues",
+	}
+	if err := validate.RegisterTranslation("maxpaid", messages); err != nil {
+		panic(err)
+	}
+}
+
+// checkPaid validates that paid <= quantity * cost * maxPaidFactor.
+func checkPaid(sl validator.StructLevel) {
+	ps := sl.Current().Interface().(pricedSale)
+
+	max := int64(ps.Quantity) * int64(ps.Cost) * maxPaidFactor
+	if int64(ps.Paid) > max {
+		sl.ReportError(ps.Paid, "paid", "Paid", "maxpaid", strconv.FormatInt(max, 10))
+	}
+}
+
 // Store manages the set of API's for sales access.
 type Store struct {
-	log *log.Logger
-	db  *database.DB
+	log    *slog.Logger
+	db     *database.DB
+	notify outbox.Notify
 }
 
-// NewStore constructs a Store for api access.
-func NewStore(log *log.Logger, db *database.DB) Store {
+// NewStore constructs a Store for api access. The events of the committed
+// sales are passed to notify, when it's set.
+func NewStore(log *slog.Logger, db *database.DB, notify outbox.Notify) Store {
 	return Store{
-		log: log,
-		db:  db,
+		log:    log,
+		db:     db,
+		notify: notify,
 	}
 }
 
@@ -33,12 +84,43 @@ func (s Store) AddSale(ctx context.Context, traceID string, ns NewSale, productI
 	ctx, span := otel.Tracer(name).Start(ctx, "business.data.sale.add")
 	defer span.End()
 
+	if _, err := uuid.Parse(productID); err != nil {
+		return Info{}, data.ErrInvalidID
+	}
+
+	if err := validate.Check(ns); err != nil {
+		return Info{}, errors.Wrap(err, "validating data")
+	}
+
 	conn, err := s.db.Acquire(ctx)
 	if err != nil {
 		return Info{}, errors.Wrap(err, "acquire db connection")
 	}
 	defer conn.Release()
 
+	// Validate the amount paid against the list price of the product.
+	const qc = `SELECT cost, user_id FROM products WHERE product_id = $1`
+
+	var prd struct {
+		Cost   int    `db:"cost"`
+		UserID string `db:"user_id"`
+	}
+	if err := pgxscan.Get(ctx, conn, &prd, qc, productID); err != nil {
+		if pgxscan.NotFound(err) {
+			return Info{}, data.ErrNotFound
+		}
+		return Info{}, errors.Wrapf(err, "selecting product %q", productID)
+	}
+
+	ps := pricedSale{
+		Quantity: ns.Quantity,
+		Paid:     ns.Paid,
+		Cost:     prd.Cost,
+	}
+	if err := validate.Check(ps); err != nil {
+		return Info{}, errors.Wrap(err, "validating data")
+	}
+
 	sale := Info{
 		ID:          uuid.New().String(),
 		ProductID:   productID,
@@ -50,10 +132,28 @@ func (s Store) AddSale(ctx context.Context, traceID string, ns NewSale, productI
 	const q = `INSERT INTO sales (sale_id, product_id, quantity, paid, date_created)
 		VALUES ($1, $2, $3, $4, $5)`
 
-	_, err = conn.Exec(ctx, q, sale.ID, sale.ProductID, sale.Quantity, sale.Paid, sale.DateCreated)
+	// Record the event for the sale in the same transaction.
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return Info{}, errors.Wrap(err, "begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, q, sale.ID, sale.ProductID, sale.Quantity, sale.Paid, sale.DateCreated)
 	if err != nil {
 		return Info{}, errors.Wrap(err, "inserting sale")
 	}
+	e, err := outbox.Add(ctx, tx, outbox.TypeSaleCreated, productID, prd.UserID, sale, now)
+	if err != nil {
+		return Info{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Info{}, errors.Wrap(err, "commit transaction")
+	}
+	if s.notify != nil {
+		s.notify(e)
+	}
 
 	return sale, nil
 }
@@ -63,6 +163,10 @@ func (s Store) List(ctx context.Context, traceID string, productID string) ([]In
 	ctx, span := otel.Tracer(name).Start(ctx, "business.data.sale.list")
 	defer span.End()
 
+	if _, err := uuid.Parse(productID); err != nil {
+		return nil, data.ErrInvalidID
+	}
+
 	conn, err := s.db.Acquire(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "acquire db connection")
@@ -78,3 +182,144 @@ func (s Store) List(ctx context.Context, traceID string, productID string) ([]In
 
 	return sales, nil
 }
+
+// Query gets a page of the Sales of a Product from the database, the most
+// recent first.
+func (s Store) Query(ctx context.Context, traceID string, productID string, pageNumber int, rowsPerPage int) ([]Info, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.sale.query")
+	defer span.End()
+
+	if _, err := uuid.Parse(productID); err != nil {
+		return nil, data.ErrInvalidID
+	}
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	SELECT
+		*
+	FROM
+		sales
+	WHERE
+		product_id = $1
+	ORDER BY
+		date_created DESC, sale_id
+	OFFSET $2 ROWS FETCH NEXT $3 ROWS ONLY`
+
+	offset := (pageNumber - 1) * rowsPerPage
+
+	sales := make([]Info, 0, rowsPerPage)
+	if err := pgxscan.Select(ctx, conn, &sales, q, productID, offset, rowsPerPage); err != nil {
+		return nil, errors.Wrap(err, "selecting sales")
+	}
+
+	return sales, nil
+}
+
+// Count returns the number of Sales of a Product.
+func (s Store) Count(ctx context.Context, traceID string, productID string) (int, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.sale.count")
+	defer span.End()
+
+	if _, err := uuid.Parse(productID); err != nil {
+		return 0, data.ErrInvalidID
+	}
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `SELECT count(*) FROM sales WHERE product_id = $1`
+
+	var count int
+	if err := conn.QueryRow(ctx, q, productID).Scan(&count); err != nil {
+		return 0, errors.Wrap(err, "counting sales")
+	}
+
+	return count, nil
+}
+
+// QueryByProducts gets the Sales of several Products from the database keyed
+// by the ID of the Product.
+func (s Store) QueryByProducts(ctx context.Context, traceID string, productIDs []string) (map[string][]Info, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.sale.querybyproducts")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	SELECT
+		*
+	FROM
+		sales
+	WHERE
+		product_id = ANY($1::UUID[])
+	ORDER BY
+		date_created DESC, sale_id`
+
+	var sales []Info
+	if err := pgxscan.Select(ctx, conn, &sales, q, productIDs); err != nil {
+		return nil, errors.Wrap(err, "selecting sales")
+	}
+
+	byProduct := make(map[string][]Info, len(productIDs))
+	for _, sl := range sales {
+		byProduct[sl.ProductID] = append(byProduct[sl.ProductID], sl)
+	}
+
+	return byProduct, nil
+}
+
+// Export streams all Sales from the database to fn. The rows are read
+// through a server-side cursor in batches so the full result set is never
+// held in memory. It stops at the first error returned by fn or once the
+// context is canceled.
+func (s Store) Export(ctx context.Context, traceID string, fn func(Info) error) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.sale.export")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return errors.Wrap(err, "begin export transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	const q = `DECLARE sale_export CURSOR FOR SELECT * FROM sales ORDER BY date_created, sale_id`
+
+	if _, err := tx.Exec(ctx, q); err != nil {
+		return errors.Wrap(err, "declaring sale cursor")
+	}
+
+	fetch := fmt.Sprintf(`FETCH %d FROM sale_export`, exportBatchSize)
+	for {
+		sales := make([]Info, 0, exportBatchSize)
+		if err := pgxscan.Select(ctx, tx, &sales, fetch); err != nil {
+			return errors.Wrap(err, "fetching sales")
+		}
+		if len(sales) == 0 {
+			return nil
+		}
+
+		for _, sale := range sales {
+			if err := fn(sale); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	user_id         UUID,
+	route           TEXT,
+	idempotency_key TEXT,
+	request_hash    TEXT,
+	status_code     INT,
+	content_type    TEXT,
+	body            BYTES,
+	date_created    TIMESTAMP,
+	date_expires    TIMESTAMP,
+
+	PRIMARY KEY (user_id, route, idempotency_key)
+);
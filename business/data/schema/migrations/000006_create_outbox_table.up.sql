@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS outbox (
+	event_id     UUID,
+	event_type   TEXT,
+	product_id   UUID,
+	owner_id     UUID,
+	data         JSONB,
+	date_created TIMESTAMP,
+
+	PRIMARY KEY (event_id),
+	INDEX (date_created)
+);
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+	endpoint_id  UUID,
+	url          TEXT,
+	events       TEXT[],
+
+	-- The secret is stored in plaintext on purpose: the dispatcher needs it
+	-- to compute the HMAC signatures of the deliveries, so an encryption key
+	-- would have to sit next to the database credentials anyway. Access to
+	-- this table allows forging deliveries, treat it like the credentials.
+	secret       TEXT,
+	date_created TIMESTAMP,
+
+	PRIMARY KEY (endpoint_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	delivery_id  UUID,
+	endpoint_id  UUID,
+	event_id     UUID,
+	event_type   TEXT,
+	payload      BYTES,
+	status       TEXT,
+	attempts     INT,
+	status_code  INT,
+	last_error   TEXT,
+	next_attempt TIMESTAMP,
+	date_created TIMESTAMP,
+	date_updated TIMESTAMP,
+
+	PRIMARY KEY (delivery_id),
+	FOREIGN KEY (endpoint_id) REFERENCES webhook_endpoints(endpoint_id) ON DELETE CASCADE,
+	INDEX (status, next_attempt)
+);
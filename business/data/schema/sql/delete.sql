@@ -1,3 +1,7 @@
+DELETE FROM webhook_deliveries;
+DELETE FROM webhook_endpoints;
+DELETE FROM outbox;
+DELETE FROM idempotency_keys;
 DELETE FROM sales;
 DELETE FROM products;
 DELETE FROM users;
@@ -7,7 +7,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 	"testing"
@@ -22,6 +22,7 @@ import (
 	"github.com/tullo/service/business/data/user"
 	"github.com/tullo/service/foundation/database"
 	"github.com/tullo/service/foundation/keystore"
+	"github.com/tullo/service/foundation/logger"
 )
 
 // Success and failure markers.
@@ -218,16 +219,15 @@ func createDatabase(ctx context.Context, pool *database.DB, name string) error {
 	return err
 }
 
-func NewUnit(t *testing.T, ctx context.Context) (*log.Logger, *database.DB, func()) {
-	// log := log.New(io.Discard, "", log.LstdFlags)
-	// log.SetFlags(0) // For completely disabling logs
-	log := log.New(os.Stdout, "TEST : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
+func NewUnit(t *testing.T, ctx context.Context) (*slog.Logger, *database.DB, func()) {
+	// log := logger.New(io.Discard, "TEST", slog.LevelInfo) // For completely disabling logs
+	log := logger.New(os.Stdout, "TEST", slog.LevelInfo)
 
 	dbaddr, ok := os.LookupEnv("DATABASE_URL")
 	if !ok {
 		t.Fatal("database url not defined")
 	}
-	maindb, err := database.ConnectWithURI(ctx, dbaddr)
+	maindb, err := database.ConnectWithURI(ctx, log, dbaddr)
 	if err != nil {
 		t.Fatal(fmt.Errorf("database connection error: %w", err))
 	}
@@ -248,7 +248,7 @@ func NewUnit(t *testing.T, ctx context.Context) (*log.Logger, *database.DB, func
 		t.Fatal(fmt.Errorf("failed to modify connnection string: %w", err))
 	}
 
-	db, err := database.ConnectWithURI(ctx, connstr)
+	db, err := database.ConnectWithURI(ctx, log, connstr)
 	if err != nil {
 		t.Fatal(fmt.Errorf("database connection error: %w", err))
 	}
@@ -282,8 +282,8 @@ func containerLog(t *testing.T, c *Container) {
 // required table structure but the database is otherwise empty. It returns
 // the database to use as well as a function to call at the end of the test.
 /*
-func NewUnit_Depricated(t *testing.T, ctr ContainerSpec) (*log.Logger, *database.DB, func()) {
-	log := log.New(os.Stdout, "TEST : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
+func NewUnit_Depricated(t *testing.T, ctr ContainerSpec) (*slog.Logger, *database.DB, func()) {
+	log := logger.New(os.Stdout, "TEST", slog.LevelInfo)
 
 	c, err := NewContainer("", ctr.Repository, ctr.Tag, ctr.Cmd, ctr.Args)
 	if err != nil {
@@ -331,7 +331,7 @@ type Test struct {
 	Auth     *auth.Auth
 	DB       *database.DB
 	KID      string
-	Log      *log.Logger
+	Log      *slog.Logger
 	Teardown func()
 	TraceID  string
 	t        *testing.T
@@ -15,11 +15,17 @@ type Info struct {
 	DateUpdated  time.Time `db:"date_updated" json:"date_updated"`
 }
 
+// Profile is the public part of a user that can be shown to other users.
+type Profile struct {
+	ID   string `db:"user_id" json:"id"`
+	Name string `db:"name" json:"name"`
+}
+
 // NewUser contains information needed to create a new User.
 type NewUser struct {
 	Name            string   `json:"name" validate:"required"`
 	Email           string   `json:"email" validate:"required,email"`
-	Roles           []string `json:"roles" validate:"required"`
+	Roles           []string `json:"roles" validate:"required,dive,role"`
 	Password        string   `json:"password" validate:"required"`
 	PasswordConfirm string   `json:"password_confirm" validate:"eqfield=Password"`
 }
@@ -33,7 +39,38 @@ type NewUser struct {
 type UpdateUser struct {
 	Name            *string  `json:"name"`
 	Email           *string  `json:"email" validate:"omitempty,email"`
-	Roles           []string `json:"roles"`
+	Roles           []string `json:"roles" validate:"omitempty,dive,role"`
 	Password        *string  `json:"password"`
 	PasswordConfirm *string  `json:"password_confirm" validate:"omitempty,eqfield=Password"`
 }
+
+// PatchUser is the document JSON patches of a User are applied to. It holds
+// the fields clients may modify. The password is not part of the current
+// document but may be added by a patch.
+type PatchUser struct {
+	Name            string   `json:"name" validate:"required"`
+	Email           string   `json:"email" validate:"required,email"`
+	Roles           []string `json:"roles" validate:"required,dive,role"`
+	Password        *string  `json:"password,omitempty"`
+	PasswordConfirm *string  `json:"password_confirm,omitempty" validate:"omitempty,eqfield=Password"`
+}
+
+// NewPatchUser returns the patch document of an existing User.
+func NewPatchUser(usr Info) PatchUser {
+	return PatchUser{
+		Name:  usr.Name,
+		Email: usr.Email,
+		Roles: usr.Roles,
+	}
+}
+
+// Update returns the update writing the patched document.
+func (pu PatchUser) Update() UpdateUser {
+	return UpdateUser{
+		Name:            &pu.Name,
+		Email:           &pu.Email,
+		Roles:           pu.Roles,
+		Password:        pu.Password,
+		PasswordConfirm: pu.PasswordConfirm,
+	}
+}
@@ -3,7 +3,7 @@ package user
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/alexedwards/argon2id"
@@ -14,6 +14,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/tullo/service/business/auth"
 	"github.com/tullo/service/business/data"
+	"github.com/tullo/service/business/validate"
 	"github.com/tullo/service/foundation/database"
 	"go.opentelemetry.io/otel"
 )
@@ -25,12 +26,12 @@ const uniqueViolation = "23505"
 
 // Store manages the set of API's for user access.
 type Store struct {
-	log *log.Logger
+	log *slog.Logger
 	db  *database.DB
 }
 
 // NewStore constructs a Store for api access.
-func NewStore(log *log.Logger, db *database.DB) Store {
+func NewStore(log *slog.Logger, db *database.DB) Store {
 	return Store{
 		log: log,
 		db:  db,
@@ -42,6 +43,10 @@ func (s Store) Create(ctx context.Context, traceID string, nu NewUser, now time.
 	ctx, span := otel.Tracer(name).Start(ctx, "business.data.user.create")
 	defer span.End()
 
+	if err := validate.Check(nu); err != nil {
+		return Info{}, errors.Wrap(err, "validating data")
+	}
+
 	conn, err := s.db.Acquire(ctx)
 	if err != nil {
 		return Info{}, errors.Wrap(err, "acquire db connection")
@@ -88,6 +93,10 @@ func (s Store) Update(ctx context.Context, traceID string, claims auth.Claims, u
 	ctx, span := otel.Tracer(name).Start(ctx, "business.data.user.update")
 	defer span.End()
 
+	if err := validate.Check(uu); err != nil {
+		return errors.Wrap(err, "validating data")
+	}
+
 	usr, err := s.QueryByID(ctx, traceID, claims, userID)
 	if err != nil {
 		return err
@@ -207,6 +216,60 @@ func (s Store) Query(ctx context.Context, traceID string, pageNumber int, rowsPe
 	return users, nil
 }
 
+// Count returns the number of users.
+func (s Store) Count(ctx context.Context, traceID string) (int, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.user.count")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `SELECT count(*) FROM users`
+
+	var count int
+	if err := conn.QueryRow(ctx, q).Scan(&count); err != nil {
+		return 0, errors.Wrap(err, "counting users")
+	}
+
+	return count, nil
+}
+
+// QueryProfiles gets the public profiles of several users from the database
+// keyed by the ID of the user. Unknown users are left out.
+func (s Store) QueryProfiles(ctx context.Context, traceID string, userIDs []string) (map[string]Profile, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.user.queryprofiles")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	SELECT
+		user_id, name
+	FROM
+		users
+	WHERE
+		user_id = ANY($1::UUID[])`
+
+	var profiles []Profile
+	if err := pgxscan.Select(ctx, conn, &profiles, q, userIDs); err != nil {
+		return nil, errors.Wrap(err, "selecting profiles")
+	}
+
+	byID := make(map[string]Profile, len(profiles))
+	for _, p := range profiles {
+		byID[p.ID] = p
+	}
+
+	return byID, nil
+}
+
 // QueryByID gets the specified user from the database.
 func (s Store) QueryByID(ctx context.Context, traceID string, claims auth.Claims, userID string) (Info, error) {
 	ctx, span := otel.Tracer(name).Start(ctx, "business.data.user.querybyid")
@@ -0,0 +1,281 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/foundation/database"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+)
+
+// Headers set on the requests delivering events.
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-ID"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// batchSize is the number of events fanned out and deliveries attempted per
+// dispatch.
+const batchSize = 100
+
+// Config represents the settings of the dispatcher.
+type Config struct {
+	Interval    time.Duration // How often due deliveries are attempted.
+	Timeout     time.Duration // How long an endpoint has to respond.
+	MaxAttempts int           // Attempts after which a delivery fails.
+	MinBackoff  time.Duration // Delay after the first failed attempt.
+	MaxBackoff  time.Duration // Longest delay between attempts.
+
+	// AllowPrivate permits endpoints on loopback, link-local and private
+	// addresses. They're refused by default, so registering an endpoint
+	// can't be used to reach the services next to the API.
+	AllowPrivate bool
+}
+
+// Dispatcher delivers the events of the outbox to the registered endpoints.
+type Dispatcher struct {
+	log    *slog.Logger
+	store  Store
+	cfg    Config
+	client *http.Client
+}
+
+// NewDispatcher constructs a Dispatcher delivering the events with the
+// settings.
+func NewDispatcher(log *slog.Logger, db *database.DB, cfg Config) (*Dispatcher, error) {
+	switch {
+	case cfg.Interval <= 0:
+		return nil, errors.Errorf("webhook interval %v isn't positive", cfg.Interval)
+	case cfg.Timeout <= 0:
+		return nil, errors.Errorf("webhook timeout %v isn't positive", cfg.Timeout)
+	case cfg.MaxAttempts <= 0:
+		return nil, errors.Errorf("webhook max attempts %d isn't positive", cfg.MaxAttempts)
+	case cfg.MinBackoff <= 0:
+		return nil, errors.Errorf("webhook min backoff %v isn't positive", cfg.MinBackoff)
+	case cfg.MaxBackoff < cfg.MinBackoff:
+		return nil, errors.Errorf("webhook max backoff %v is below the min backoff %v", cfg.MaxBackoff, cfg.MinBackoff)
+	}
+
+	d := Dispatcher{
+		log:    log,
+		store:  NewStore(log, db),
+		cfg:    cfg,
+		client: NewClient(cfg),
+	}
+
+	return &d, nil
+}
+
+// NewClient constructs the client posting the events. It doesn't follow
+// redirects, and refuses private addresses unless the config allows them.
+func NewClient(cfg Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !cfg.AllowPrivate {
+
+		// The address is checked once resolved, when dialing, so a host
+		// can't resolve to a private address after being registered.
+		dialer := net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   refusePrivate,
+		}
+		transport.DialContext = dialer.DialContext
+
+		// A proxy would be dialed instead of the endpoint.
+		transport.Proxy = nil
+	}
+
+	return &http.Client{
+		Transport: otelhttp.NewTransport(transport),
+		Timeout:   cfg.Timeout,
+
+		// A redirect could lead to an address refused otherwise, the
+		// endpoint has to accept the event itself.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run dispatches the events every interval until the context is canceled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Dispatch(ctx); err != nil && ctx.Err() == nil {
+				d.log.ErrorContext(ctx, "dispatching webhooks", "error", err)
+			}
+		}
+	}
+}
+
+// Dispatch queues the deliveries of the events in the outbox and attempts
+// the deliveries which are due.
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.webhook.dispatch")
+	defer span.End()
+
+	traceID := span.SpanContext().TraceID().String()
+
+	for {
+		n, err := d.store.Fanout(ctx, traceID, batchSize, time.Now())
+		if err != nil {
+			return errors.Wrap(err, "fanning out events")
+		}
+		if n < batchSize {
+			break
+		}
+	}
+
+	// Deliveries not recorded before their lease ends are claimed again.
+	lease := d.cfg.Timeout + time.Minute
+
+	deliveries, endpoints, err := d.store.Claim(ctx, traceID, batchSize, lease, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "claiming deliveries")
+	}
+
+	var wg sync.WaitGroup
+	for _, dl := range deliveries {
+		wg.Add(1)
+		go func(dl Delivery) {
+			defer wg.Done()
+
+			dl = d.attempt(ctx, dl, endpoints[dl.EndpointID])
+			if err := d.store.Record(ctx, traceID, dl, time.Now()); err != nil {
+				d.log.ErrorContext(ctx, "recording webhook delivery", "delivery_id", dl.ID, "error", err)
+			}
+		}(dl)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// attempt delivers the event to the endpoint and returns the delivery with
+// the outcome.
+func (d *Dispatcher) attempt(ctx context.Context, dl Delivery, ep Endpoint) Delivery {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.webhook.attempt")
+	defer span.End()
+
+	dl.Attempts++
+	dl.StatusCode = 0
+	dl.LastError = ""
+
+	err := d.post(ctx, &dl, ep)
+	if err == nil {
+		dl.Status = StatusDelivered
+		return dl
+	}
+
+	dl.LastError = err.Error()
+	if dl.Attempts >= d.cfg.MaxAttempts {
+		dl.Status = StatusFailed
+		return dl
+	}
+	dl.Status = StatusPending
+	dl.NextAttempt = time.Now().Add(Backoff(d.cfg.MinBackoff, d.cfg.MaxBackoff, dl.Attempts))
+
+	return dl
+}
+
+// post sends the payload of the delivery to the endpoint.
+func (d *Dispatcher) post(ctx context.Context, dl *Delivery, ep Endpoint) error {
+	if ep.URL == "" {
+		return errors.New("endpoint not found")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(dl.Payload))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(ep.Secret, time.Now(), dl.Payload))
+	req.Header.Set(HeaderEvent, dl.EventType)
+	req.Header.Set(HeaderEventID, dl.EventID)
+	req.Header.Set(HeaderDelivery, dl.ID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "sending request")
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection is reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	dl.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+// refusePrivate fails the dialing of loopback, link-local, private and
+// unspecified addresses.
+func refusePrivate(network string, address string, c syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return errors.Wrap(err, "parsing address")
+	}
+
+	ip := ap.Addr().Unmap()
+	switch {
+	case ip.IsLoopback(), ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast(),
+		ip.IsPrivate(), ip.IsUnspecified(), ip.IsMulticast():
+		return fmt.Errorf("endpoint address %s refused", ip)
+	}
+
+	return nil
+}
+
+// Backoff returns the delay after the failed attempt, doubling from the
+// shortest delay with each attempt up to the longest.
+func Backoff(shortest, longest time.Duration, attempts int) time.Duration {
+	delay := shortest
+	for i := 1; i < attempts && delay < longest; i++ {
+		delay *= 2
+	}
+	if delay > longest {
+		return longest
+	}
+	return delay
+}
+
+// Sign returns the signature header of a request with the body sent at the
+// time. Receivers recompute the HMAC-SHA256 of "<timestamp>.<body>" with the
+// secret of the endpoint and compare it to v1, rejecting old timestamps to
+// prevent replays.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
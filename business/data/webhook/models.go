@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"slices"
+	"time"
+)
+
+// Set of delivery statuses.
+const (
+	StatusPending   = "pending"   // Waiting for its next attempt.
+	StatusDelivered = "delivered" // Accepted by the endpoint.
+	StatusFailed    = "failed"    // Dead letter, every attempt failed.
+)
+
+// Endpoint represents a URL events are delivered to.
+type Endpoint struct {
+	ID          string    `db:"endpoint_id" json:"id"`            // Unique identifier.
+	URL         string    `db:"url" json:"url"`                   // Where events are posted to.
+	Events      []string  `db:"events" json:"events"`             // Types of the events delivered, all if empty.
+	Secret      string    `db:"secret" json:"secret,omitempty"`   // Key of the signatures, only returned on registration.
+	DateCreated time.Time `db:"date_created" json:"date_created"` // When the endpoint was registered.
+}
+
+// Accepts reports whether events of the type are delivered to the endpoint.
+func (e Endpoint) Accepts(typ string) bool {
+	return len(e.Events) == 0 || slices.Contains(e.Events, typ)
+}
+
+// NewEndpoint is what we require from admins when registering an Endpoint. A
+// secret is generated when none is provided.
+type NewEndpoint struct {
+	URL    string   `json:"url" validate:"required,http_url"`
+	Events []string `json:"events" validate:"dive,oneof=sale.created product.updated product.deleted"`
+	Secret string   `json:"secret" validate:"omitempty,min=16"`
+}
+
+// Delivery represents the delivery of an event to an endpoint.
+type Delivery struct {
+	ID          string    `db:"delivery_id" json:"id"`            // Unique identifier.
+	EndpointID  string    `db:"endpoint_id" json:"endpoint_id"`   // Endpoint the event is delivered to.
+	EventID     string    `db:"event_id" json:"event_id"`         // Event delivered.
+	EventType   string    `db:"event_type" json:"event_type"`     // Type of the event delivered.
+	Payload     []byte    `db:"payload" json:"-"`                 // Body of the requests.
+	Status      string    `db:"status" json:"status"`             // One of pending, delivered or failed.
+	Attempts    int       `db:"attempts" json:"attempts"`         // Number of requests made.
+	StatusCode  int       `db:"status_code" json:"status_code"`   // Status code of the last response, 0 if there was none.
+	LastError   string    `db:"last_error" json:"last_error"`     // Why the last attempt failed.
+	NextAttempt time.Time `db:"next_attempt" json:"next_attempt"` // When the delivery is attempted next.
+	DateCreated time.Time `db:"date_created" json:"date_created"` // When the event was queued for delivery.
+	DateUpdated time.Time `db:"date_updated" json:"date_updated"` // When the delivery was last attempted or replayed.
+}
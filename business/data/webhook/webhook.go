@@ -0,0 +1,412 @@
+// Package webhook contains the registration of webhook endpoints and the
+// delivery of the events recorded in the outbox to them.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/tullo/service/business/data"
+	"github.com/tullo/service/business/data/outbox"
+	"github.com/tullo/service/business/validate"
+	"github.com/tullo/service/foundation/database"
+	"go.opentelemetry.io/otel"
+)
+
+const name = "webhook"
+
+func init() {
+	messages := map[string]string{
+		"en": "{0} must be an http or https URL",
+		"de": "{0} muss eine http- oder https-URL sein",
+		"da": "{0} skal være en http- eller https-URL",
+		"es": "{0} debe ser una URL http o https",
+		"fr": "{0} doit être une URL http ou https",
+	}
+	if err := validate.RegisterTranslation("http_url", messages); err != nil {
+		panic(err)
+	}
+}
+
+// Store manages the set of API's for webhook access.
+type Store struct {
+	log *slog.Logger
+	db  *database.DB
+}
+
+// NewStore constructs a Store for api access.
+func NewStore(log *slog.Logger, db *database.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// CreateEndpoint registers an endpoint. The returned endpoint holds the
+// secret of its signatures, it's not returned by any other method.
+func (s Store) CreateEndpoint(ctx context.Context, traceID string, ne NewEndpoint, now time.Time) (Endpoint, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.webhook.createendpoint")
+	defer span.End()
+
+	if err := validate.Check(ne); err != nil {
+		return Endpoint{}, errors.Wrap(err, "validating data")
+	}
+
+	secret := ne.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return Endpoint{}, errors.Wrap(err, "generating secret")
+		}
+		secret = "whsec_" + hex.EncodeToString(b)
+	}
+
+	ep := Endpoint{
+		ID:          uuid.New().String(),
+		URL:         ne.URL,
+		Events:      ne.Events,
+		Secret:      secret,
+		DateCreated: now.UTC(),
+	}
+	if ep.Events == nil {
+		ep.Events = []string{}
+	}
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return Endpoint{}, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	INSERT INTO webhook_endpoints
+		(endpoint_id, url, events, secret, date_created)
+	VALUES
+		($1, $2, $3, $4, $5)`
+
+	if _, err = conn.Exec(ctx, q, ep.ID, ep.URL, ep.Events, ep.Secret, ep.DateCreated); err != nil {
+		return Endpoint{}, errors.Wrap(err, "inserting endpoint")
+	}
+
+	return ep, nil
+}
+
+// QueryEndpoints retrieves the registered endpoints without their secrets.
+func (s Store) QueryEndpoints(ctx context.Context, traceID string) ([]Endpoint, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.webhook.queryendpoints")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	SELECT
+		endpoint_id, url, events, date_created
+	FROM
+		webhook_endpoints
+	ORDER BY
+		date_created, endpoint_id`
+
+	endpoints := []Endpoint{}
+	if err := pgxscan.Select(ctx, conn, &endpoints, q); err != nil {
+		return nil, errors.Wrap(err, "selecting endpoints")
+	}
+
+	return endpoints, nil
+}
+
+// DeleteEndpoint removes an endpoint together with its deliveries.
+func (s Store) DeleteEndpoint(ctx context.Context, traceID string, endpointID string) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.webhook.deleteendpoint")
+	defer span.End()
+
+	if _, err := uuid.Parse(endpointID); err != nil {
+		return data.ErrInvalidID
+	}
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	DELETE FROM
+		webhook_endpoints
+	WHERE
+		endpoint_id = $1`
+
+	if _, err := conn.Exec(ctx, q, endpointID); err != nil {
+		return errors.Wrapf(err, "deleting endpoint %s", endpointID)
+	}
+
+	return nil
+}
+
+// QueryDeliveries retrieves a page of the deliveries with the status, all
+// deliveries if the status is empty, the most recent first.
+func (s Store) QueryDeliveries(ctx context.Context, traceID string, status string, pageNumber int, rowsPerPage int) ([]Delivery, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.webhook.querydeliveries")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	SELECT
+		*
+	FROM
+		webhook_deliveries
+	WHERE
+		$1 = '' OR status = $1
+	ORDER BY
+		date_created DESC, delivery_id
+	OFFSET $2 ROWS FETCH NEXT $3 ROWS ONLY`
+
+	offset := (pageNumber - 1) * rowsPerPage
+
+	deliveries := make([]Delivery, 0, rowsPerPage)
+	if err := pgxscan.Select(ctx, conn, &deliveries, q, status, offset, rowsPerPage); err != nil {
+		return nil, errors.Wrap(err, "selecting deliveries")
+	}
+
+	return deliveries, nil
+}
+
+// CountDeliveries returns the number of deliveries with the status, of all
+// deliveries if the status is empty.
+func (s Store) CountDeliveries(ctx context.Context, traceID string, status string) (int, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.webhook.countdeliveries")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `SELECT count(*) FROM webhook_deliveries WHERE $1 = '' OR status = $1`
+
+	var count int
+	if err := conn.QueryRow(ctx, q, status).Scan(&count); err != nil {
+		return 0, errors.Wrap(err, "counting deliveries")
+	}
+
+	return count, nil
+}
+
+// Replay queues a failed delivery to be attempted again right away with a
+// fresh number of attempts.
+func (s Store) Replay(ctx context.Context, traceID string, deliveryID string, now time.Time) (Delivery, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.webhook.replay")
+	defer span.End()
+
+	if _, err := uuid.Parse(deliveryID); err != nil {
+		return Delivery{}, data.ErrInvalidID
+	}
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return Delivery{}, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const qs = `SELECT status FROM webhook_deliveries WHERE delivery_id = $1`
+
+	var status string
+	if err := pgxscan.Get(ctx, conn, &status, qs, deliveryID); err != nil {
+		if pgxscan.NotFound(err) {
+			return Delivery{}, data.ErrNotFound
+		}
+		return Delivery{}, errors.Wrapf(err, "selecting delivery %q", deliveryID)
+	}
+	if status != StatusFailed {
+		return Delivery{}, data.ErrDeliveryNotFailed
+	}
+
+	const q = `
+	UPDATE
+		webhook_deliveries
+	SET
+		status = $2,
+		attempts = 0,
+		next_attempt = $3,
+		date_updated = $3
+	WHERE
+		delivery_id = $1 AND status = $4
+	RETURNING
+		*`
+
+	var d Delivery
+	if err := pgxscan.Get(ctx, conn, &d, q, deliveryID, StatusPending, now.UTC(), StatusFailed); err != nil {
+
+		// The delivery was replayed concurrently.
+		if pgxscan.NotFound(err) {
+			return Delivery{}, data.ErrDeliveryNotFailed
+		}
+		return Delivery{}, errors.Wrapf(err, "replaying delivery %q", deliveryID)
+	}
+
+	return d, nil
+}
+
+// Fanout takes up to limit events from the outbox and queues their delivery
+// to every endpoint accepting them. It returns the number of events taken.
+func (s Store) Fanout(ctx context.Context, traceID string, limit int, now time.Time) (int, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.webhook.fanout")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	events, err := outbox.Take(ctx, tx, limit)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	const qe = `SELECT endpoint_id, events FROM webhook_endpoints`
+
+	var endpoints []Endpoint
+	if err := pgxscan.Select(ctx, tx, &endpoints, qe); err != nil {
+		return 0, errors.Wrap(err, "selecting endpoints")
+	}
+
+	const q = `
+	INSERT INTO webhook_deliveries
+		(delivery_id, endpoint_id, event_id, event_type, payload, status, attempts, status_code, last_error, next_attempt, date_created, date_updated)
+	VALUES
+		($1, $2, $3, $4, $5, $6, 0, 0, '', $7, $7, $7)`
+
+	for _, e := range events {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return 0, errors.Wrapf(err, "encoding event %s", e.ID)
+		}
+		for _, ep := range endpoints {
+			if !ep.Accepts(e.Type) {
+				continue
+			}
+			if _, err := tx.Exec(ctx, q, uuid.New().String(), ep.ID, e.ID, e.Type, payload, StatusPending, now.UTC()); err != nil {
+				return 0, errors.Wrapf(err, "inserting delivery of event %s", e.ID)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, errors.Wrap(err, "commit transaction")
+	}
+
+	return len(events), nil
+}
+
+// Claim leases up to limit of the pending deliveries which are due, together
+// with their endpoints. The deliveries aren't claimed by others until the
+// lease ends, the outcome of the attempt must be recorded before then.
+func (s Store) Claim(ctx context.Context, traceID string, limit int, lease time.Duration, now time.Time) ([]Delivery, map[string]Endpoint, error) {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.webhook.claim")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	UPDATE
+		webhook_deliveries
+	SET
+		next_attempt = $3
+	WHERE
+		delivery_id IN (
+			SELECT delivery_id FROM webhook_deliveries
+			WHERE status = $1 AND next_attempt <= $2
+			ORDER BY next_attempt LIMIT $4
+		)
+		AND status = $1 AND next_attempt <= $2
+	RETURNING
+		*`
+
+	var deliveries []Delivery
+	if err := pgxscan.Select(ctx, conn, &deliveries, q, StatusPending, now.UTC(), now.Add(lease).UTC(), limit); err != nil {
+		return nil, nil, errors.Wrap(err, "claiming deliveries")
+	}
+	if len(deliveries) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]string, len(deliveries))
+	for i, d := range deliveries {
+		ids[i] = d.EndpointID
+	}
+
+	const qe = `SELECT * FROM webhook_endpoints WHERE endpoint_id = ANY($1::UUID[])`
+
+	var endpoints []Endpoint
+	if err := pgxscan.Select(ctx, conn, &endpoints, qe, ids); err != nil {
+		return nil, nil, errors.Wrap(err, "selecting endpoints")
+	}
+
+	byID := make(map[string]Endpoint, len(endpoints))
+	for _, ep := range endpoints {
+		byID[ep.ID] = ep
+	}
+
+	return deliveries, byID, nil
+}
+
+// Record stores the outcome of an attempt to deliver an event.
+func (s Store) Record(ctx context.Context, traceID string, d Delivery, now time.Time) error {
+	ctx, span := otel.Tracer(name).Start(ctx, "business.data.webhook.record")
+	defer span.End()
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "acquire db connection")
+	}
+	defer conn.Release()
+
+	const q = `
+	UPDATE
+		webhook_deliveries
+	SET
+		status = $2,
+		attempts = $3,
+		status_code = $4,
+		last_error = $5,
+		next_attempt = $6,
+		date_updated = $7
+	WHERE
+		delivery_id = $1`
+
+	if _, err := conn.Exec(ctx, q, d.ID, d.Status, d.Attempts, d.StatusCode, d.LastError, d.NextAttempt.UTC(), now.UTC()); err != nil {
+		return errors.Wrapf(err, "recording delivery %s", d.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,294 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/pkg/errors"
+	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/data"
+	"github.com/tullo/service/business/data/outbox"
+	"github.com/tullo/service/business/data/product"
+	"github.com/tullo/service/business/data/tests"
+	"github.com/tullo/service/business/data/webhook"
+)
+
+func TestSign(t *testing.T) {
+	t.Log("Given the need to sign the deliveries of events.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen signing a body.", testID)
+		{
+			now := time.Unix(1546300800, 0)
+
+			// Computed with: printf '1546300800.{"a":1}' | openssl dgst -sha256 -hmac secret
+			const want = "t=1546300800,v1=8a5df84bd5c491f61e8ba0ae07826efc0b17b3b18a5907bf1ff68cab3f1eb005"
+
+			got := webhook.Sign("secret", now, []byte(`{"a":1}`))
+			if got != want {
+				t.Fatalf("\t%s\tTest %d:\tShould get the timestamp and HMAC-SHA256 : got %s want %s.", tests.Failed, testID, got, want)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the timestamp and HMAC-SHA256.", tests.Success, testID)
+
+			if got == webhook.Sign("other", now, []byte(`{"a":1}`)) || got == webhook.Sign("secret", now, []byte(`{"a":2}`)) {
+				t.Fatalf("\t%s\tTest %d:\tShould depend on the secret and body.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould depend on the secret and body.", tests.Success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen backing off after failed attempts.", testID)
+		{
+			for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 40: 10 * time.Second} {
+				if got := webhook.Backoff(time.Second, 10*time.Second, attempts); got != want {
+					t.Fatalf("\t%s\tTest %d:\tShould double the delay up to the longest after %d attempts : got %v want %v.", tests.Failed, testID, attempts, got, want)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould double the delay up to the longest.", tests.Success, testID)
+		}
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	var redirected bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/other", http.StatusFound)
+	})
+	mux.HandleFunc("/other", func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	t.Log("Given the need to restrict where events are delivered to.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen registering an endpoint which isn't an http URL.", testID)
+		{
+			s := webhook.NewStore(slog.New(slog.DiscardHandler), nil)
+
+			for _, url := range []string{"ftp://example.com/hook", "file:///etc/passwd", "example.com/hook"} {
+				if _, err := s.CreateEndpoint(context.Background(), "", webhook.NewEndpoint{URL: url}, time.Now()); err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT be able to register %s.", tests.Failed, testID, url)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to register the endpoint.", tests.Success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen posting to a loopback address.", testID)
+		{
+			client := webhook.NewClient(webhook.Config{Timeout: 5 * time.Second})
+			if resp, err := client.Post(srv.URL+"/other", "application/json", nil); err == nil {
+				resp.Body.Close()
+				t.Fatalf("\t%s\tTest %d:\tShould refuse the address by default.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould refuse the address by default.", tests.Success, testID)
+
+			client = webhook.NewClient(webhook.Config{Timeout: 5 * time.Second, AllowPrivate: true})
+			resp, err := client.Post(srv.URL+"/other", "application/json", nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould post when private addresses are allowed : %s.", tests.Failed, testID, err)
+			}
+			resp.Body.Close()
+			t.Logf("\t%s\tTest %d:\tShould post when private addresses are allowed.", tests.Success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen the endpoint redirects.", testID)
+		{
+			redirected = false
+
+			client := webhook.NewClient(webhook.Config{Timeout: 5 * time.Second, AllowPrivate: true})
+			resp, err := client.Post(srv.URL+"/hook", "application/json", nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to post : %s.", tests.Failed, testID, err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusFound || redirected {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT follow the redirect : got %d.", tests.Failed, testID, resp.StatusCode)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT follow the redirect.", tests.Success, testID)
+		}
+
+		testID = 3
+		t.Logf("\tTest %d:\tWhen the dispatcher is misconfigured.", testID)
+		{
+			valid := webhook.Config{Interval: time.Second, Timeout: time.Second, MaxAttempts: 1, MinBackoff: time.Second, MaxBackoff: time.Minute}
+			log := slog.New(slog.DiscardHandler)
+
+			if _, err := webhook.NewDispatcher(log, nil, valid); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct a dispatcher : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to construct a dispatcher.", tests.Success, testID)
+
+			for name, change := range map[string]func(*webhook.Config){
+				"no interval":                 func(c *webhook.Config) { c.Interval = 0 },
+				"no timeout":                  func(c *webhook.Config) { c.Timeout = 0 },
+				"no attempts":                 func(c *webhook.Config) { c.MaxAttempts = 0 },
+				"a negative min backoff":      func(c *webhook.Config) { c.MinBackoff = -time.Second },
+				"a max backoff below the min": func(c *webhook.Config) { c.MaxBackoff = time.Millisecond },
+			} {
+				cfg := valid
+				change(&cfg)
+				if _, err := webhook.NewDispatcher(log, nil, cfg); err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT be able to construct a dispatcher with %s.", tests.Failed, testID, name)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to construct a dispatcher with invalid settings.", tests.Success, testID)
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
+	defer cancel()
+
+	log, db, teardown := tests.NewUnit(t, ctx)
+	t.Cleanup(teardown)
+
+	s := webhook.NewStore(log, db)
+	d, err := webhook.NewDispatcher(log, db, webhook.Config{
+		Interval:    time.Second,
+		Timeout:     5 * time.Second,
+		MaxAttempts: 1,
+		MinBackoff:  time.Second,
+		MaxBackoff:  time.Minute,
+
+		// The receivers below listen on the loopback interface.
+		AllowPrivate: true,
+	})
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to construct a dispatcher : %s.", tests.Failed, err)
+	}
+
+	// The receiver records the requests it accepted.
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, b)
+		mu.Unlock()
+	}))
+	t.Cleanup(ok.Close)
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(broken.Close)
+
+	t.Log("Given the need to deliver events to webhook endpoints.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a product is updated.", testID)
+		{
+			now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+			traceID := "00000000-0000-0000-0000-000000000000"
+
+			ep, err := s.CreateEndpoint(ctx, traceID, webhook.NewEndpoint{URL: ok.URL, Events: []string{outbox.TypeProductUpdated}}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to register an endpoint : %s.", tests.Failed, testID, err)
+			}
+			if ep.Secret == "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get a generated secret.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to register an endpoint with a generated secret.", tests.Success, testID)
+
+			if _, err := s.CreateEndpoint(ctx, traceID, webhook.NewEndpoint{URL: broken.URL}, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to register a second endpoint : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to register a second endpoint.", tests.Success, testID)
+
+			claims := auth.Claims{
+				StandardClaims: jwt.StandardClaims{
+					Subject:   tests.AdminID,
+					ExpiresAt: jwt.At(now.Add(time.Hour)),
+					IssuedAt:  jwt.At(now),
+				},
+				Roles: []string{auth.RoleAdmin},
+			}
+			p := product.NewStore(log, db, nil)
+			prd, err := p.Create(ctx, traceID, claims, product.NewProduct{Name: "Comic Books", Cost: 10, Quantity: 55}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a product : %s.", tests.Failed, testID, err)
+			}
+			if err := p.Update(ctx, traceID, claims, prd.ID, product.UpdateProduct{Quantity: tests.IntPointer(40)}, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to update the product : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to update the product.", tests.Success, testID)
+
+			if err := d.Dispatch(ctx); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to dispatch the event : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to dispatch the event.", tests.Success, testID)
+
+			if len(received) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould deliver the event once : got %d.", tests.Failed, testID, len(received))
+			}
+			r, body := received[0], bodies[0]
+			if r.Header.Get(webhook.HeaderEvent) != outbox.TypeProductUpdated {
+				t.Fatalf("\t%s\tTest %d:\tShould deliver the event type : got %q.", tests.Failed, testID, r.Header.Get(webhook.HeaderEvent))
+			}
+			t.Logf("\t%s\tTest %d:\tShould deliver the event once.", tests.Success, testID)
+
+			sig := r.Header.Get(webhook.HeaderSignature)
+			ts, _, _ := strings.Cut(strings.TrimPrefix(sig, "t="), ",")
+			unix, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil || sig != webhook.Sign(ep.Secret, time.Unix(unix, 0), body) {
+				t.Fatalf("\t%s\tTest %d:\tShould sign the body with the secret : got %q.", tests.Failed, testID, sig)
+			}
+			t.Logf("\t%s\tTest %d:\tShould sign the body with the secret.", tests.Success, testID)
+
+			var e outbox.Event
+			if err := json.Unmarshal(body, &e); err != nil || e.ProductID != prd.ID {
+				t.Fatalf("\t%s\tTest %d:\tShould post the event of the product : %s %s.", tests.Failed, testID, body, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould post the event of the product.", tests.Success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen an endpoint keeps failing.", testID)
+		{
+			now := time.Now()
+			traceID := "00000000-0000-0000-0000-000000000000"
+
+			failed, err := s.QueryDeliveries(ctx, traceID, webhook.StatusFailed, 1, 10)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to query failed deliveries : %s.", tests.Failed, testID, err)
+			}
+			if len(failed) != 1 || failed[0].StatusCode != http.StatusInternalServerError {
+				t.Fatalf("\t%s\tTest %d:\tShould dead letter the delivery after its last attempt : %+v.", tests.Failed, testID, failed)
+			}
+			t.Logf("\t%s\tTest %d:\tShould dead letter the delivery after its last attempt.", tests.Success, testID)
+
+			dl, err := s.Replay(ctx, traceID, failed[0].ID, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to replay the delivery : %s.", tests.Failed, testID, err)
+			}
+			if dl.Status != webhook.StatusPending || dl.Attempts != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould queue the delivery again : %+v.", tests.Failed, testID, dl)
+			}
+			t.Logf("\t%s\tTest %d:\tShould queue the delivery again.", tests.Success, testID)
+
+			if _, err := s.Replay(ctx, traceID, failed[0].ID, now); errors.Cause(err) != data.ErrDeliveryNotFailed {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to replay a pending delivery : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to replay a pending delivery.", tests.Success, testID)
+		}
+	}
+}
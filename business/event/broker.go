@@ -0,0 +1,187 @@
+package event
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tullo/service/business/data/outbox"
+)
+
+// subscriberBuffer is the number of events a subscriber may fall behind
+// before it is dropped.
+const subscriberBuffer = 64
+
+// Broker fans the published events out to the subscribers. It keeps the
+// most recent events in a bounded buffer so subscribers can resume the stream
+// after reconnecting.
+//
+// Event IDs are made of the epoch of the broker and a sequence number, so the
+// IDs handed out before a restart are recognized as unknown.
+type Broker struct {
+	epoch string
+
+	mu     sync.Mutex
+	seq    uint64
+	buf    []Event // Ring buffer of the most recent events.
+	start  int     // Index of the oldest event in buf.
+	count  int     // Number of events in buf.
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBroker constructs a Broker buffering up to size events.
+func NewBroker(size int) *Broker {
+	return &Broker{
+		epoch: strconv.FormatInt(time.Now().UnixNano(), 36),
+		buf:   make([]Event, max(size, 1)),
+		subs:  make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the events selected by its filter.
+type Subscription struct {
+	// Backlog holds the buffered events published after the last event seen
+	// by the subscriber.
+	Backlog []Event
+
+	// Lost reports the last event seen by the subscriber is no longer
+	// buffered, so the events published after it can't be replayed.
+	Lost bool
+
+	// C delivers the events published after the subscription was made. It
+	// is closed when the subscriber falls too far behind, when the
+	// subscription is canceled and when the broker is closed.
+	C <-chan Event
+
+	b      *Broker
+	c      chan Event
+	filter Filter
+}
+
+// Cancel stops the delivery of events to the subscription.
+func (s *Subscription) Cancel() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	s.b.drop(s)
+}
+
+// Publish assigns the event its ID and time, buffers it and delivers it to
+// the matching subscribers. It doesn't block on slow subscribers, they are
+// dropped instead and may resume from the buffer.
+func (b *Broker) Publish(e Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.ID = b.epoch + "-" + strconv.FormatUint(b.seq, 10)
+	e.Time = time.Now().UTC()
+
+	// Overwrite the oldest event once the buffer is full.
+	b.buf[(b.start+b.count)%len(b.buf)] = e
+	if b.count < len(b.buf) {
+		b.count++
+	} else {
+		b.start = (b.start + 1) % len(b.buf)
+	}
+
+	for s := range b.subs {
+		if !s.filter.Match(e) {
+			continue
+		}
+		select {
+		case s.c <- e:
+		default:
+			b.drop(s)
+		}
+	}
+
+	return e
+}
+
+// Notify publishes the event of a committed change. It's handed to the
+// stores as their outbox.Notify, so only changes which were made are
+// published.
+func (b *Broker) Notify(e outbox.Event) {
+	b.Publish(FromOutbox(e))
+}
+
+// Subscribe registers a subscriber for the events matching the filter.
+// Passing the ID of the last event seen resumes the stream after it; an
+// empty ID starts with the events published from now on.
+func (b *Broker) Subscribe(f Filter, lastEventID string) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := Subscription{
+		b:      b,
+		c:      make(chan Event, subscriberBuffer),
+		filter: f,
+	}
+	s.C = s.c
+
+	if lastEventID != "" {
+		s.Backlog, s.Lost = b.since(f, lastEventID)
+	}
+
+	if b.closed {
+		close(s.c)
+		return &s
+	}
+	b.subs[&s] = struct{}{}
+
+	return &s
+}
+
+// Close ends all subscriptions. Subscriptions made afterwards are closed
+// right away.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subs {
+		b.drop(s)
+	}
+}
+
+// since returns the buffered events matching the filter which were published
+// after the event with the ID. It reports whether that event is unknown or no
+// longer buffered. The caller must hold the lock.
+func (b *Broker) since(f Filter, id string) ([]Event, bool) {
+	epoch, n, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return nil, true
+	}
+	seq, err := strconv.ParseUint(n, 10, 64)
+	if err != nil || seq > b.seq {
+		return nil, true
+	}
+
+	// The sequence number of the oldest buffered event.
+	oldest := b.seq - uint64(b.count) + 1
+	if seq+1 < oldest {
+		return nil, true
+	}
+
+	var events []Event
+	for i := seq + 1 - oldest; i < uint64(b.count); i++ {
+		e := b.buf[(b.start+int(i))%len(b.buf)]
+		if f.Match(e) {
+			events = append(events, e)
+		}
+	}
+	return events, false
+}
+
+// drop removes a subscription and closes its channel. The caller must hold
+// the lock.
+func (b *Broker) drop(s *Subscription) {
+	if _, ok := b.subs[s]; !ok {
+		return
+	}
+	delete(b.subs, s)
+	close(s.c)
+}
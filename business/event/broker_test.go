@@ -0,0 +1,160 @@
+package event_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tullo/service/business/data/outbox"
+	"github.com/tullo/service/business/event"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// changed returns the event of a change of the product owned by the user.
+func changed(typ string, productID string, ownerID string) event.Event {
+	return event.Event{
+		Type:      typ,
+		ProductID: productID,
+		OwnerID:   ownerID,
+	}
+}
+
+// ids returns the product IDs of the events.
+func ids(events []event.Event) []string {
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, e.ProductID)
+	}
+	return ids
+}
+
+func TestBroker(t *testing.T) {
+	b := event.NewBroker(3)
+
+	t.Log("Given the need to stream events to subscribers.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen subscribing with a filter.", testID)
+		{
+			sub := b.Subscribe(event.Filter{OwnerID: "u1"}, "")
+			defer sub.Cancel()
+
+			b.Publish(changed(event.TypeProductUpdated, "p1", "u2"))
+			b.Publish(changed(event.TypeProductUpdated, "p2", "u1"))
+
+			e := <-sub.C
+			if e.ProductID != "p2" || e.Type != event.TypeProductUpdated {
+				t.Fatalf("\t%s\tTest %d:\tShould receive the matching event : %+v", failed, testID, e)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive the matching event.", success, testID)
+
+			select {
+			case e := <-sub.C:
+				t.Fatalf("\t%s\tTest %d:\tShould receive no other events : %+v", failed, testID, e)
+			default:
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive no other events.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen resuming after a buffered event.", testID)
+		{
+			first := b.Publish(changed(event.TypeProductDeleted, "p3", "u1"))
+			b.Publish(changed(event.TypeProductDeleted, "p4", "u1"))
+
+			sub := b.Subscribe(event.Filter{}, first.ID)
+			defer sub.Cancel()
+
+			if sub.Lost {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to resume.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to resume.", success, testID)
+
+			if diff := cmp.Diff([]string{"p4"}, ids(sub.Backlog)); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould replay the events published after it. Diff:\n%s", failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould replay the events published after it.", success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen resuming after an event no longer buffered.", testID)
+		{
+			first := b.Publish(changed(event.TypeProductDeleted, "p5", "u1"))
+			for _, id := range []string{"p6", "p7", "p8", "p9"} {
+				b.Publish(changed(event.TypeProductDeleted, id, "u1"))
+			}
+
+			for _, id := range []string{first.ID, "unknown-1", ""} {
+				sub := b.Subscribe(event.Filter{}, id)
+				sub.Cancel()
+
+				if exp := id != ""; sub.Lost != exp {
+					t.Fatalf("\t%s\tTest %d:\tShould report lost events for %q : %v", failed, testID, id, sub.Lost)
+				}
+				if len(sub.Backlog) != 0 {
+					t.Fatalf("\t%s\tTest %d:\tShould replay no events for %q : %v", failed, testID, id, ids(sub.Backlog))
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould report lost events.", success, testID)
+		}
+
+		testID = 3
+		t.Logf("\tTest %d:\tWhen a subscriber falls behind.", testID)
+		{
+			sub := b.Subscribe(event.Filter{}, "")
+			for range 100 {
+				b.Publish(changed(event.TypeProductUpdated, "p10", "u1"))
+			}
+
+			var n int
+			for range sub.C {
+				n++
+			}
+			if n == 0 || n == 100 {
+				t.Fatalf("\t%s\tTest %d:\tShould drop the subscriber after a bounded number of events : %d", failed, testID, n)
+			}
+			t.Logf("\t%s\tTest %d:\tShould drop the subscriber after a bounded number of events.", success, testID)
+		}
+
+		testID = 4
+		t.Logf("\tTest %d:\tWhen a store commits a change.", testID)
+		{
+			sub := b.Subscribe(event.Filter{ProductID: "p11"}, "")
+			defer sub.Cancel()
+
+			b.Notify(outbox.Event{
+				ID:        "f3c9a8e4-3b7e-4c1e-9d8a-2f0e6c1b5a7d",
+				Type:      outbox.TypeSaleCreated,
+				ProductID: "p11",
+				OwnerID:   "u1",
+				Data:      json.RawMessage(`{"paid":50}`),
+			})
+
+			e := <-sub.C
+			if e.Type != event.TypeSaleCreated || e.OwnerID != "u1" || string(e.Data) != `{"paid":50}` {
+				t.Fatalf("\t%s\tTest %d:\tShould publish the event of the outbox : %+v", failed, testID, e)
+			}
+			if e.ID == "f3c9a8e4-3b7e-4c1e-9d8a-2f0e6c1b5a7d" || e.Time.IsZero() {
+				t.Fatalf("\t%s\tTest %d:\tShould assign the event its ID and time : %+v", failed, testID, e)
+			}
+			t.Logf("\t%s\tTest %d:\tShould publish the event of the outbox.", success, testID)
+		}
+
+		testID = 5
+		t.Logf("\tTest %d:\tWhen the broker is closed.", testID)
+		{
+			sub := b.Subscribe(event.Filter{}, "")
+			b.Close()
+
+			if _, ok := <-sub.C; ok {
+				t.Fatalf("\t%s\tTest %d:\tShould end the subscriptions.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould end the subscriptions.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Package event publishes the changes made to products and sales to the
+// subscribers of the event stream.
+package event
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/tullo/service/business/data/outbox"
+)
+
+// Set of event types.
+const (
+	TypeSaleCreated    = outbox.TypeSaleCreated
+	TypeProductUpdated = outbox.TypeProductUpdated
+	TypeProductDeleted = outbox.TypeProductDeleted
+
+	// TypeReset tells a subscriber resuming the stream that events were
+	// lost and the state it built from the events has to be refetched.
+	TypeReset = "stream.reset"
+)
+
+// Event represents a change to a product or one of its sales.
+type Event struct {
+	ID        string          `json:"id"`         // Unique identifier, assigned when published.
+	Type      string          `json:"type"`       // Kind of change.
+	ProductID string          `json:"product_id"` // ID of the product changed or sold.
+	OwnerID   string          `json:"owner_id"`   // ID of the user who created the product.
+	Time      time.Time       `json:"time"`       // When the change was published.
+	Data      json.RawMessage `json:"data"`       // The product or sale after the change.
+}
+
+// FromOutbox constructs the event of a change recorded in the outbox.
+func FromOutbox(e outbox.Event) Event {
+	return Event{
+		Type:      e.Type,
+		ProductID: e.ProductID,
+		OwnerID:   e.OwnerID,
+		Data:      e.Data,
+	}
+}
+
+// Filter selects the events of a subscription. Empty fields match any event.
+type Filter struct {
+	ProductID string
+	OwnerID   string
+}
+
+// Match reports whether the filter selects the event.
+func (f Filter) Match(e Event) bool {
+	if f.ProductID != "" && f.ProductID != e.ProductID {
+		return false
+	}
+	if f.OwnerID != "" && f.OwnerID != e.OwnerID {
+		return false
+	}
+	return true
+}
@@ -3,6 +3,7 @@ package mid
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -12,8 +13,9 @@ import (
 	"go.opentelemetry.io/otel"
 )
 
-// Authenticate validates a JWT from the `Authorization` header.
-func Authenticate(a *auth.Auth) web.Middleware {
+// Authenticate validates a JWT from the `Authorization` header. Rejected
+// tokens are logged at debug level.
+func Authenticate(log *slog.Logger, a *auth.Auth) web.Middleware {
 
 	// This is the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
@@ -28,14 +30,17 @@ func Authenticate(a *auth.Auth) web.Middleware {
 			parts := strings.Split(authStr, " ")
 			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
 				err := errors.New("expected authorization header format: Bearer <token>")
+				log.DebugContext(ctx, "authentication failed", "error", err)
 				return web.NewRequestError(err, http.StatusUnauthorized)
 			}
 
 			// Validate the token is signed by us.
 			claims, err := a.ValidateToken(parts[1])
 			if err != nil {
+				log.DebugContext(ctx, "authentication failed", "error", err)
 				return web.NewRequestError(err, http.StatusUnauthorized)
 			}
+			log.DebugContext(ctx, "authenticated", "subject", claims.Subject, "roles", claims.Roles)
 
 			// Add claims to the context so they can be retrieved later.
 			ctx = context.WithValue(ctx, auth.Key, claims)
@@ -0,0 +1,43 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/tullo/service/foundation/logger"
+	"github.com/tullo/service/foundation/web"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DebugLog switches on verbose logging for requests carrying an X-Debug-Log
+// header signed with the key, including the SQL statements they execute.
+// The header is ignored when the key is empty.
+func DebugLog(key string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if logger.VerifyDebug(key, r.Header.Get(logger.DebugHeader), time.Now()) {
+				ctx = logger.WithVerbose(ctx)
+			}
+
+			ctx, span := otel.Tracer(name).Start(ctx, "business.mid.debuglog")
+			defer span.End()
+
+			if logger.Verbose(ctx) {
+				span.SetAttributes(attribute.Bool("debug.verbose", true))
+			}
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -2,17 +2,28 @@ package mid
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
 
+	"github.com/pkg/errors"
+	"github.com/tullo/service/business/data"
+	"github.com/tullo/service/foundation/i18n"
+	"github.com/tullo/service/foundation/tracer"
+	"github.com/tullo/service/foundation/validate"
 	"github.com/tullo/service/foundation/web"
 	"go.opentelemetry.io/otel"
 )
 
 // Errors handles errors coming out of the call chain. It detects normal
 // application errors which are used to respond to the client in a uniform way.
-// Unexpected errors (status >= 500) are logged.
-func Errors(log *log.Logger) web.Middleware {
+// Unexpected errors (status >= 500) are logged and their trace is kept.
+//
+// Errors are reported as application/problem+json documents in the language
+// negotiated from the Accept-Language header. If legacy is set
+// the `{error, fields}` form is used instead, unless the client explicitly
+// accepts problem documents.
+func Errors(log *slog.Logger, legacy bool) web.Middleware {
 
 	// This is the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
@@ -34,13 +45,18 @@ func Errors(log *log.Logger) web.Middleware {
 			if err := handler(ctx, w, r); err != nil {
 
 				// Log the error.
-				log.Printf("%s: ERROR : %+v", v.TraceID, err)
+				log.ErrorContext(ctx, "request failed", "error", err)
 
 				// Respond to the error.
-				if err := web.RespondError(ctx, w, err); err != nil {
+				if err := respondError(ctx, w, r, err, legacy); err != nil {
 					return err
 				}
 
+				// Keep the trace of requests failing on the server side.
+				if v.StatusCode >= http.StatusInternalServerError {
+					tracer.Keep(ctx)
+				}
+
 				// If we receive the shutdown err we need to return it
 				// back to the base handler to shutdown the service.
 				if ok := web.IsShutdown(err); ok {
@@ -57,3 +73,39 @@ func Errors(log *log.Logger) web.Middleware {
 
 	return m
 }
+
+// respondError maps business errors to their problem type and responds in
+// the format negotiated with the client.
+func respondError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error, legacy bool) error {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+
+	var de *data.Error
+	var ve *validate.Errors
+	switch {
+	case errors.As(err, &de):
+		title, detail := de.Translate(lang)
+		err = &web.Error{
+			Err:    de,
+			Status: de.Status,
+			Type:   de.Type,
+			Title:  title,
+			Code:   de.Code,
+			Detail: detail,
+		}
+
+	// Values rejected by the stores are reported like request validation
+	// errors.
+	case errors.As(err, &ve):
+		err = &web.Error{
+			Err:    ve,
+			Status: http.StatusBadRequest,
+			Fields: ve.Fields(lang),
+			Detail: ve.Summary(lang),
+		}
+	}
+
+	if legacy && !strings.Contains(r.Header.Get("Accept"), web.ProblemContentType) {
+		return web.RespondError(ctx, w, err)
+	}
+	return web.RespondProblem(ctx, w, err, r.URL.Path)
+}
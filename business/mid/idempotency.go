@@ -0,0 +1,168 @@
+package mid
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/data/idempotency"
+	"github.com/tullo/service/foundation/web"
+	"go.opentelemetry.io/otel"
+)
+
+// maxIdempotencyKey is the longest Idempotency-Key value that is accepted.
+const maxIdempotencyKey = 255
+
+// Idempotency stores the first response of a request made with an
+// `Idempotency-Key` header and replays it to repeated requests using the same
+// key. Keys are scoped per authenticated user, method and path and expire
+// after the specified ttl. Reusing a key with a different request body is
+// rejected with a 422. Requests that fail are not stored so they can be
+// retried.
+//
+// Routes whose responses hold secrets must not use it, since the responses
+// are stored until the keys expire.
+//
+// Must be applied after Authenticate.
+func Idempotency(log *slog.Logger, store idempotency.Store, ttl time.Duration) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			ctx, span := otel.Tracer(name).Start(ctx, "business.mid.idempotency")
+			defer span.End()
+
+			// Requests without a key are processed as usual.
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				return handler(ctx, w, r)
+			}
+			if len(key) > maxIdempotencyKey {
+				err := errors.Errorf("idempotency key must not exceed %d characters", maxIdempotencyKey)
+				return web.NewRequestError(err, http.StatusBadRequest)
+			}
+
+			// If the context is missing this value, request the service
+			// to be shutdown gracefully.
+			v, ok := ctx.Value(web.KeyValues).(*web.Values)
+			if !ok {
+				return web.NewShutdownError("web value missing from context")
+			}
+
+			claims, ok := ctx.Value(auth.Key).(auth.Claims)
+			if !ok {
+				return web.NewShutdownError("claims missing from context")
+			}
+
+			// Hash the body so a repeated key can be matched against the
+			// original request. Restore the body for the next handler.
+			body, err := web.ReadBody(r)
+			if err != nil {
+				return err
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			sum := sha256.Sum256(body)
+			hash := hex.EncodeToString(sum[:])
+
+			// Scope the key by the path rather than the route pattern, so the
+			// same key used on /products/{A}/sales and /products/{B}/sales
+			// doesn't replay A's response to B.
+			route := r.Method + " " + r.URL.Path
+
+			stored, reserved, err := store.Reserve(ctx, v.TraceID, claims.Subject, route, key, hash, v.Now, ttl)
+			if err != nil {
+				return errors.Wrap(err, "reserving idempotency key")
+			}
+
+			if !reserved {
+				switch {
+				case stored.RequestHash != hash:
+					err := errors.New("idempotency key already used for a different request")
+					return web.NewRequestError(err, http.StatusUnprocessableEntity)
+				case !stored.Completed():
+					err := errors.New("a request with this idempotency key is still being processed")
+					return web.NewRequestError(err, http.StatusConflict)
+				}
+
+				// Replay the stored response.
+				v.StatusCode = stored.StatusCode
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.StatusCode)
+				if _, err := w.Write(stored.Body); err != nil {
+					return err
+				}
+				return nil
+			}
+
+			// Capture the response while it is written to the client.
+			rec := responseRecorder{ResponseWriter: w}
+			if err := handler(ctx, &rec, r); err != nil {
+
+				// Release the key so the client is able to retry.
+				if rerr := store.Release(ctx, v.TraceID, claims.Subject, route, key); rerr != nil {
+					return errors.Wrapf(err, "releasing idempotency key: %v", rerr)
+				}
+				return err
+			}
+
+			// A handler returning without writing gets the implicit 200 of
+			// net/http.
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			// The response has been sent already, so failing to store it is
+			// only logged. The key is released so retries are processed
+			// again instead of conflicting until it expires.
+			contentType := rec.Header().Get("Content-Type")
+			if err := store.Complete(ctx, v.TraceID, claims.Subject, route, key, status, contentType, rec.body.Bytes()); err != nil {
+				log.ErrorContext(ctx, "storing idempotent response", "error", err)
+				if err := store.Release(ctx, v.TraceID, claims.Subject, route, key); err != nil {
+					log.ErrorContext(ctx, "releasing idempotency key", "error", err)
+				}
+			}
+
+			return nil
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// responseRecorder captures the status code and body written to the
+// underlying http.ResponseWriter.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code before writing it.
+func (rr *responseRecorder) WriteHeader(statusCode int) {
+	rr.status = statusCode
+	rr.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the bytes before writing them.
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
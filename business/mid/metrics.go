@@ -5,25 +5,101 @@ import (
 	"expvar"
 	"net/http"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tullo/service/foundation/web"
 	"go.opentelemetry.io/otel"
 )
 
 // m contains the global program counters for the application.
 var m = struct {
-	gr  *expvar.Int
 	req *expvar.Int
 	err *expvar.Int
 }{
-	gr:  expvar.NewInt("goroutines"),
 	req: expvar.NewInt("requests"),
 	err: expvar.NewInt("errors"),
 }
 
-// Metrics updates program counters.
-func Metrics() web.Middleware {
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} {
+		return runtime.NumGoroutine()
+	}))
+}
+
+// requestMetrics holds the Prometheus metrics of the requests, labelled by
+// the route pattern and method, and the status once completed.
+type requestMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+// newRequestMetrics registers the request metrics with the registerer. The
+// metrics already registered are reused, so several apps can share them.
+func newRequestMetrics(reg prometheus.Registerer) (requestMetrics, error) {
+	rm := requestMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of requests completed.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to complete requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of requests being served.",
+		}, []string{"route", "method"}),
+	}
+
+	var err error
+	if rm.requests, err = register(reg, rm.requests); err != nil {
+		return requestMetrics{}, err
+	}
+	if rm.duration, err = register(reg, rm.duration); err != nil {
+		return requestMetrics{}, err
+	}
+	if rm.inFlight, err = register(reg, rm.inFlight); err != nil {
+		return requestMetrics{}, err
+	}
+
+	return rm, nil
+}
+
+// register registers the collector, returning the one registered before
+// when there is one.
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing, nil
+			}
+		}
+		return c, errors.Wrap(err, "registering request metrics")
+	}
+	return c, nil
+}
+
+// Metrics updates program counters. When a registerer is given, the number,
+// duration and in-flight count of the requests are also reported to
+// Prometheus by route pattern, method and status. It must run outside of
+// Errors to see the status of failed requests.
+func Metrics(reg prometheus.Registerer) web.Middleware {
+	var rm *requestMetrics
+	if reg != nil {
+		v, err := newRequestMetrics(reg)
+		if err != nil {
+			panic(err)
+		}
+		rm = &v
+	}
 
 	// This is the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
@@ -40,20 +116,42 @@ func Metrics() web.Middleware {
 				return handler(ctx, w, r)
 			}
 
+			// If the context is missing this value, request the service
+			// to be shutdown gracefully.
+			v, ok := ctx.Value(web.KeyValues).(*web.Values)
+			if !ok {
+				return web.NewShutdownError("web value missing from context")
+			}
+
+			// Label by the route pattern so the paths of resources don't
+			// each become a series.
+			route := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			if rm != nil {
+				inFlight := rm.inFlight.WithLabelValues(route, r.Method)
+				inFlight.Inc()
+				defer inFlight.Dec()
+			}
+
 			// Call the next handler.
+			start := time.Now()
 			err := handler(ctx, w, r)
 
 			// Increment the request counter.
 			m.req.Add(1)
 
-			// Update the count for the number of active goroutines every 100 requests.
-			if m.req.Value()%100 == 0 {
-				m.gr.Set(int64(runtime.NumGoroutine()))
+			// Increment the errors counter if the request failed.
+			if err != nil || v.StatusCode >= http.StatusBadRequest {
+				m.err.Add(1)
 			}
 
-			// Increment the errors counter if an error occurred on this request.
-			if err != nil {
-				m.err.Add(1)
+			if rm != nil {
+				status := strconv.Itoa(v.StatusCode)
+				rm.requests.WithLabelValues(route, r.Method, status).Inc()
+				rm.duration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
 			}
 
 			// Return the error so it can be handled further up the chain.
@@ -2,18 +2,20 @@ package mid
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"runtime/debug"
 
 	"github.com/pkg/errors"
+	"github.com/tullo/service/foundation/tracer"
 	"github.com/tullo/service/foundation/web"
 	"go.opentelemetry.io/otel"
 )
 
 // Panics recovers from panics and converts the panic to an error so it is
-// reported in Metrics and handled in Errors.
-func Panics(log *log.Logger) web.Middleware {
+// reported in Metrics and handled in Errors. The trace of the request is
+// kept.
+func Panics(log *slog.Logger) web.Middleware {
 
 	// This is the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
@@ -26,8 +28,7 @@ func Panics(log *log.Logger) web.Middleware {
 
 			// If the context is missing this value, request the service
 			// to be shutdown gracefully.
-			v, ok := ctx.Value(web.KeyValues).(*web.Values)
-			if !ok {
+			if _, ok := ctx.Value(web.KeyValues).(*web.Values); !ok {
 				return web.NewShutdownError("web value missing from context")
 			}
 
@@ -38,7 +39,8 @@ func Panics(log *log.Logger) web.Middleware {
 					err = errors.Errorf("panic: %v", r)
 
 					// Log the Go stack trace for this panic'd goroutine.
-					log.Printf("%s: PANIC:\n%s", v.TraceID, debug.Stack())
+					log.ErrorContext(ctx, "request panicked", "error", err, "stack", string(debug.Stack()))
+					tracer.Keep(ctx)
 				}
 			}()
 
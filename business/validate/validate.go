@@ -1,68 +1,90 @@
-// Package validate contains the support for validating models.
+// Package validate contains the support for validating models. It registers
+// the domain specific validation tags with the validation engine shared with
+// the web framework:
+//
+//	role   the value is one of the roles known to the auth package.
+//	cents  the value is a non-negative amount of money in cents.
+//
+// The uuid tag is provided by the engine.
 package validate
 
 import (
+	"errors"
+	"math"
 	"reflect"
-	"strings"
 
-	"github.com/go-playground/locales/en"
-	ut "github.com/go-playground/universal-translator"
-	"github.com/go-playground/validator/v10"
-	en_translations "github.com/go-playground/validator/v10/translations/en"
+	validator "github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/foundation/openapi"
+	engine "github.com/tullo/service/foundation/validate"
 )
 
-// validate holds the settings and caches for validating request struct values.
-var validate *validator.Validate
-
-// translator is a cache of locale and translation information.
-var translator ut.Translator
+// ErrInvalidID occurs when an ID is not in a valid form.
+var ErrInvalidID = errors.New("ID is not in its proper form")
+
+// MaxCents is the largest amount of money accepted in a single field. It
+// keeps computations on amounts like quantity * cost from overflowing.
+const MaxCents = math.MaxInt32
+
+// messages holds the error messages of the domain specific tags.
+var messages = map[string]map[string]string{
+	"role": {
+		"en": "{0} must only contain the roles " + auth.RoleAdmin + " or " + auth.RoleUser,
+		"de": "{0} darf nur die Rollen " + auth.RoleAdmin + " oder " + auth.RoleUser + " enthalten",
+		"da": "{0} må kun indeholde rollerne " + auth.RoleAdmin + " eller " + auth.RoleUser,
+		"es": "{0} solo puede contener los roles " + auth.RoleAdmin + " o " + auth.RoleUser,
+		"fr": "{0} ne doit contenir que les rôles " + auth.RoleAdmin + " ou " + auth.RoleUser,
+	},
+	"cents": {
+		"en": "{0} must be an amount in cents between 0 and 2147483647",
+		"de": "{0} muss ein Betrag in Cent zwischen 0 und 2147483647 sein",
+		"da": "{0} skal være et beløb i øre mellem 0 og 2147483647",
+		"es": "{0} debe ser un importe en céntimos entre 0 y 2147483647",
+		"fr": "{0} doit être un montant en centimes compris entre 0 et 2147483647",
+	},
+}
 
 func init() {
-
-	// Instantiate a validator.
-	validate = validator.New()
-
-	// Create a translator for english so the error messages are
-	// more human readable than technical.
-	translator, _ = ut.New(en.New(), en.New()).GetTranslator("en")
-
-	// Register the english error messages for use.
-	en_translations.RegisterDefaultTranslations(validate, translator)
-
-	// Use JSON tag names for errors instead of Go struct names.
-	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
-		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
-		if name == "-" {
-			return ""
+	tags := map[string]validator.Func{
+		"role":  isRole,
+		"cents": isCents,
+	}
+	for tag, fn := range tags {
+		if err := engine.RegisterValidation(tag, fn); err != nil {
+			panic(err)
 		}
-		return name
+		if err := engine.RegisterTranslation(tag, messages[tag]); err != nil {
+			panic(err)
+		}
+	}
+
+	// Document the constraints of the tags in the OpenAPI schemas.
+	openapi.RegisterTag("role", func(s *openapi.Schema, param string) {
+		s.Enum = []interface{}{auth.RoleAdmin, auth.RoleUser}
+	})
+	openapi.RegisterTag("cents", func(s *openapi.Schema, param string) {
+		minimum, maximum := 0.0, float64(MaxCents)
+		s.Minimum, s.Maximum = &minimum, &maximum
 	})
 }
 
-// Check validates the provided model against it's declared tags.
+// Check validates the provided model against it's declared tags. Failures
+// are returned as a *validate.Errors value of the validation engine.
 func Check(val interface{}) error {
-	if err := validate.Struct(val); err != nil {
-
-		// Use a type assertion to get the real error value.
-		verrors, ok := err.(validator.ValidationErrors)
-		if !ok {
-			return err
-		}
-
-		var fields FieldErrors
-		for _, verror := range verrors {
-			field := FieldError{
-				Field: verror.Field(),
-				Error: verror.Translate(translator),
-			}
-			fields = append(fields, field)
-		}
+	return engine.Check(val)
+}
 
-		return fields
-	}
+// RegisterStructValidation adds a rule validating the fields of the specified
+// types against each other. It must be called during initialization.
+func RegisterStructValidation(fn validator.StructLevelFunc, types ...interface{}) {
+	engine.RegisterStructValidation(fn, types...)
+}
 
-	return nil
+// RegisterTranslation adds the error messages of a tag keyed by language. It
+// must be called during initialization.
+func RegisterTranslation(tag string, messages map[string]string) error {
+	return engine.RegisterTranslation(tag, messages)
 }
 
 // GenerateID generate a unique id for entities.
@@ -77,3 +99,24 @@ func CheckID(id string) error {
 	}
 	return nil
 }
+
+// isRole reports whether the field holds one of the known roles.
+func isRole(fl validator.FieldLevel) bool {
+	switch fl.Field().String() {
+	case auth.RoleAdmin, auth.RoleUser:
+		return true
+	}
+	return false
+}
+
+// isCents reports whether the field holds a valid amount of money.
+func isCents(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int() >= 0 && field.Int() <= MaxCents
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field.Uint() <= MaxCents
+	}
+	return false
+}
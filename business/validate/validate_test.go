@@ -0,0 +1,85 @@
+package validate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tullo/service/business/auth"
+	"github.com/tullo/service/business/validate"
+	engine "github.com/tullo/service/foundation/validate"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+type account struct {
+	ID      string   `json:"id" validate:"required,uuid"`
+	Roles   []string `json:"roles" validate:"required,dive,role"`
+	Balance int      `json:"balance" validate:"cents"`
+}
+
+func TestCheck(t *testing.T) {
+	t.Log("Given the need to validate values against the domain rules.")
+	{
+		tests := []struct {
+			name string
+			val  account
+			lang string
+			exp  []engine.FieldError
+		}{
+			{
+				name: "valid",
+				val:  account{ID: "45b5fbd3-755f-4379-8f07-a58d4a30fa2f", Roles: []string{auth.RoleAdmin, auth.RoleUser}, Balance: 2500},
+			},
+			{
+				name: "invalid",
+				val:  account{ID: "12345", Roles: []string{"ROOT"}, Balance: -1},
+				lang: "en",
+				exp: []engine.FieldError{
+					{Field: "id", Error: "id must be a valid UUID"},
+					{Field: "roles[0]", Error: "roles[0] must only contain the roles ADMIN or USER"},
+					{Field: "balance", Error: "balance must be an amount in cents between 0 and 2147483647"},
+				},
+			},
+			{
+				name: "invalid",
+				val:  account{ID: "12345", Roles: []string{"ROOT"}, Balance: -1},
+				lang: "da",
+				exp: []engine.FieldError{
+					{Field: "id", Error: "id skal være et gyldigt UUID"},
+					{Field: "roles[0]", Error: "roles[0] må kun indeholde rollerne ADMIN eller USER"},
+					{Field: "balance", Error: "balance skal være et beløb i øre mellem 0 og 2147483647"},
+				},
+			},
+		}
+
+		for testID, tt := range tests {
+			t.Logf("\tTest %d:\tWhen checking a %s value in %q.", testID, tt.name, tt.lang)
+			{
+				err := validate.Check(tt.val)
+				if tt.exp == nil {
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to validate the value : %v", failed, testID, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould be able to validate the value.", success, testID)
+					continue
+				}
+
+				var verr *engine.Errors
+				if !errors.As(err, &verr) {
+					t.Fatalf("\t%s\tTest %d:\tShould get validation errors : %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get validation errors.", success, testID)
+
+				if diff := cmp.Diff(tt.exp, verr.Fields(tt.lang)); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get the expected fields. Diff:\n%s", failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get the expected fields.", success, testID)
+			}
+		}
+	}
+}
@@ -15,7 +15,10 @@ const SalesPrefix = "SALES"
 type CmdConfig struct {
 	conf.Version
 	Args conf.Args
-	DB   struct {
+	Log  struct {
+		Level string `conf:"default:warn"`
+	}
+	DB struct {
 		User       string `conf:"default:root"`
 		Password   string `conf:"mask"`
 		Host       string `conf:"default:0.0.0.0:26257"`
@@ -39,30 +42,55 @@ type AppConfig struct {
 	Web struct {
 		APIHost         string        `conf:"default:0.0.0.0:3000"`
 		DebugHost       string        `conf:"default:0.0.0.0:4000"`
+		GRPCHost        string        `conf:"default:0.0.0.0:5000"`
 		ReadTimeout     time.Duration `conf:"default:5s"`
 		WriteTimeout    time.Duration `conf:"default:5s"`
 		ShutdownTimeout time.Duration `conf:"default:5s"`
+		IdempotencyTTL  time.Duration `conf:"default:24h"`
+		LegacyErrors    bool          `conf:"default:false"`
+		MaxBodyBytes    int64         `conf:"default:1048576"`
+		EventBufferSize int           `conf:"default:1024"`
 		//CorsOrigin    string        `conf:"default:https://MY_DOMAIN.COM,env:CORS_ORIGIN"`
 	}
+	Log struct {
+		Level    string `conf:"default:info"`
+		DebugKey string `conf:"mask"`
+	}
 	DB struct {
-		User         string `conf:"default:root"`
-		Password     string `conf:"mask"`
-		Host         string `conf:"default:0.0.0.0:26257"`
-		Name         string `conf:"default:defaultdb"`
-		DisableTLS   bool   `conf:"default:false"`
-		MaxIdleConns int    `conf:"default:2"`
-		MaxOpenConns int    `conf:"default:0"`
+		User       string `conf:"default:root"`
+		Password   string `conf:"mask"`
+		Host       string `conf:"default:0.0.0.0:26257"`
+		Name       string `conf:"default:defaultdb"`
+		DisableTLS bool   `conf:"default:false"`
 		// If MaxIdleConns <= 0, no idle connections are retained.
+		MaxIdleConns int `conf:"default:2"`
 		// If MaxOpenConns <= 0, no limit on the number of open connections.
+		MaxOpenConns int `conf:"default:0"`
+		// If TraceStatements is false, the query spans leave out the statements.
+		TraceStatements bool `conf:"default:true"`
 	}
 	Auth struct {
 		KeysFolder string `conf:"default:/service/keys"`
 		Algorithm  string `conf:"default:RS256"`
 	}
-	Zipkin struct {
-		ReporterURI string  `conf:"default:http://zipkin:9411/api/v2/spans"`
+	Webhooks struct {
+		Interval     time.Duration `conf:"default:5s"`
+		Timeout      time.Duration `conf:"default:10s"`
+		MaxAttempts  int           `conf:"default:8"`
+		MinBackoff   time.Duration `conf:"default:30s"`
+		MaxBackoff   time.Duration `conf:"default:1h"`
+		AllowPrivate bool          `conf:"default:false"`
+	}
+	Trace struct {
+		// One of otlp-grpc, otlp-http, zipkin, stdout or none.
+		Exporter    string  `conf:"default:zipkin"`
+		Endpoint    string  `conf:"default:http://zipkin:9411/api/v2/spans"`
 		ServiceName string  `conf:"default:sales-api"`
 		Probability float64 `conf:"default:0.05"`
+		// Traces not sampled are kept when failing or slower than KeepLatency.
+		KeepLatency time.Duration `conf:"default:1s"`
+		// If MaxBuffered <= 0, only the sampled traces are kept.
+		MaxBuffered int `conf:"default:10000"`
 	}
 }
 
@@ -69,6 +69,7 @@ func TestVersionString(t *testing.T) {
 var cmdConfigHelp string = `Usage: config.test [options] [arguments]
 
 OPTIONS
+  --log-level/$TEST_LOG_LEVEL            <string>  (default: warn)
   --db-user/$TEST_DB_USER                <string>  (default: root)
   --db-password/$TEST_DB_PASSWORD        <string>  
   --db-host/$TEST_DB_HOST                <string>  (default: 0.0.0.0:26257)
@@ -83,24 +84,41 @@ OPTIONS
 var appConfigHelp string = `Usage: config.test [options] [arguments]
 
 OPTIONS
-  --web-api-host/$TEST_WEB_API_HOST                  <string>    (default: 0.0.0.0:3000)
-  --web-debug-host/$TEST_WEB_DEBUG_HOST              <string>    (default: 0.0.0.0:4000)
-  --web-read-timeout/$TEST_WEB_READ_TIMEOUT          <duration>  (default: 5s)
-  --web-write-timeout/$TEST_WEB_WRITE_TIMEOUT        <duration>  (default: 5s)
-  --web-shutdown-timeout/$TEST_WEB_SHUTDOWN_TIMEOUT  <duration>  (default: 5s)
-  --db-user/$TEST_DB_USER                            <string>    (default: root)
-  --db-password/$TEST_DB_PASSWORD                    <string>    
-  --db-host/$TEST_DB_HOST                            <string>    (default: 0.0.0.0:26257)
-  --db-name/$TEST_DB_NAME                            <string>    (default: defaultdb)
-  --db-disable-tls/$TEST_DB_DISABLE_TLS              <bool>      (default: false)
-  --db-max-idle-conns/$TEST_DB_MAX_IDLE_CONNS        <int>       (default: 2)
-  --db-max-open-conns/$TEST_DB_MAX_OPEN_CONNS        <int>       (default: 0)
-  --auth-keys-folder/$TEST_AUTH_KEYS_FOLDER          <string>    (default: /service/keys)
-  --auth-algorithm/$TEST_AUTH_ALGORITHM              <string>    (default: RS256)
-  --zipkin-reporter-uri/$TEST_ZIPKIN_REPORTER_URI    <string>    (default: http://zipkin:9411/api/v2/spans)
-  --zipkin-service-name/$TEST_ZIPKIN_SERVICE_NAME    <string>    (default: sales-api)
-  --zipkin-probability/$TEST_ZIPKIN_PROBABILITY      <float>     (default: 0.05)
-  --help/-h                                          
+  --web-api-host/$TEST_WEB_API_HOST                      <string>    (default: 0.0.0.0:3000)
+  --web-debug-host/$TEST_WEB_DEBUG_HOST                  <string>    (default: 0.0.0.0:4000)
+  --web-grpc-host/$TEST_WEB_GRPC_HOST                    <string>    (default: 0.0.0.0:5000)
+  --web-read-timeout/$TEST_WEB_READ_TIMEOUT              <duration>  (default: 5s)
+  --web-write-timeout/$TEST_WEB_WRITE_TIMEOUT            <duration>  (default: 5s)
+  --web-shutdown-timeout/$TEST_WEB_SHUTDOWN_TIMEOUT      <duration>  (default: 5s)
+  --web-idempotency-ttl/$TEST_WEB_IDEMPOTENCY_TTL        <duration>  (default: 24h)
+  --web-legacy-errors/$TEST_WEB_LEGACY_ERRORS            <bool>      (default: false)
+  --web-max-body-bytes/$TEST_WEB_MAX_BODY_BYTES          <int>       (default: 1048576)
+  --web-event-buffer-size/$TEST_WEB_EVENT_BUFFER_SIZE    <int>       (default: 1024)
+  --log-level/$TEST_LOG_LEVEL                            <string>    (default: info)
+  --log-debug-key/$TEST_LOG_DEBUG_KEY                    <string>    
+  --db-user/$TEST_DB_USER                                <string>    (default: root)
+  --db-password/$TEST_DB_PASSWORD                        <string>    
+  --db-host/$TEST_DB_HOST                                <string>    (default: 0.0.0.0:26257)
+  --db-name/$TEST_DB_NAME                                <string>    (default: defaultdb)
+  --db-disable-tls/$TEST_DB_DISABLE_TLS                  <bool>      (default: false)
+  --db-max-idle-conns/$TEST_DB_MAX_IDLE_CONNS            <int>       (default: 2)
+  --db-max-open-conns/$TEST_DB_MAX_OPEN_CONNS            <int>       (default: 0)
+  --db-trace-statements/$TEST_DB_TRACE_STATEMENTS        <bool>      (default: true)
+  --auth-keys-folder/$TEST_AUTH_KEYS_FOLDER              <string>    (default: /service/keys)
+  --auth-algorithm/$TEST_AUTH_ALGORITHM                  <string>    (default: RS256)
+  --webhooks-interval/$TEST_WEBHOOKS_INTERVAL            <duration>  (default: 5s)
+  --webhooks-timeout/$TEST_WEBHOOKS_TIMEOUT              <duration>  (default: 10s)
+  --webhooks-max-attempts/$TEST_WEBHOOKS_MAX_ATTEMPTS    <int>       (default: 8)
+  --webhooks-min-backoff/$TEST_WEBHOOKS_MIN_BACKOFF      <duration>  (default: 30s)
+  --webhooks-max-backoff/$TEST_WEBHOOKS_MAX_BACKOFF      <duration>  (default: 1h)
+  --webhooks-allow-private/$TEST_WEBHOOKS_ALLOW_PRIVATE  <bool>      (default: false)
+  --trace-exporter/$TEST_TRACE_EXPORTER                  <string>    (default: zipkin)
+  --trace-endpoint/$TEST_TRACE_ENDPOINT                  <string>    (default: http://zipkin:9411/api/v2/spans)
+  --trace-service-name/$TEST_TRACE_SERVICE_NAME          <string>    (default: sales-api)
+  --trace-probability/$TEST_TRACE_PROBABILITY            <float>     (default: 0.05)
+  --trace-keep-latency/$TEST_TRACE_KEEP_LATENCY          <duration>  (default: 1s)
+  --trace-max-buffered/$TEST_TRACE_MAX_BUFFERED          <int>       (default: 10000)
+  --help/-h                                              
   display this help message
   --version/-v  
   display version information
@@ -139,6 +157,7 @@ func TestParse(t *testing.T) {
 	var cmdConfig string = `--version=
 --description='testing cmd config'
 --args=[migrate]
+--log-level=warn
 --db-user='USER'
 --db-password=xxxxxx
 --db-host='HOST'
@@ -149,9 +168,16 @@ func TestParse(t *testing.T) {
 --description='testing app config'
 --web-api-host=0.0.0.0:80
 --web-debug-host=0.0.0.0:4040
+--web-grpc-host=0.0.0.0:5000
 --web-read-timeout=5s
 --web-write-timeout=5s
 --web-shutdown-timeout=5s
+--web-idempotency-ttl=24h0m0s
+--web-legacy-errors=false
+--web-max-body-bytes=1048576
+--web-event-buffer-size=1024
+--log-level=info
+--log-debug-key=xxxxxx
 --db-user=root
 --db-password=xxxxxx
 --db-host=0.0.0.0:26257
@@ -159,11 +185,21 @@ func TestParse(t *testing.T) {
 --db-disable-tls=false
 --db-max-idle-conns=2
 --db-max-open-conns=0
+--db-trace-statements=true
 --auth-keys-folder=/service/keys
 --auth-algorithm=RS256
---zipkin-reporter-uri=http://zipkin:9411/api/v2/spans
---zipkin-service-name=sales-api
---zipkin-probability=0.01`
+--webhooks-interval=5s
+--webhooks-timeout=10s
+--webhooks-max-attempts=8
+--webhooks-min-backoff=30s
+--webhooks-max-backoff=1h0m0s
+--webhooks-allow-private=false
+--trace-exporter=zipkin
+--trace-endpoint=http://zipkin:9411/api/v2/spans
+--trace-service-name=sales-api
+--trace-probability=0.01
+--trace-keep-latency=1s
+--trace-max-buffered=10000`
 
 	cmdConf := []string{
 		"--description='testing cmd config'", "--db-user='USER'",
@@ -172,7 +208,7 @@ func TestParse(t *testing.T) {
 	}
 	appConf := []string{
 		"--description='testing app config'", "--web-api-host=0.0.0.0:80",
-		"--web-debug-host=0.0.0.0:4040", "--zipkin-probability=0.01",
+		"--web-debug-host=0.0.0.0:4040", "--trace-probability=0.01",
 		"--db-password='V3ryS3cr3t!'",
 	}
 	type args struct {
@@ -4,15 +4,15 @@ package database
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"time"
 
-	zapadapter "github.com/jackc/pgx-zap"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/tracelog"
 	"go.opentelemetry.io/otel"
-	"go.uber.org/zap"
 )
 
 type DB struct {
@@ -28,11 +28,24 @@ type Config struct {
 	DisableTLS   bool
 	MaxIdleConns int
 	MaxOpenConns int
+
+	// Log receives the statements executed at debug level when set.
+	Log *slog.Logger
+
+	// TraceStatements adds the sanitized text of the statements to their
+	// spans.
+	TraceStatements bool
 }
 
 // Connect establishes a database connection based on the configuration.
 func Connect(ctx context.Context, cfg Config) (*DB, error) {
-	pool, err := pgxpool.New(ctx, ConnString(cfg))
+	conf, err := pgxpool.ParseConfig(ConnString(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("database config error: %w", err)
+	}
+	conf.ConnConfig.Tracer = newTracer(cfg.Log, cfg.TraceStatements)
+
+	pool, err := pgxpool.NewWithConfig(ctx, conf)
 	if err != nil {
 		return nil, fmt.Errorf("database connection error: %w", err)
 	}
@@ -41,39 +54,80 @@ func Connect(ctx context.Context, cfg Config) (*DB, error) {
 	return &db, nil
 }
 
-func traceLogConfig(pool *pgxpool.Pool) (*pgxpool.Config, error) {
-	logger, err := zap.NewDevelopmentConfig().Build()
+// ConnectWithURI establishes a database connection to the URI. The
+// statements executed are logged at debug level and added to their spans.
+func ConnectWithURI(ctx context.Context, log *slog.Logger, uri string) (*DB, error) {
+	conf, err := pgxpool.ParseConfig(uri)
 	if err != nil {
-		return nil, fmt.Errorf("zap logger error: %w", err)
+		return nil, fmt.Errorf("database config error: %w", err)
 	}
-	conf := pool.Config()
-	conf.ConnConfig.Tracer = &tracelog.TraceLog{
-		Logger:   zapadapter.NewLogger(logger),
-		LogLevel: tracelog.LogLevelDebug,
+	conf.ConnConfig.Tracer = newTracer(log, true)
+
+	pool, err := pgxpool.NewWithConfig(ctx, conf)
+	if err != nil {
+		return nil, fmt.Errorf("database connection error: %w", err)
 	}
 
-	return conf, nil
+	db := DB{pool}
 
+	return &db, nil
 }
-func ConnectWithURI(ctx context.Context, uri string) (*DB, error) {
-	pool, err := pgxpool.New(ctx, uri)
-	if err != nil {
-		return nil, fmt.Errorf("database connection error: %w", err)
+
+// newTracer constructs the tracer of the connections, which spans the
+// acquires, queries, batches and copies and logs the statements when the
+// logger is set.
+func newTracer(log *slog.Logger, statements bool) pgx.QueryTracer {
+	qt := queryTracer{statements: statements}
+	if log == nil {
+		return qt
 	}
 
-	conf, err := traceLogConfig(pool)
-	if err != nil {
-		return nil, fmt.Errorf("database config error: %w", err)
+	tl := tracelog.TraceLog{
+		Logger:   traceLogger{log},
+		LogLevel: tracelog.LogLevelDebug,
 	}
+	return multitracer.New(qt, &tl)
+}
 
-	pool, err = pgxpool.NewWithConfig(ctx, conf)
-	if err != nil {
-		return nil, fmt.Errorf("database connection error: %w", err)
+// traceLogger writes the messages of pgx to a structured logger.
+type traceLogger struct {
+	log *slog.Logger
+}
+
+// Log writes a message of pgx with its data at the matching level. The
+// statements of requests logged verbosely are written at any level, so only
+// their sanitized SQL is kept: the arguments bound to them hold values like
+// password hashes and secrets.
+func (l traceLogger) Log(ctx context.Context, level tracelog.LogLevel, msg string, data map[string]any) {
+	var lvl slog.Level
+	switch level {
+	case tracelog.LogLevelTrace, tracelog.LogLevelDebug:
+		lvl = slog.LevelDebug
+	case tracelog.LogLevelInfo:
+		lvl = slog.LevelInfo
+	case tracelog.LogLevelWarn:
+		lvl = slog.LevelWarn
+	default:
+		lvl = slog.LevelError
+	}
+	if !l.log.Enabled(ctx, lvl) {
+		return
 	}
 
-	db := DB{pool}
+	attrs := make([]slog.Attr, 0, len(data))
+	for k, v := range data {
+		switch k {
+		case "args":
+			continue
+		case "sql":
+			if sql, ok := v.(string); ok {
+				v = SanitizeSQL(sql)
+			}
+		}
+		attrs = append(attrs, slog.Any(k, v))
+	}
 
-	return &db, nil
+	l.log.LogAttrs(ctx, lvl, msg, attrs...)
 }
 
 // ConnString translates the config to a db connection string.
@@ -0,0 +1,43 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/tullo/service/foundation/database"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tt := []struct {
+		name string
+		sql  string
+		exp  string
+	}{
+		{"placeholders", "SELECT * FROM products WHERE product_id = $1", "SELECT * FROM products WHERE product_id = $1"},
+		{"whitespace", "\n\tSELECT\n\t\tname,  cost\n\tFROM products\n", "SELECT name, cost FROM products"},
+		{"strings", "SELECT * FROM users WHERE email = 'admin@example.com' AND name = 'O''Brien'", "SELECT * FROM users WHERE email = ? AND name = ?"},
+		{"numbers", "SELECT * FROM sales LIMIT 10 OFFSET 2.5", "SELECT * FROM sales LIMIT ? OFFSET ?"},
+		{"identifiers", "SELECT col_1, t2.x FROM t2", "SELECT col_1, t2.x FROM t2"},
+		{"escape strings", `SELECT * FROM users WHERE name = E'O\'Brien' OR name = e'it''s\\'`, "SELECT * FROM users WHERE name = ? OR name = ?"},
+		{"dollar quotes", "SELECT $$it's $1$$, $fn$a $$ b$fn$, $1", "SELECT ?, ?, $1"},
+		{"unterminated literals", "SELECT 'abc, $$def", "SELECT ?"},
+	}
+
+	t.Log("Given the need to keep values out of the traced statements.")
+	{
+		for testID, tc := range tt {
+			t.Logf("\tTest %d:\tWhen sanitizing %s.", testID, tc.name)
+			{
+				if got := database.SanitizeSQL(tc.sql); got != tc.exp {
+					t.Fatalf("\t%s\tTest %d:\tShould get %q : got %q", failed, testID, tc.exp, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get %q.", success, testID, tc.exp)
+			}
+		}
+	}
+}
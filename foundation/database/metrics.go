@@ -0,0 +1,92 @@
+package database
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reports the statistics of a connection pool to Prometheus.
+type poolCollector struct {
+	db *DB
+
+	acquiredConns           *prometheus.Desc
+	idleConns               *prometheus.Desc
+	constructingConns       *prometheus.Desc
+	totalConns              *prometheus.Desc
+	maxConns                *prometheus.Desc
+	acquires                *prometheus.Desc
+	acquireDuration         *prometheus.Desc
+	canceledAcquires        *prometheus.Desc
+	emptyAcquires           *prometheus.Desc
+	emptyAcquireWait        *prometheus.Desc
+	newConns                *prometheus.Desc
+	maxLifetimeDestroyConns *prometheus.Desc
+	maxIdleDestroyConns     *prometheus.Desc
+}
+
+// NewPoolCollector constructs a collector of the statistics of the
+// connection pool of the database, read on every scrape.
+func NewPoolCollector(db *DB) prometheus.Collector {
+	desc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("db", "pool", name), help, nil, nil)
+	}
+
+	return &poolCollector{
+		db:                      db,
+		acquiredConns:           desc("acquired_connections", "Number of connections currently acquired from the pool."),
+		idleConns:               desc("idle_connections", "Number of idle connections in the pool."),
+		constructingConns:       desc("constructing_connections", "Number of connections being established."),
+		totalConns:              desc("connections", "Total number of connections in the pool."),
+		maxConns:                desc("max_connections", "Maximum size of the pool."),
+		acquires:                desc("acquires_total", "Number of successful acquires from the pool."),
+		acquireDuration:         desc("acquire_duration_seconds_total", "Total time spent on successful acquires from the pool."),
+		canceledAcquires:        desc("canceled_acquires_total", "Number of acquires canceled by their context."),
+		emptyAcquires:           desc("empty_acquires_total", "Number of successful acquires that waited for a connection."),
+		emptyAcquireWait:        desc("empty_acquire_wait_seconds_total", "Total time spent waiting by the acquires that found the pool empty."),
+		newConns:                desc("new_connections_total", "Number of connections opened."),
+		maxLifetimeDestroyConns: desc("max_lifetime_closed_total", "Number of connections closed for exceeding their maximum lifetime."),
+		maxIdleDestroyConns:     desc("max_idle_closed_total", "Number of connections closed for exceeding their maximum idle time."),
+	}
+}
+
+// Describe sends the descriptors of the pool statistics.
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.constructingConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquires
+	ch <- c.acquireDuration
+	ch <- c.canceledAcquires
+	ch <- c.emptyAcquires
+	ch <- c.emptyAcquireWait
+	ch <- c.newConns
+	ch <- c.maxLifetimeDestroyConns
+	ch <- c.maxIdleDestroyConns
+}
+
+// Collect sends the current statistics of the pool.
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.db.Stat()
+
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+
+	gauge(c.acquiredConns, float64(s.AcquiredConns()))
+	gauge(c.idleConns, float64(s.IdleConns()))
+	gauge(c.constructingConns, float64(s.ConstructingConns()))
+	gauge(c.totalConns, float64(s.TotalConns()))
+	gauge(c.maxConns, float64(s.MaxConns()))
+	counter(c.acquires, float64(s.AcquireCount()))
+	counter(c.acquireDuration, s.AcquireDuration().Seconds())
+	counter(c.canceledAcquires, float64(s.CanceledAcquireCount()))
+	counter(c.emptyAcquires, float64(s.EmptyAcquireCount()))
+	counter(c.emptyAcquireWait, s.EmptyAcquireWaitTime().Seconds())
+	counter(c.newConns, float64(s.NewConnsCount()))
+	counter(c.maxLifetimeDestroyConns, float64(s.MaxLifetimeDestroyCount()))
+	counter(c.maxIdleDestroyConns, float64(s.MaxIdleDestroyCount()))
+}
@@ -0,0 +1,251 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes of the database spans not covered by the semantic conventions.
+const (
+	rowsAffectedKey = attribute.Key("db.rows_affected")
+	sqlStateKey     = attribute.Key("db.sqlstate")
+)
+
+// queryTracer traces the connections acquired from the pool and the
+// queries, batches and copies run on them as child spans of the calls.
+type queryTracer struct {
+	statements bool
+}
+
+// TraceAcquireStart starts the span of waiting for a connection.
+func (t queryTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	ctx, _ = t.start(ctx, "foundation.database.acquire")
+	return ctx
+}
+
+// TraceAcquireEnd ends the span of waiting for a connection.
+func (t queryTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	end(trace.SpanFromContext(ctx), data.Err)
+}
+
+// TraceQueryStart starts the span of a query.
+func (t queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, span := t.start(ctx, "foundation.database.query")
+	span.SetAttributes(semconv.DBOperationKey.String(operation(data.SQL)))
+	if t.statements {
+		span.SetAttributes(semconv.DBStatementKey.String(SanitizeSQL(data.SQL)))
+	}
+	return ctx
+}
+
+// TraceQueryEnd ends the span of a query with the rows it affected.
+func (t queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(rowsAffectedKey.Int64(data.CommandTag.RowsAffected()))
+	end(span, data.Err)
+}
+
+// TraceBatchStart starts the span of a batch.
+func (t queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, span := t.start(ctx, "foundation.database.batch")
+	span.SetAttributes(attribute.Int("db.batch.size", data.Batch.Len()))
+	return ctx
+}
+
+// TraceBatchQuery adds an event for a query of a batch to its span.
+func (t queryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	attrs := []attribute.KeyValue{
+		semconv.DBOperationKey.String(operation(data.SQL)),
+		rowsAffectedKey.Int64(data.CommandTag.RowsAffected()),
+	}
+	if t.statements {
+		attrs = append(attrs, semconv.DBStatementKey.String(SanitizeSQL(data.SQL)))
+	}
+	if code := sqlState(data.Err); code != "" {
+		attrs = append(attrs, sqlStateKey.String(code))
+	}
+	trace.SpanFromContext(ctx).AddEvent("query", trace.WithAttributes(attrs...))
+}
+
+// TraceBatchEnd ends the span of a batch.
+func (t queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	end(trace.SpanFromContext(ctx), data.Err)
+}
+
+// TraceCopyFromStart starts the span of a copy into a table.
+func (t queryTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	ctx, span := t.start(ctx, "foundation.database.copy")
+	span.SetAttributes(
+		semconv.DBOperationKey.String("COPY"),
+		semconv.DBSQLTableKey.String(data.TableName.Sanitize()),
+	)
+	return ctx
+}
+
+// TraceCopyFromEnd ends the span of a copy with the rows it copied.
+func (t queryTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(rowsAffectedKey.Int64(data.CommandTag.RowsAffected()))
+	end(span, data.Err)
+}
+
+// start starts a client span of the database.
+func (t queryTracer) start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer("database").Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemCockroachdb),
+	)
+}
+
+// end records the error, with its SQLSTATE code when it's from the
+// database, and ends the span.
+func end(span trace.Span, err error) {
+	if err != nil {
+		if code := sqlState(err); code != "" {
+			span.SetAttributes(sqlStateKey.String(code))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// sqlState returns the SQLSTATE code of an error of the database.
+func sqlState(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// operation returns the first keyword of a statement, like SELECT.
+func operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// SanitizeSQL returns the statement with its string and number literals
+// replaced by ? and its whitespace collapsed, so no values end up in the
+// traces. Placeholders like $1 and identifiers are kept.
+func SanitizeSQL(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+
+	rs := []rune(sql)
+	space := false
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+			continue
+
+		// Quoted literals, with '' escaping a quote.
+		case r == '\'':
+			i = quoteEnd(rs, i, false)
+			r = '?'
+
+		// Escape strings like E'it\'s', where a backslash escapes a quote
+		// as well.
+		case (r == 'E' || r == 'e') && i+1 < len(rs) && rs[i+1] == '\'':
+			i = quoteEnd(rs, i+1, true)
+			r = '?'
+
+		// Dollar-quoted literals like $$it's$$ or $fn$it's$fn$.
+		case r == '$' && dollarTag(rs, i) > 0:
+			tag := rs[i : i+dollarTag(rs, i)]
+			i += len(tag)
+			for i < len(rs) && !hasRunes(rs[i:], tag) {
+				i++
+			}
+			i += len(tag) - 1
+			r = '?'
+
+		// Numbers, since identifiers and placeholders are consumed whole
+		// below.
+		case unicode.IsDigit(r):
+			for i+1 < len(rs) && (unicode.IsDigit(rs[i+1]) || rs[i+1] == '.') {
+				i++
+			}
+			r = '?'
+
+		// Identifiers and placeholders are copied whole so their digits
+		// are kept.
+		case r == '$' || r == '_' || unicode.IsLetter(r):
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			b.WriteRune(r)
+			for i+1 < len(rs) && (rs[i+1] == '_' || unicode.IsLetter(rs[i+1]) || unicode.IsDigit(rs[i+1])) {
+				i++
+				b.WriteRune(rs[i])
+			}
+			continue
+		}
+
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// quoteEnd returns the index of the quote closing the literal opened at i.
+// The length of the statement is returned when it isn't closed.
+func quoteEnd(rs []rune, i int, backslash bool) int {
+	for i++; i < len(rs); i++ {
+		switch {
+		case backslash && rs[i] == '\\':
+			i++
+		case rs[i] == '\'':
+			if i+1 < len(rs) && rs[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return i
+}
+
+// dollarTag returns the length of the delimiter of a dollar-quoted literal
+// starting at i, like $$ or $fn$, or 0 when there's none. Placeholders like
+// $1 aren't delimiters since a tag can't start with a digit.
+func dollarTag(rs []rune, i int) int {
+	for j := i + 1; j < len(rs); j++ {
+		switch r := rs[j]; {
+		case r == '$':
+			return j - i + 1
+		case r == '_' || unicode.IsLetter(r):
+		case unicode.IsDigit(r) && j > i+1:
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+// hasRunes reports whether rs begins with prefix.
+func hasRunes(rs []rune, prefix []rune) bool {
+	return len(rs) >= len(prefix) && slices.Equal(rs[:len(prefix)], prefix)
+}
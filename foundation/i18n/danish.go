@@ -0,0 +1,75 @@
+package i18n
+
+import (
+	"reflect"
+
+	ut "github.com/go-playground/universal-translator"
+	validator "github.com/go-playground/validator/v10"
+)
+
+// danish holds the Danish error messages of the validation tags used by the
+// models. The validator doesn't ship Danish translations. Tags comparing
+// sizes have separate messages for strings, collections and numbers. Tags
+// without a message here fall back to English.
+var danish = map[string]struct {
+	str  string
+	coll string
+	num  string
+}{
+	"required": {num: "{0} er et obligatorisk felt"},
+	"email":    {num: "{0} skal være en gyldig e-mailadresse"},
+	"uuid":     {num: "{0} skal være et gyldigt UUID"},
+	"url":      {num: "{0} skal være en gyldig URL"},
+	"oneof":    {num: "{0} skal være en af [{1}]"},
+	"eqfield":  {num: "{0} skal være lig med {1}"},
+	"nefield":  {num: "{0} må ikke være lig med {1}"},
+	"len":      {str: "{0} skal være {1} tegn lang", coll: "{0} skal indeholde {1} elementer", num: "{0} skal være lig med {1}"},
+	"min":      {str: "{0} skal være mindst {1} tegn lang", coll: "{0} skal indeholde mindst {1} elementer", num: "{0} skal være {1} eller større"},
+	"max":      {str: "{0} må højst være {1} tegn lang", coll: "{0} må højst indeholde {1} elementer", num: "{0} skal være {1} eller mindre"},
+	"gte":      {str: "{0} skal være mindst {1} tegn lang", coll: "{0} skal indeholde mindst {1} elementer", num: "{0} skal være {1} eller større"},
+	"lte":      {str: "{0} må højst være {1} tegn lang", coll: "{0} må højst indeholde {1} elementer", num: "{0} skal være {1} eller mindre"},
+	"gt":       {str: "{0} skal være længere end {1} tegn", coll: "{0} skal indeholde mere end {1} elementer", num: "{0} skal være større end {1}"},
+	"lt":       {str: "{0} skal være kortere end {1} tegn", coll: "{0} skal indeholde færre end {1} elementer", num: "{0} skal være mindre end {1}"},
+}
+
+// registerDanish registers the Danish error messages with the validator.
+func registerDanish(v *validator.Validate, trans ut.Translator) error {
+	for tag, msg := range danish {
+		register := func(trans ut.Translator) error {
+			for suffix, text := range map[string]string{"-string": msg.str, "-items": msg.coll, "": msg.num} {
+				if text == "" {
+					continue
+				}
+				if err := trans.Add(tag+suffix, text, false); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		translate := func(trans ut.Translator, fe validator.FieldError) string {
+			key := tag
+			switch fe.Kind() {
+			case reflect.String:
+				if msg.str != "" {
+					key += "-string"
+				}
+			case reflect.Slice, reflect.Map, reflect.Array:
+				if msg.coll != "" {
+					key += "-items"
+				}
+			}
+
+			text, err := trans.T(key, fe.Field(), fe.Param())
+			if err != nil {
+				return fe.Error()
+			}
+			return text
+		}
+
+		if err := v.RegisterTranslation(tag, trans, register, translate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Package i18n provides support for translating messages into the language
+// requested by a client.
+package i18n
+
+import (
+	"github.com/go-playground/locales/da"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	ut "github.com/go-playground/universal-translator"
+	validator "github.com/go-playground/validator/v10"
+	de_translations "github.com/go-playground/validator/v10/translations/de"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	es_translations "github.com/go-playground/validator/v10/translations/es"
+	fr_translations "github.com/go-playground/validator/v10/translations/fr"
+	"github.com/pkg/errors"
+	"golang.org/x/text/language"
+)
+
+// Default is the language used when a client doesn't ask for one of the
+// supported languages.
+const Default = "en"
+
+// Languages lists the supported languages. The first one is the default.
+var Languages = []string{"en", "de", "da", "es", "fr"}
+
+// translator is a cache of locale and translation information.
+var translator = ut.New(en.New(), en.New(), de.New(), da.New(), es.New(), fr.New())
+
+// matcher selects the best supported language for an Accept-Language header.
+var matcher = language.NewMatcher([]language.Tag{
+	language.English,
+	language.German,
+	language.Danish,
+	language.Spanish,
+	language.French,
+})
+
+// registrations holds the functions registering the default validator error
+// messages per language.
+var registrations = map[string]func(*validator.Validate, ut.Translator) error{
+	"en": en_translations.RegisterDefaultTranslations,
+	"de": de_translations.RegisterDefaultTranslations,
+	"da": registerDanish,
+	"es": es_translations.RegisterDefaultTranslations,
+	"fr": fr_translations.RegisterDefaultTranslations,
+}
+
+// Negotiate returns the supported language that best matches the value of an
+// Accept-Language header. It returns the default language if the header is
+// empty, malformed or none of the languages are supported.
+func Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return Default
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return Default
+	}
+
+	return Languages[index]
+}
+
+// Translator returns the translator for the specified language. It returns
+// the translator of the default language if the language is not supported.
+func Translator(lang string) ut.Translator {
+	trans, found := translator.GetTranslator(lang)
+	if !found {
+		trans, _ = translator.GetTranslator(Default)
+	}
+	return trans
+}
+
+// RegisterValidator registers the error messages of the built in validation
+// tags for all supported languages.
+func RegisterValidator(v *validator.Validate) error {
+	for _, lang := range Languages {
+		if err := registrations[lang](v, Translator(lang)); err != nil {
+			return errors.Wrapf(err, "registering %s translations", lang)
+		}
+	}
+	return nil
+}
+
+// Add registers the text for a message key in the specified language.
+func Add(lang string, key string, text string) error {
+	if err := Translator(lang).Add(key, text, false); err != nil {
+		return errors.Wrapf(err, "adding %s translation for %q", lang, key)
+	}
+	return nil
+}
+
+// T returns the text registered for a message key in the specified language.
+// It falls back to the default language and then to the key itself.
+func T(lang string, key string) string {
+	if text, err := Translator(lang).T(key); err == nil {
+		return text
+	}
+	if text, err := Translator(Default).T(key); err == nil {
+		return text
+	}
+	return key
+}
+
+// Field translates a validation error into the specified language. Errors
+// for tags without a translation in that language use the default language.
+func Field(fe validator.FieldError, lang string) string {
+
+	// The validator falls back to the technical error message when there is
+	// no translation registered for the tag.
+	if msg := fe.Translate(Translator(lang)); msg != fe.Error() {
+		return msg
+	}
+	return fe.Translate(Translator(Default))
+}
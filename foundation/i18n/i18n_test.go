@@ -0,0 +1,90 @@
+package i18n_test
+
+import (
+	"testing"
+
+	validator "github.com/go-playground/validator/v10"
+	"github.com/tullo/service/foundation/i18n"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestNegotiate(t *testing.T) {
+	t.Log("Given the need to select a language from the Accept-Language header.")
+	{
+		tests := []struct {
+			accept string
+			lang   string
+		}{
+			{"", "en"},
+			{"de-DE,de;q=0.9,en;q=0.8", "de"},
+			{"da", "da"},
+			{"en;q=0.5, fr-CA", "fr"},
+			{"es-MX", "es"},
+			{"ja", "en"},
+			{"not a language;q=", "en"},
+		}
+
+		for testID, tt := range tests {
+			t.Logf("\tTest %d:\tWhen accepting %q.", testID, tt.accept)
+			{
+				if got := i18n.Negotiate(tt.accept); got != tt.lang {
+					t.Fatalf("\t%s\tTest %d:\tShould select %q : %q", failed, testID, tt.lang, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould select %q.", success, testID, tt.lang)
+			}
+		}
+	}
+}
+
+func TestField(t *testing.T) {
+	v := validator.New()
+	if err := i18n.RegisterValidator(v); err != nil {
+		t.Fatal(err)
+	}
+
+	var product struct {
+		Name     string `validate:"required"`
+		Cost     int    `validate:"gte=1"`
+		Category string `validate:"omitempty,oneof=toys books"`
+		Code     string `validate:"omitempty,hexcolor"`
+	}
+	product.Category = "food"
+	product.Code = "red"
+
+	var verrs validator.ValidationErrors
+	if err := v.Struct(product); err != nil {
+		verrs = err.(validator.ValidationErrors)
+	}
+
+	t.Log("Given the need to translate validation errors.")
+	{
+		tests := []struct {
+			lang string
+			exp  []string
+		}{
+			{"en", []string{"Name is a required field", "Cost must be 1 or greater", "Category must be one of [toys books]", "Code must be a valid HEX color"}},
+			{"de", []string{"Name ist ein Pflichtfeld", "Cost muss 1 oder größer sein", "Category muss einer der folgenden sein: [toys books]", "Code muss eine gültige Hexadezimalfarbe sein"}},
+			{"da", []string{"Name er et obligatorisk felt", "Cost skal være 1 eller større", "Category skal være en af [toys books]", "Code must be a valid HEX color"}},
+		}
+
+		for testID, tt := range tests {
+			t.Logf("\tTest %d:\tWhen translating into %q.", testID, tt.lang)
+			{
+				if len(verrs) != len(tt.exp) {
+					t.Fatalf("\t%s\tTest %d:\tShould get %d validation errors : %d", failed, testID, len(tt.exp), len(verrs))
+				}
+				for i, fe := range verrs {
+					if got := i18n.Field(fe, tt.lang); got != tt.exp[i] {
+						t.Fatalf("\t%s\tTest %d:\tShould get the expected message : got %q exp %q", failed, testID, got, tt.exp[i])
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould get the expected messages.", success, testID)
+			}
+		}
+	}
+}
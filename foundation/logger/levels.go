@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Levels holds the log levels of the components of a service. They can be
+// read and changed at runtime through its HTTP handler.
+type Levels struct {
+	mu     sync.RWMutex
+	levels map[string]*slog.LevelVar
+}
+
+// NewLevels constructs the levels of the components, all starting at the
+// level.
+func NewLevels(level slog.Level, components ...string) *Levels {
+	l := Levels{
+		levels: make(map[string]*slog.LevelVar, len(components)),
+	}
+	for _, c := range components {
+		var lv slog.LevelVar
+		lv.Set(level)
+		l.levels[c] = &lv
+	}
+	return &l
+}
+
+// Logger returns the logger of a component, which follows the level of the
+// component. The logger is returned unchanged when the levels are nil or
+// the component is unknown.
+func (l *Levels) Logger(log *slog.Logger, component string) *slog.Logger {
+	if l == nil {
+		return log
+	}
+
+	l.mu.RLock()
+	lv, ok := l.levels[component]
+	l.mu.RUnlock()
+	if !ok {
+		return log
+	}
+
+	return Component(log, component, lv)
+}
+
+// Set changes the level of a component.
+func (l *Levels) Set(component string, level slog.Level) error {
+	l.mu.RLock()
+	lv, ok := l.levels[component]
+	l.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown component %q", component)
+	}
+
+	lv.Set(level)
+	return nil
+}
+
+// Levels returns the names of the levels of all components.
+func (l *Levels) Levels() map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	levels := make(map[string]string, len(l.levels))
+	for c, lv := range l.levels {
+		levels[c] = lv.Level().String()
+	}
+	return levels
+}
+
+// ServeHTTP responds with the levels of the components. PUT requests change
+// them first, with a body like {"database": "debug"}. No level is changed
+// when any component or level of the body is unknown.
+func (l *Levels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+
+	case http.MethodPut:
+		var req map[string]string
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("decoding levels: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		// Validate the whole request before changing any level.
+		levels := make(map[string]slog.Level, len(req))
+		components := make([]string, 0, len(req))
+		for c, s := range req {
+			level, err := ParseLevel(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			levels[c] = level
+			components = append(components, c)
+		}
+		sort.Strings(components)
+
+		l.mu.RLock()
+		for _, c := range components {
+			if _, ok := l.levels[c]; !ok {
+				l.mu.RUnlock()
+				http.Error(w, fmt.Sprintf("unknown component %q", c), http.StatusBadRequest)
+				return
+			}
+		}
+		l.mu.RUnlock()
+
+		for _, c := range components {
+			l.Set(c, levels[c])
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l.Levels())
+}
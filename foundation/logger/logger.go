@@ -0,0 +1,87 @@
+// Package logger provides support for structured logging. Records are written
+// as JSON and carry the IDs of the trace and span of their context.
+package logger
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New constructs a Logger writing the records at or above the level to w.
+// Every record is tagged with the name of the service. Records of requests
+// marked verbose are written whatever their level.
+func New(w io.Writer, service string, level slog.Leveler) *slog.Logger {
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     slog.LevelDebug,
+	})
+
+	return slog.New(handler{Handler: h, level: level}).With("service", service)
+}
+
+// Component returns a logger for a component of the service. Its records
+// are tagged with the name of the component and filtered by its own level.
+func Component(log *slog.Logger, name string, level slog.Leveler) *slog.Logger {
+	h, ok := log.Handler().(handler)
+	if !ok {
+		return log.With("component", name)
+	}
+
+	attrs := []slog.Attr{slog.String("component", name)}
+	return slog.New(handler{Handler: h.Handler.WithAttrs(attrs), level: level})
+}
+
+// ParseLevel returns the level named by the string: debug, info, warn or
+// error.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, errors.Wrapf(err, "parsing log level %q", s)
+	}
+	return level, nil
+}
+
+// StdLogger returns a standard library logger writing its lines to the
+// logger at the level, for packages which only accept those.
+func StdLogger(log *slog.Logger, level slog.Level) *log.Logger {
+	return slog.NewLogLogger(log.Handler(), level)
+}
+
+// handler filters the records by level and adds the trace and span IDs of
+// the context to them.
+type handler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+// Enabled reports whether records of the level are written in the context.
+func (h handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() || Verbose(ctx)
+}
+
+// Handle adds trace_id and span_id attributes to the record when its context
+// holds a span.
+func (h handler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler adding the attributes to every record.
+func (h handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return handler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+// WithGroup returns a handler nesting the attributes added later in a group.
+func (h handler) WithGroup(name string) slog.Handler {
+	return handler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
@@ -0,0 +1,231 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tullo/service/foundation/logger"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestLogger(t *testing.T) {
+	t.Log("Given the need to write structured records.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen logging in the context of a span.", testID)
+		{
+			var buf bytes.Buffer
+			log := logger.New(&buf, "sales-api", slog.LevelInfo)
+
+			sc := trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+				SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+				TraceFlags: trace.FlagsSampled,
+			})
+			ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+			log.With("component", "test").InfoContext(ctx, "request completed", "status", 200)
+
+			var rec map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould write a JSON record : %v : %s", failed, testID, err, buf.String())
+			}
+			t.Logf("\t%s\tTest %d:\tShould write a JSON record.", success, testID)
+
+			exp := map[string]interface{}{
+				"msg":       "request completed",
+				"level":     "INFO",
+				"service":   "sales-api",
+				"component": "test",
+				"status":    float64(200),
+				"trace_id":  "4bf92f3577b34da6a3ce929d0e0e4736",
+				"span_id":   "00f067aa0ba902b7",
+			}
+			for k, v := range exp {
+				if rec[k] != v {
+					t.Fatalf("\t%s\tTest %d:\tShould get %s %v : got %v", failed, testID, k, v, rec[k])
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould carry the service, attributes, trace and span IDs.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen logging below the level or without a span.", testID)
+		{
+			var buf bytes.Buffer
+			var level slog.LevelVar
+			level.Set(slog.LevelWarn)
+			log := logger.New(&buf, "sales-api", &level)
+
+			log.Info("dropped")
+			if buf.Len() != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould drop records below the level : %s", failed, testID, buf.String())
+			}
+			t.Logf("\t%s\tTest %d:\tShould drop records below the level.", success, testID)
+
+			log.Warn("kept")
+			var rec map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould write a JSON record : %v : %s", failed, testID, err, buf.String())
+			}
+			if _, ok := rec["trace_id"]; ok {
+				t.Fatalf("\t%s\tTest %d:\tShould leave out the trace ID : %s", failed, testID, buf.String())
+			}
+			t.Logf("\t%s\tTest %d:\tShould leave out the trace ID.", success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen parsing levels.", testID)
+		{
+			lvl, err := logger.ParseLevel("debug")
+			if err != nil || lvl != slog.LevelDebug {
+				t.Fatalf("\t%s\tTest %d:\tShould parse debug : %v %v", failed, testID, lvl, err)
+			}
+			if _, err := logger.ParseLevel("verbose"); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject unknown levels.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould parse known levels only.", success, testID)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	t.Log("Given the need to change the log levels of components at runtime.")
+	{
+		var buf bytes.Buffer
+		log := logger.New(&buf, "sales-api", slog.LevelInfo)
+		levels := logger.NewLevels(slog.LevelInfo, "web", "database")
+		db := levels.Logger(log, "database")
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen changing the level of a component.", testID)
+		{
+			db.Debug("dropped")
+			if buf.Len() != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould drop debug records at info : %s", failed, testID, buf.String())
+			}
+			t.Logf("\t%s\tTest %d:\tShould drop debug records at info.", success, testID)
+
+			r := httptest.NewRequest(http.MethodPut, "/debug/log/levels", strings.NewReader(`{"database":"debug"}`))
+			w := httptest.NewRecorder()
+			levels.ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 200 for the response : %v", failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 200 for the response.", success, testID)
+
+			var got map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", failed, testID, err)
+			}
+			if got["database"] != "DEBUG" || got["web"] != "INFO" {
+				t.Fatalf("\t%s\tTest %d:\tShould respond with the new levels : %v", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould respond with the new levels.", success, testID)
+
+			db.Debug("kept")
+			var rec map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould write a JSON record : %v : %s", failed, testID, err, buf.String())
+			}
+			if rec["component"] != "database" {
+				t.Fatalf("\t%s\tTest %d:\tShould tag the record with the component : %v", failed, testID, rec["component"])
+			}
+			t.Logf("\t%s\tTest %d:\tShould write debug records of the component.", success, testID)
+
+			buf.Reset()
+			levels.Logger(log, "web").Debug("dropped")
+			if buf.Len() != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould leave the other components alone : %s", failed, testID, buf.String())
+			}
+			t.Logf("\t%s\tTest %d:\tShould leave the other components alone.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen changing levels with an invalid request.", testID)
+		{
+			tt := []struct {
+				method string
+				body   string
+				status int
+			}{
+				{http.MethodPut, `{"web":"debug","cache":"debug"}`, http.StatusBadRequest},
+				{http.MethodPut, `{"web":"verbose"}`, http.StatusBadRequest},
+				{http.MethodPut, `not json`, http.StatusBadRequest},
+				{http.MethodPost, `{"web":"debug"}`, http.StatusMethodNotAllowed},
+			}
+			for _, tc := range tt {
+				r := httptest.NewRequest(tc.method, "/debug/log/levels", strings.NewReader(tc.body))
+				w := httptest.NewRecorder()
+				levels.ServeHTTP(w, r)
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for %s %s : %v", failed, testID, tc.status, tc.method, tc.body, w.Code)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject unknown components, levels and methods.", success, testID)
+
+			if lv := levels.Levels()["web"]; lv != "INFO" {
+				t.Fatalf("\t%s\tTest %d:\tShould change no level on a rejected request : %s", failed, testID, lv)
+			}
+			t.Logf("\t%s\tTest %d:\tShould change no level on a rejected request.", success, testID)
+		}
+	}
+}
+
+func TestDebugHeader(t *testing.T) {
+	t.Log("Given the need to switch on verbose logging for a request.")
+	{
+		const key = "debug-key"
+		now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen verifying values of the debug header.", testID)
+		{
+			tt := []struct {
+				name  string
+				key   string
+				value string
+				exp   bool
+			}{
+				{"valid", key, logger.SignDebug(key, now.Add(time.Minute)), true},
+				{"expired", key, logger.SignDebug(key, now.Add(-time.Second)), false},
+				{"too long", key, logger.SignDebug(key, now.Add(logger.MaxDebugTTL+time.Minute)), false},
+				{"wrong key", key, logger.SignDebug("other-key", now.Add(time.Minute)), false},
+				{"empty key", "", logger.SignDebug("", now.Add(time.Minute)), false},
+				{"malformed", key, "garbage", false},
+			}
+			for _, tc := range tt {
+				if got := logger.VerifyDebug(tc.key, tc.value, now); got != tc.exp {
+					t.Fatalf("\t%s\tTest %d:\tShould get %v for a %s value : got %v", failed, testID, tc.exp, tc.name, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get %v for a %s value.", success, testID, tc.exp, tc.name)
+			}
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen logging in a verbose context.", testID)
+		{
+			var buf bytes.Buffer
+			log := logger.New(&buf, "sales-api", slog.LevelWarn)
+
+			log.DebugContext(logger.WithVerbose(context.Background()), "kept")
+			if buf.Len() == 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould write records below the level.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould write records below the level.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DebugHeader is the header asking for verbose logging of a request. Its
+// value must be signed with the debug key of the service, see SignDebug.
+const DebugHeader = "X-Debug-Log"
+
+// ctxKey is how the verbose flag is stored in a context.
+type ctxKey int
+
+const verboseKey ctxKey = 1
+
+// MaxDebugTTL bounds how long a value of the debug header is valid, so a
+// leaked value can't switch on verbose logging for long.
+const MaxDebugTTL = time.Hour
+
+// WithVerbose returns a context in which every record is written, whatever
+// the level of the logger.
+func WithVerbose(ctx context.Context) context.Context {
+	return context.WithValue(ctx, verboseKey, true)
+}
+
+// Verbose reports whether every record is written in the context.
+func Verbose(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	v, _ := ctx.Value(verboseKey).(bool)
+	return v
+}
+
+// SignDebug returns a value of the debug header valid until the expiry. It's
+// "<expiry>.<signature>" with the expiry in Unix seconds and the signature
+// the hex HMAC-SHA256 of the expiry with the key.
+func SignDebug(key string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + debugSignature(key, exp)
+}
+
+// VerifyDebug reports whether the value of the debug header is signed with
+// the key and not expired. Values expiring later than MaxDebugTTL from now
+// are rejected, as is any value with an empty key.
+func VerifyDebug(key string, value string, now time.Time) bool {
+	if key == "" || value == "" {
+		return false
+	}
+
+	exp, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	expires := time.Unix(unix, 0)
+	if err != nil || now.After(expires) || expires.Sub(now) > MaxDebugTTL {
+		return false
+	}
+
+	return hmac.Equal([]byte(sig), []byte(debugSignature(key, exp)))
+}
+
+// debugSignature returns the hex HMAC-SHA256 of the expiry with the key.
+func debugSignature(key string, exp string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(exp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,283 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TagFunc applies the constraint of a validation tag to a schema. The param
+// is the value following the = of the tag, if any.
+type TagFunc func(s *Schema, param string)
+
+// tags holds the functions converting validation tags into schema
+// constraints.
+var tags = struct {
+	mu  sync.RWMutex
+	fns map[string]TagFunc
+}{
+	fns: map[string]TagFunc{
+		"email":    format("email"),
+		"uuid":     format("uuid"),
+		"url":      format("uri"),
+		"http_url": format("uri"),
+		"hostname": format("hostname"),
+		"ip":       format("ip"),
+		"oneof":    oneOf,
+		"len":      bound(true, true, false),
+		"min":      bound(true, false, false),
+		"gte":      bound(true, false, false),
+		"gt":       bound(true, false, true),
+		"max":      bound(false, true, false),
+		"lte":      bound(false, true, false),
+		"lt":       bound(false, true, true),
+	},
+}
+
+// RegisterTag adds the conversion of a custom validation tag into schema
+// constraints. Unknown tags are ignored when schemas are generated.
+func RegisterTag(tag string, fn TagFunc) {
+	tags.mu.Lock()
+	defer tags.mu.Unlock()
+
+	tags.fns[tag] = fn
+}
+
+// lookupTag returns the conversion of a validation tag.
+func lookupTag(tag string) (TagFunc, bool) {
+	tags.mu.RLock()
+	defer tags.mu.RUnlock()
+
+	fn, ok := tags.fns[tag]
+	return fn, ok
+}
+
+// =============================================================================
+
+// Schemas holds the named schemas of a document keyed by the name of the Go
+// type they were generated from.
+type Schemas map[string]*Schema
+
+// Of returns the schema of the type of the provided value. Named struct types
+// are added to the schemas and referenced.
+func (s Schemas) Of(val interface{}) *Schema {
+	return s.schema(reflect.TypeOf(val))
+}
+
+// Ref returns a schema referencing a named schema.
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// schema returns the schema of a type.
+func (s Schemas) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return &Schema{Type: "string", Format: "date-time"}
+	case reflect.TypeOf(time.Duration(0)):
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.TypeOf(json.RawMessage(nil)):
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := t.String()
+		if _, exists := s[name]; !exists {
+
+			// Register the name first so recursive types terminate.
+			s[name] = &Schema{}
+			*s[name] = *s.object(t)
+		}
+		return Ref(name)
+	}
+
+	// Interfaces and other kinds accept any value.
+	return &Schema{}
+}
+
+// object returns the schema of a struct type. Properties are named after the
+// JSON tags of the fields and constrained by their validation tags.
+func (s Schemas) object(t reflect.Type) *Schema {
+	obj := Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if !fld.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(fld.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		// Embedded structs without a name contribute their fields.
+		if fld.Anonymous && name == "" {
+			ft := fld.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := s.object(ft)
+				for n, p := range embedded.Properties {
+					obj.Properties[n] = p
+				}
+				obj.Required = append(obj.Required, embedded.Required...)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = fld.Name
+		}
+
+		prop := s.schema(fld.Type)
+		if constrain(prop, fld.Tag.Get("validate")) {
+			obj.Required = append(obj.Required, name)
+		}
+		obj.Properties[name] = prop
+	}
+
+	return &obj
+}
+
+// constrain applies the rules of a validation tag to a property schema. It
+// reports whether the property is required. Rules following dive apply to
+// the items of arrays. Constraints can't be added to referenced schemas.
+func constrain(prop *Schema, validate string) bool {
+	if validate == "" {
+		return false
+	}
+
+	var required bool
+	target := prop
+	for _, rule := range strings.Split(validate, ",") {
+		tag, param, _ := strings.Cut(rule, "=")
+		switch tag {
+		case "required":
+			required = target == prop
+			continue
+		case "dive":
+			if target.Items == nil {
+				return required
+			}
+			target = target.Items
+			continue
+		}
+
+		if target.Ref != "" {
+			continue
+		}
+		if fn, ok := lookupTag(tag); ok {
+			fn(target, param)
+		}
+	}
+
+	return required
+}
+
+// =============================================================================
+
+// format returns a TagFunc setting the format of a string.
+func format(f string) TagFunc {
+	return func(s *Schema, param string) {
+		s.Format = f
+	}
+}
+
+// oneOf restricts the values to the space separated list of the param.
+func oneOf(s *Schema, param string) {
+	for _, v := range strings.Fields(param) {
+		switch s.Type {
+		case "integer", "number":
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				s.Enum = append(s.Enum, n)
+			}
+		default:
+			s.Enum = append(s.Enum, v)
+		}
+	}
+}
+
+// bound returns a TagFunc setting the lower and/or upper bound of a value.
+// Strings are bound by their length and arrays by their number of items.
+func bound(lower bool, upper bool, exclusive bool) TagFunc {
+	return func(s *Schema, param string) {
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+
+		switch s.Type {
+		case "integer", "number":
+			switch {
+			case lower && exclusive:
+				s.ExclusiveMinimum = &n
+			case lower:
+				s.Minimum = &n
+			}
+			switch {
+			case upper && exclusive:
+				s.ExclusiveMaximum = &n
+			case upper:
+				s.Maximum = &n
+			}
+
+		case "string", "array":
+			length := int(n)
+			if exclusive {
+				if lower {
+					length++
+				} else {
+					length--
+				}
+			}
+			minimum, maximum := &s.MinLength, &s.MaxLength
+			if s.Type == "array" {
+				minimum, maximum = &s.MinItems, &s.MaxItems
+			}
+			if lower {
+				*minimum = &length
+			}
+			if upper {
+				*maximum = &length
+			}
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package tracer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// keepKey marks a span whose trace is exported whatever the sampling
+// decision. It follows the sampling.priority convention of OpenTracing.
+const keepKey = attribute.Key("sampling.priority")
+
+// Keep marks the trace of the context to be exported even when it isn't
+// sampled, like when its request failed. It only has an effect with tail
+// sampling.
+func Keep(ctx context.Context) {
+	trace.SpanFromContext(ctx).SetAttributes(keepKey.Int(1))
+}
+
+// TailSampler returns a sampler sampling traces by the decision of the
+// base sampler, but recording the spans it drops so the processor returned
+// by NewTailProcessor can still keep their trace.
+func TailSampler(base sdktrace.Sampler) sdktrace.Sampler {
+	return tailSampler{base: base}
+}
+
+// tailSampler samples traces by the decision of the base sampler and
+// records the others.
+type tailSampler struct {
+	base sdktrace.Sampler
+}
+
+// ShouldSample records every span and samples those the base sampler does.
+func (s tailSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	res := s.base.ShouldSample(p)
+	if res.Decision == sdktrace.Drop {
+		res.Decision = sdktrace.RecordOnly
+	}
+	return res
+}
+
+// Description returns the name of the sampler.
+func (s tailSampler) Description() string {
+	return "TailSampler{" + s.base.Description() + "}"
+}
+
+// tailProcessor buffers the spans of the traces which aren't sampled until
+// their local root ends. The trace is then passed on as sampled when any of
+// its spans was marked with Keep or failed, or its root took longer than
+// the latency threshold. Otherwise it's dropped. Sampled spans are passed
+// on right away.
+type tailProcessor struct {
+	next      sdktrace.SpanProcessor
+	latency   time.Duration
+	maxTraces int
+	maxSpans  int
+
+	mu     sync.Mutex
+	traces map[trace.TraceID]*tailTrace
+}
+
+// tailTrace holds the ended spans of a trace while it isn't done.
+type tailTrace struct {
+	spans []sdktrace.ReadOnlySpan
+	open  int
+	keep  bool
+}
+
+// maxSpansPerTrace bounds the spans buffered for a single trace. Spans past
+// it are dropped even when the trace is kept.
+const maxSpansPerTrace = 1024
+
+// NewTailProcessor constructs a processor passing the kept traces on to
+// next, buffering at most maxTraces traces. The spans of traces started
+// when the buffer is full are dropped.
+func NewTailProcessor(next sdktrace.SpanProcessor, latency time.Duration, maxTraces int) sdktrace.SpanProcessor {
+	return &tailProcessor{
+		next:      next,
+		latency:   latency,
+		maxTraces: maxTraces,
+		maxSpans:  maxSpansPerTrace,
+		traces:    make(map[trace.TraceID]*tailTrace),
+	}
+}
+
+// OnStart counts the spans of the traces being buffered.
+func (p *tailProcessor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	if s.SpanContext().IsSampled() {
+		p.next.OnStart(ctx, s)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tid := s.SpanContext().TraceID()
+	t, ok := p.traces[tid]
+	if !ok {
+		if len(p.traces) >= p.maxTraces {
+			return
+		}
+		t = &tailTrace{}
+		p.traces[tid] = t
+	}
+	t.open++
+}
+
+// OnEnd buffers the span and decides the fate of its trace once its last
+// open span has ended.
+func (p *tailProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.next.OnEnd(s)
+		return
+	}
+
+	p.mu.Lock()
+	tid := s.SpanContext().TraceID()
+	t, ok := p.traces[tid]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+
+	if len(t.spans) < p.maxSpans {
+		t.spans = append(t.spans, s)
+	}
+	t.keep = t.keep || p.interesting(s)
+	t.open--
+
+	if t.open > 0 {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.traces, tid)
+	p.mu.Unlock()
+
+	if !t.keep {
+		return
+	}
+	for _, s := range t.spans {
+		p.next.OnEnd(sampledSpan{ReadOnlySpan: s})
+	}
+}
+
+// interesting reports whether the span keeps its trace.
+func (p *tailProcessor) interesting(s sdktrace.ReadOnlySpan) bool {
+	if s.Status().Code == codes.Error {
+		return true
+	}
+	for _, a := range s.Attributes() {
+		if a.Key == keepKey && a.Value.AsInt64() > 0 {
+			return true
+		}
+	}
+
+	// Only the duration of the local root tells the latency of a request.
+	parent := s.Parent()
+	root := !parent.IsValid() || parent.IsRemote()
+	return root && p.latency > 0 && s.EndTime().Sub(s.StartTime()) > p.latency
+}
+
+// Shutdown drops the buffered traces and shuts the next processor down.
+func (p *tailProcessor) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.traces = make(map[trace.TraceID]*tailTrace)
+	p.mu.Unlock()
+
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush flushes the next processor. Traces still open stay buffered.
+func (p *tailProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// sampledSpan is a span of a kept trace, reported as sampled so exporters
+// don't drop it.
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+// SpanContext returns the span context with the sampled flag set.
+func (s sampledSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
@@ -0,0 +1,64 @@
+package tracer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tullo/service/foundation/tracer"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTailSampling(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(tracer.TailSampler(sdktrace.NeverSample())),
+		sdktrace.WithSpanProcessor(tracer.NewTailProcessor(rec, 50*time.Millisecond, 10)),
+	)
+	defer tp.Shutdown(context.Background())
+	tr := tp.Tracer("test")
+
+	tt := []struct {
+		name  string
+		fn    func(ctx context.Context)
+		spans int
+	}{
+		{"a fast request", func(ctx context.Context) {}, 0},
+		{"a request marked to keep", func(ctx context.Context) { tracer.Keep(ctx) }, 2},
+		{"a failed request", func(ctx context.Context) {
+			_, span := tr.Start(ctx, "query")
+			span.SetStatus(codes.Error, "failed")
+			span.End()
+		}, 3},
+		{"a slow request", func(ctx context.Context) { time.Sleep(60 * time.Millisecond) }, 2},
+	}
+
+	t.Log("Given the need to keep the traces of failed and slow requests.")
+	{
+		for testID, tc := range tt {
+			t.Logf("\tTest %d:\tWhen tracing %s which isn't sampled.", testID, tc.name)
+			{
+				before := len(rec.Ended())
+
+				ctx, root := tr.Start(context.Background(), "request")
+				ctx, child := tr.Start(ctx, "handler")
+				tc.fn(ctx)
+				child.End()
+				root.End()
+
+				ended := rec.Ended()[before:]
+				if len(ended) != tc.spans {
+					t.Fatalf("\t%s\tTest %d:\tShould export %d spans : got %d", failed, testID, tc.spans, len(ended))
+				}
+				for _, s := range ended {
+					if !s.SpanContext().IsSampled() {
+						t.Fatalf("\t%s\tTest %d:\tShould export the spans as sampled : %s", failed, testID, s.Name())
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould export %d spans.", success, testID, tc.spans)
+			}
+		}
+	}
+}
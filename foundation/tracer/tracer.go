@@ -3,69 +3,161 @@ package tracer
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"os"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/tullo/service/foundation/logger"
+	"go.opentelemetry.io/contrib/propagators/b3"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/exporters/zipkin"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
 )
 
+// Exporters the spans can be sent with.
+const (
+	ExporterOTLPGRPC = "otlp-grpc"
+	ExporterOTLPHTTP = "otlp-http"
+	ExporterZipkin   = "zipkin"
+	ExporterStdout   = "stdout"
+	ExporterNone     = "none"
+)
+
 // Config holds trace config properties.
 type Config struct {
 	ServiceName string
-	ReporterURI string
+	Version     string
+
+	// Exporter is one of otlp-grpc, otlp-http, zipkin, stdout or none.
+	Exporter string
+
+	// Endpoint is the URL the spans are sent to, like
+	// http://otel-collector:4317 for otlp-grpc. The OTLP exporters fall back
+	// to the OTEL_EXPORTER_OTLP_* environment variables when it's empty.
+	Endpoint string
+
+	// Probability is the ratio of the traces started here which are sampled.
+	// Traces continued from a caller follow its sampling decision.
 	Probability float64
+
+	// The traces which aren't sampled are buffered until their request is
+	// done, and still exported when it failed or took longer than
+	// KeepLatency. At most MaxBufferedTraces are buffered at once, zero
+	// turns the buffering off.
+	KeepLatency       time.Duration
+	MaxBufferedTraces int
+
+	// The Kubernetes pod the service is running in, if any.
+	Pod       string
+	Namespace string
+	Node      string
 }
 
-// Init creates a new trace provider instance and registers it as global trace provider.
-func Init(l *log.Logger, c *Config) (func(ctx context.Context) error, error) {
-	exporter, err := zipkin.New(c.ReporterURI, zipkin.WithLogger(l))
+// Init creates a new trace provider instance and registers it as global
+// trace provider. The W3C TraceContext and B3 headers are propagated.
+func Init(log *slog.Logger, c *Config) (func(ctx context.Context) error, error) {
+	exporter, err := newExporter(log, c)
 	if err != nil {
-		return nil, errors.Wrap(err, "creating new exporter")
+		return nil, errors.Wrapf(err, "creating %s exporter", c.Exporter)
 	}
 
-	batcher := sdktrace.NewBatchSpanProcessor(
-		exporter,
-		sdktrace.WithBatchTimeout(sdktrace.DefaultScheduleDelay*time.Millisecond),
-		sdktrace.WithMaxExportBatchSize(sdktrace.DefaultMaxExportBatchSize),
-	)
-
-	// Demo mode configuarion, always record and sample.
-	//
-	// By default the returned TracerProvider is configured with:
-	// - a ParentBased(AlwaysSample) Sampler
-	// - a random number IDGenerator
-	// - the resource.Default() Resource
-	// - the default SpanLimits
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithSpanProcessor(batcher),
-		sdktrace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String(c.ServiceName),
-		)),
-	)
-
-	if c.Probability < 1 {
-		// Production mode configuarion. A probability=0.01 means only 1% of
-		// tracing information will be exported to Zipkin.
-		tp = sdktrace.NewTracerProvider(
-			sdktrace.WithSpanProcessor(batcher),
-			sdktrace.WithSampler(sdktrace.TraceIDRatioBased(c.Probability)),
-			sdktrace.WithResource(resource.NewWithAttributes(
-				semconv.SchemaURL,
-				semconv.ServiceNameKey.String("zipkin-search"),
-			)))
+	// A probability=0.01 means only 1% of the traces starting here are
+	// sampled.
+	var sampler sdktrace.Sampler = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.Probability))
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(newResource(c)),
 	}
 
-	otel.SetTracerProvider(tp)
+	// Without an exporter spans are still created, so the trace IDs are
+	// reported to clients and in the logs.
+	if exporter != nil {
+		var processor sdktrace.SpanProcessor = sdktrace.NewBatchSpanProcessor(
+			exporter,
+			sdktrace.WithBatchTimeout(sdktrace.DefaultScheduleDelay*time.Millisecond),
+			sdktrace.WithMaxExportBatchSize(sdktrace.DefaultMaxExportBatchSize),
+		)
 
-	//	var b3 trace.B3
-	//	props := propagation.New(propagation.WithExtractors(b3))
-	//	global.SetPropagators(props)
+		// Buffer the traces which aren't sampled to keep those of failed
+		// and slow requests.
+		if c.Probability < 1 && c.MaxBufferedTraces > 0 {
+			sampler = TailSampler(sampler)
+			processor = NewTailProcessor(processor, c.KeepLatency, c.MaxBufferedTraces)
+		}
+
+		opts = append(opts, sdktrace.WithSpanProcessor(processor))
+	}
+	opts = append(opts, sdktrace.WithSampler(sampler))
+
+	tp := sdktrace.NewTracerProvider(opts...)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+		b3.New(),
+	))
 
 	return tp.Shutdown, nil
 }
+
+// newExporter constructs the exporter selected by the config. There is no
+// exporter when none is selected.
+func newExporter(log *slog.Logger, c *Config) (sdktrace.SpanExporter, error) {
+	switch c.Exporter {
+	case ExporterOTLPGRPC:
+		var opts []otlptracegrpc.Option
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(c.Endpoint))
+		}
+		return otlptracegrpc.New(context.Background(), opts...)
+
+	case ExporterOTLPHTTP:
+		var opts []otlptracehttp.Option
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(c.Endpoint))
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+
+	case ExporterZipkin:
+		return zipkin.New(c.Endpoint, zipkin.WithLogger(logger.StdLogger(log, slog.LevelError)))
+
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+
+	case ExporterNone:
+		return nil, nil
+	}
+
+	return nil, errors.Errorf("unknown exporter %q", c.Exporter)
+}
+
+// newResource describes the service and where it's running. The
+// attributes of the pod are only set when running in Kubernetes.
+func newResource(c *Config) *resource.Resource {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(c.ServiceName),
+		semconv.ServiceVersionKey.String(c.Version),
+	}
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, semconv.HostNameKey.String(host))
+	}
+	if c.Pod != "" {
+		attrs = append(attrs, semconv.K8SPodNameKey.String(c.Pod))
+	}
+	if c.Namespace != "" {
+		attrs = append(attrs, semconv.K8SNamespaceNameKey.String(c.Namespace))
+	}
+	if c.Node != "" {
+		attrs = append(attrs, semconv.K8SNodeNameKey.String(c.Node))
+	}
+
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+}
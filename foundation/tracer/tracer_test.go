@@ -0,0 +1,63 @@
+package tracer_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/tullo/service/foundation/logger"
+	"github.com/tullo/service/foundation/tracer"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestInit(t *testing.T) {
+	log := logger.New(io.Discard, "TEST", slog.LevelInfo)
+
+	t.Log("Given the need to select the trace exporter.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen selecting no exporter.", testID)
+		{
+			shutdown, err := tracer.Init(log, &tracer.Config{ServiceName: "sales-api", Exporter: tracer.ExporterNone, Probability: 1})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to initialize tracing : %v", failed, testID, err)
+			}
+			defer shutdown(context.Background())
+			t.Logf("\t%s\tTest %d:\tShould be able to initialize tracing.", success, testID)
+
+			ctx, span := otel.Tracer("test").Start(context.Background(), "test")
+			defer span.End()
+			if !span.SpanContext().IsSampled() {
+				t.Fatalf("\t%s\tTest %d:\tShould sample the trace.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould sample the trace.", success, testID)
+
+			h := make(http.Header)
+			otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+			for _, k := range []string{"Traceparent", "B3"} {
+				if h.Get(k) == "" {
+					t.Fatalf("\t%s\tTest %d:\tShould propagate the %s header : %v", failed, testID, k, h)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould propagate the W3C and B3 headers.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen selecting an unknown exporter.", testID)
+		{
+			if _, err := tracer.Init(log, &tracer.Config{Exporter: "jaeger"}); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the exporter.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the exporter.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package validate provides the validation engine shared by the web framework
+// and the business layer. Values are checked against their `validate` struct
+// tags. Additional tags and cross-field rules can be registered by the
+// packages owning the rules.
+package validate
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	validator "github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/tullo/service/foundation/i18n"
+)
+
+// validate holds the settings and caches for validating struct values.
+var validate *validator.Validate
+
+// summary holds the translations of the message describing a failed
+// validation.
+var summary = map[string]string{
+	"en": "field validation error",
+	"de": "Fehler bei der Feldvalidierung",
+	"da": "fejl ved validering af felter",
+	"es": "error de validación de campos",
+	"fr": "erreur de validation des champs",
+}
+
+func init() {
+
+	// Instantiate the validator for use.
+	validate = validator.New()
+
+	// Register the error messages for validation errors in all the
+	// supported languages.
+	if err := i18n.RegisterValidator(validate); err != nil {
+		panic(err)
+	}
+	for lang, text := range summary {
+		if err := i18n.Add(lang, "field_validation_error", text); err != nil {
+			panic(err)
+		}
+	}
+
+	// Use JSON tag names for errors instead of Go struct names.
+	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// Accept the same identifiers the stores do. The built in tag only
+	// accepts the canonical form.
+	if err := validate.RegisterValidation("uuid", isUUID); err != nil {
+		panic(err)
+	}
+}
+
+// =============================================================================
+
+// FieldError is used to indicate an error with a specific field.
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// Errors reports the fields of a value that failed validation. The messages
+// are translated when the errors are reported to a client so values can be
+// validated in places that don't know the client's language.
+type Errors struct {
+	errs validator.ValidationErrors
+}
+
+// Error implements the error interface. It reports the fields in the default
+// language. This is what will be shown in the services' logs.
+func (e *Errors) Error() string {
+	d, err := json.Marshal(e.Fields(i18n.Default))
+	if err != nil {
+		return err.Error()
+	}
+	return string(d)
+}
+
+// Fields returns the fields that failed validation with messages in the
+// specified language.
+func (e *Errors) Fields(lang string) []FieldError {
+	fields := make([]FieldError, 0, len(e.errs))
+	for _, fe := range e.errs {
+		field := FieldError{
+			Field: fe.Field(),
+			Error: i18n.Field(fe, lang),
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// Summary returns the message describing the failed validation in the
+// specified language.
+func (e *Errors) Summary(lang string) string {
+	return i18n.T(lang, "field_validation_error")
+}
+
+// =============================================================================
+
+// Check validates the provided value against its declared tags and the
+// registered struct level rules. Failures are returned as *Errors.
+func Check(val interface{}) error {
+	if err := validate.Struct(val); err != nil {
+
+		// Use a type assertion to get the real error value.
+		verrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			return err
+		}
+
+		return &Errors{errs: verrors}
+	}
+
+	return nil
+}
+
+// RegisterValidation adds a validation tag. It's not safe to call while
+// values are validated so it must be called during initialization.
+func RegisterValidation(tag string, fn validator.Func) error {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		return errors.Wrapf(err, "registering %q", tag)
+	}
+	return nil
+}
+
+// RegisterStructValidation adds a rule validating the fields of the specified
+// types against each other. Failures are reported with StructLevel.ReportError
+// using a tag that has a registered translation. It must be called during
+// initialization.
+func RegisterStructValidation(fn validator.StructLevelFunc, types ...interface{}) {
+	validate.RegisterStructValidation(fn, types...)
+}
+
+// RegisterTranslation adds the error messages of a tag keyed by language.
+// In the messages {0} is replaced by the field name and {1} by the tag
+// parameter. Languages without a message use the default language. It must
+// be called during initialization.
+func RegisterTranslation(tag string, messages map[string]string) error {
+	for lang, text := range messages {
+		register := func(trans ut.Translator) error {
+			return trans.Add(tag, text, true)
+		}
+		translate := func(trans ut.Translator, fe validator.FieldError) string {
+			text, err := trans.T(tag, fe.Field(), fe.Param())
+			if err != nil {
+				return fe.Error()
+			}
+			return text
+		}
+		if err := validate.RegisterTranslation(tag, i18n.Translator(lang), register, translate); err != nil {
+			return errors.Wrapf(err, "registering %s translation for %q", lang, tag)
+		}
+	}
+	return nil
+}
+
+// isUUID reports whether the field holds a valid identifier.
+func isUUID(fl validator.FieldLevel) bool {
+	_, err := uuid.Parse(fl.Field().String())
+	return err == nil
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// limitedBody enforces the body size limit of a request. The limit is read
+// from the request values on every read so it can be set by middleware after
+// the body has been wrapped.
+type limitedBody struct {
+	io.ReadCloser
+	v    *Values
+	read int64
+}
+
+// Read implements the io.Reader interface.
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	limit := lb.v.maxBodyBytes
+	switch {
+	case limit <= 0:
+		return lb.ReadCloser.Read(p)
+	case lb.read > limit:
+		return 0, &http.MaxBytesError{Limit: limit}
+	}
+
+	// Allow reading one byte past the limit to detect oversized bodies.
+	if remaining := limit + 1 - lb.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := lb.ReadCloser.Read(p)
+	lb.read += int64(n)
+	if lb.read > limit {
+		return n - int(lb.read-limit), &http.MaxBytesError{Limit: limit}
+	}
+	return n, err
+}
+
+// ReadBody reads the complete body of a request. Bodies exceeding the limit
+// set by MaxBodyBytes are reported as a 413.
+func ReadBody(r *http.Request) ([]byte, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, bodyError(err)
+	}
+	return data, nil
+}
+
+// bodyError converts an error reading or decoding a request body into a
+// request error.
+func bodyError(err error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		err := fmt.Errorf("request body must not exceed %d bytes", mbe.Limit)
+		return NewRequestError(err, http.StatusRequestEntityTooLarge)
+	}
+	if errors.Is(err, io.EOF) {
+		return NewRequestError(errors.New("request body must not be empty"), http.StatusBadRequest)
+	}
+	return NewRequestError(err, http.StatusBadRequest)
+}
+
+// jsonError describes a JSON decoding error with the line and column of the
+// offending input.
+func jsonError(data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// The offset points just past the offending character.
+		line, col := position(data, syntaxErr.Offset-1)
+		return fmt.Errorf("request body contains malformed JSON at line %d, column %d: %v", line, col, err)
+
+	case errors.As(err, &typeErr):
+		line, col := position(data, typeErr.Offset)
+		if typeErr.Field != "" {
+			return fmt.Errorf("request body contains an invalid value for field %q at line %d, column %d: expected %s", typeErr.Field, line, col, typeErr.Type)
+		}
+		return fmt.Errorf("request body contains an invalid value at line %d, column %d: expected %s", line, col, typeErr.Type)
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		line, col := position(data, int64(len(data)))
+		return fmt.Errorf("request body contains malformed JSON at line %d, column %d: unexpected end of input", line, col)
+	}
+
+	return err
+}
+
+// position converts a byte offset into a 1-based line and column.
+func position(data []byte, offset int64) (int, int) {
+	switch {
+	case offset < 0:
+		offset = 0
+	case offset > int64(len(data)):
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
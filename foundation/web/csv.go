@@ -0,0 +1,301 @@
+package web
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvCodec encodes and decodes text/csv documents. The first record holds
+// the column names which are taken from the JSON struct tags. Values that
+// are not scalars are written as JSON text.
+type csvCodec struct{}
+
+// ContentType implements the Encoder and Decoder interfaces.
+func (csvCodec) ContentType() string {
+	return "text/csv"
+}
+
+// canEncode reports whether the value is a struct or a slice of structs,
+// the values Encode supports.
+func (csvCodec) canEncode(val interface{}) bool {
+	_, ok := csvElem(reflect.Indirect(reflect.ValueOf(val)))
+	return ok
+}
+
+// csvElem returns the struct type of the rows of the value.
+func csvElem(rv reflect.Value) (reflect.Type, bool) {
+	if !rv.IsValid() {
+		return nil, false
+	}
+
+	elem := rv.Type()
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		elem = elem.Elem()
+	case reflect.Struct:
+	default:
+		return nil, false
+	}
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem, elem.Kind() == reflect.Struct
+}
+
+// Encode implements the Encoder interface. The value must be a struct or a
+// slice of structs.
+func (csvCodec) Encode(w io.Writer, val interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(val))
+
+	elem, ok := csvElem(rv)
+	if !ok {
+		return fmt.Errorf("csv: unsupported type %T", val)
+	}
+
+	var rows []reflect.Value
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			row := reflect.Indirect(rv.Index(i))
+			if !row.IsValid() {
+				return fmt.Errorf("csv: nil row %d", i)
+			}
+			rows = append(rows, row)
+		}
+	case reflect.Struct:
+		rows = append(rows, rv)
+	}
+
+	fields := csvFields(elem)
+
+	cw := csv.NewWriter(w)
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = f.name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(fields))
+	for _, row := range rows {
+		for i, f := range fields {
+			cell, err := formatCSV(row.Field(f.index))
+			if err != nil {
+				return fmt.Errorf("csv: field %s: %w", f.name, err)
+			}
+			record[i] = cell
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// Decode implements the Decoder interface. The value must be a pointer to a
+// struct, in which case exactly one record is expected, or a pointer to a
+// slice of structs. Unknown columns are rejected.
+func (csvCodec) Decode(r io.Reader, val interface{}) error {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("csv: decode requires a non-nil pointer, got %T", val)
+	}
+	rv = rv.Elem()
+
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return io.EOF
+	}
+
+	elem := rv.Type()
+	if rv.Kind() == reflect.Slice {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("csv: unsupported type %s", rv.Type())
+	}
+
+	// Map the header to the struct fields.
+	byName := make(map[string]int)
+	for _, f := range csvFields(elem) {
+		byName[f.name] = f.index
+	}
+	columns := make([]int, len(records[0]))
+	for i, name := range records[0] {
+		idx, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("csv: unknown field %q", name)
+		}
+		columns[i] = idx
+	}
+
+	decodeRow := func(dst reflect.Value, record []string) error {
+		for i, cell := range record {
+			name := records[0][i]
+			if err := parseCSV(dst.Field(columns[i]), cell); err != nil {
+				return fmt.Errorf("csv: field %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+
+	rows := records[1:]
+	if rv.Kind() == reflect.Struct {
+		if len(rows) != 1 {
+			return fmt.Errorf("csv: expected one record, got %d", len(rows))
+		}
+		return decodeRow(rv, rows[0])
+	}
+
+	slice := reflect.MakeSlice(rv.Type(), len(rows), len(rows))
+	for i, record := range rows {
+		if err := decodeRow(slice.Index(i), record); err != nil {
+			return err
+		}
+	}
+	rv.Set(slice)
+
+	return nil
+}
+
+// csvField maps a column name to the index of a struct field.
+type csvField struct {
+	name  string
+	index int
+}
+
+// csvFields returns the exported fields of a struct type which are part of
+// its JSON representation.
+func csvFields(t reflect.Type) []csvField {
+	var fields []csvField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := strings.SplitN(sf.Tag.Get("json"), ",", 2)[0]
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = sf.Name
+		}
+		fields = append(fields, csvField{name: name, index: i})
+	}
+	return fields
+}
+
+// formatCSV converts a field value into a cell.
+func formatCSV(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return escapeFormula(v.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// escapeFormula prefixes text starting like a formula with a quote, so
+// spreadsheets opening the document show it as text instead of evaluating
+// it. Numbers are formatted separately and keep their sign.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// parseCSV sets a field value from a cell. Empty cells leave pointer fields
+// nil.
+func parseCSV(v reflect.Value, cell string) error {
+	if v.Kind() == reflect.Ptr {
+		if cell == "" {
+			return nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	if _, ok := v.Interface().(time.Time); ok {
+		t, err := time.Parse(time.RFC3339Nano, cell)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(cell)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(cell)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(cell, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(cell, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(cell, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+		return nil
+	}
+
+	if cell == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(cell), v.Addr().Interface())
+}
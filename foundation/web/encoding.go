@@ -0,0 +1,277 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoder marshals response values into the media type it represents.
+type Encoder interface {
+	ContentType() string
+	Encode(w io.Writer, val interface{}) error
+}
+
+// limitedEncoder is implemented by encoders which can't represent every
+// value, like CSV which needs structs.
+type limitedEncoder interface {
+	canEncode(val interface{}) bool
+}
+
+// canEncode reports whether the encoder is able to represent the value.
+func canEncode(enc Encoder, val interface{}) bool {
+	le, ok := enc.(limitedEncoder)
+	return !ok || le.canEncode(val)
+}
+
+// Decoder unmarshals request bodies of the media type it represents.
+type Decoder interface {
+	ContentType() string
+	Decode(r io.Reader, val interface{}) error
+}
+
+// codecs holds the registered encoders and decoders. Encoders are kept in
+// registration order, the first one is used when the client accepts any
+// media type.
+var codecs = struct {
+	mu       sync.RWMutex
+	encoders []Encoder
+	decoders map[string]Decoder
+}{
+	decoders: make(map[string]Decoder),
+}
+
+func init() {
+	RegisterEncoder(jsonCodec{})
+	RegisterEncoder(csvCodec{})
+	RegisterEncoder(msgpackCodec{})
+	RegisterEncoder(eventStreamCodec{})
+
+	RegisterDecoder(jsonCodec{})
+	RegisterDecoder(csvCodec{})
+	RegisterDecoder(msgpackCodec{})
+}
+
+// RegisterEncoder adds an encoder to the set of encoders Respond selects
+// from using the Accept header. An encoder registered for a content type that
+// already has one replaces it.
+func RegisterEncoder(enc Encoder) {
+	codecs.mu.Lock()
+	defer codecs.mu.Unlock()
+
+	for i, e := range codecs.encoders {
+		if e.ContentType() == enc.ContentType() {
+			codecs.encoders[i] = enc
+			return
+		}
+	}
+	codecs.encoders = append(codecs.encoders, enc)
+}
+
+// RegisterDecoder adds a decoder to the set of decoders Decode selects from
+// using the Content-Type header.
+func RegisterDecoder(dec Decoder) {
+	codecs.mu.Lock()
+	defer codecs.mu.Unlock()
+
+	codecs.decoders[dec.ContentType()] = dec
+}
+
+// negotiateEncoder returns the encoder best matching the media ranges of an
+// Accept header. It returns false if no registered encoder is acceptable. An
+// empty header accepts any media type.
+func negotiateEncoder(accept string) (Encoder, bool) {
+	return selectEncoder(accept, func(Encoder) bool { return true })
+}
+
+// negotiateValueEncoder returns the encoder best matching the media ranges
+// of an Accept header which is able to represent the value.
+func negotiateValueEncoder(accept string, val interface{}) (Encoder, bool) {
+	return selectEncoder(accept, func(enc Encoder) bool { return canEncode(enc, val) })
+}
+
+// selectEncoder returns the encoder best matching the media ranges of an
+// Accept header among those the filter keeps.
+func selectEncoder(accept string, keep func(Encoder) bool) (Encoder, bool) {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+
+	ranges := parseAccept(accept)
+	if strings.TrimSpace(accept) == "" {
+		ranges = []mediaRange{{typ: "*", subtype: "*", q: 1}}
+	}
+
+	for _, mr := range ranges {
+		for _, enc := range codecs.encoders {
+			if mr.matches(enc.ContentType()) && keep(enc) {
+				return enc, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// lookupDecoder returns the decoder for the media type of a Content-Type
+// header. An empty header is treated as JSON.
+func lookupDecoder(contentType string) (Decoder, bool) {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+
+	if contentType == "" {
+		return codecs.decoders["application/json"], true
+	}
+
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	dec, ok := codecs.decoders[mt]
+	return dec, ok
+}
+
+// =============================================================================
+
+// mediaRange is a single entry of an Accept header.
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// matches reports whether the media range includes the content type. JSON
+// includes the media types with a +json suffix, like application/problem+json.
+func (mr mediaRange) matches(contentType string) bool {
+	typ, subtype, _ := strings.Cut(contentType, "/")
+	switch {
+	case mr.typ == "*":
+		return true
+	case mr.typ != typ:
+		return false
+	case mr.subtype == "*":
+		return true
+	case subtype == "json" && strings.HasSuffix(mr.subtype, "+json"):
+		return true
+	}
+	return mr.subtype == subtype
+}
+
+// specificity ranks exact media ranges over wildcard ones.
+func (mr mediaRange) specificity() int {
+	switch {
+	case mr.typ == "*":
+		return 0
+	case mr.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+// parseAccept parses an Accept header into media ranges ordered by
+// preference. Ranges with a quality of zero are dropped.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mt, "/")
+		if !ok {
+			continue
+		}
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+		if q, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(q, 64); err == nil {
+				mr.q = f
+			}
+		}
+		if mr.q <= 0 {
+			continue
+		}
+		ranges = append(ranges, mr)
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+
+	return ranges
+}
+
+// =============================================================================
+
+// jsonCodec encodes and decodes application/json documents.
+type jsonCodec struct{}
+
+// ContentType implements the Encoder and Decoder interfaces.
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+// Encode implements the Encoder interface.
+func (jsonCodec) Encode(w io.Writer, val interface{}) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Decode implements the Decoder interface. Unknown fields and data trailing
+// the JSON document are rejected. Syntax errors report the line and column
+// of the offending input.
+func (jsonCodec) Decode(r io.Reader, val interface{}) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(val); err != nil {
+		return jsonError(data, err)
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("request body must only contain a single JSON document")
+	}
+
+	return nil
+}
+
+// msgpackCodec encodes and decodes application/msgpack documents. The JSON
+// struct tags are used for field names so both formats share one schema.
+type msgpackCodec struct{}
+
+// ContentType implements the Encoder and Decoder interfaces.
+func (msgpackCodec) ContentType() string {
+	return "application/msgpack"
+}
+
+// Encode implements the Encoder interface.
+func (msgpackCodec) Encode(w io.Writer, val interface{}) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(val)
+}
+
+// Decode implements the Decoder interface. Unknown fields are rejected.
+func (msgpackCodec) Decode(r io.Reader, val interface{}) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	dec.DisallowUnknownFields(true)
+	return dec.Decode(val)
+}
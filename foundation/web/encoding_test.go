@@ -0,0 +1,263 @@
+package web_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tullo/service/foundation/web"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+type item struct {
+	Name  string   `json:"name" validate:"required"`
+	Cost  int      `json:"cost" validate:"gte=0"`
+	Tags  []string `json:"tags"`
+	Notes *string  `json:"notes"`
+}
+
+// respondErrors is a minimal error handling middleware for the tests.
+func respondErrors(handler web.Handler) web.Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if err := handler(ctx, w, r); err != nil {
+			return web.RespondError(ctx, w, err)
+		}
+		return nil
+	}
+}
+
+func newApp() *web.App {
+	app := web.NewApp(make(chan os.Signal, 1), respondErrors)
+
+	items := []item{
+		{Name: "Comic Books", Cost: 25, Tags: []string{"paper"}},
+		{Name: "McDonalds Toys", Cost: 75},
+	}
+	app.Handle(http.MethodGet, "/items", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, items, http.StatusOK)
+	})
+	app.Handle(http.MethodGet, "/formulas", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		formulas := []item{
+			{Name: "=HYPERLINK(\"http://example.com\")", Cost: -5},
+			{Name: "+1", Cost: 1},
+			{Name: "-1", Cost: 2},
+			{Name: "@SUM(A1:A2)", Cost: 3},
+			{Name: "\tTab", Cost: 4},
+			{Name: "\rReturn", Cost: 5},
+			{Name: "Plain = text", Cost: 6},
+		}
+		return web.Respond(ctx, w, formulas, http.StatusOK)
+	})
+	app.Handle(http.MethodGet, "/sparse", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		sparse := []map[string]interface{}{{"name": "Comic Books"}}
+		return web.Respond(ctx, w, sparse, http.StatusOK)
+	})
+	app.Handle(http.MethodGet, "/nil", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, nil, http.StatusOK)
+	})
+	app.Handle(http.MethodPost, "/items", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		var it item
+		if err := web.Decode(r, &it); err != nil {
+			return err
+		}
+		return web.Respond(ctx, w, it, http.StatusCreated)
+	})
+
+	return app
+}
+
+func TestRespondNegotiation(t *testing.T) {
+	app := newApp()
+
+	t.Log("Given the need to respond in the media type the client accepts.")
+	{
+		tests := []struct {
+			accept      string
+			status      int
+			contentType string
+		}{
+			{"", http.StatusOK, "application/json"},
+			{"*/*", http.StatusOK, "application/json"},
+			{"text/csv", http.StatusOK, "text/csv"},
+			{"application/json;q=0.5, text/csv", http.StatusOK, "text/csv"},
+			{"application/msgpack", http.StatusOK, "application/msgpack"},
+			{"application/problem+json", http.StatusOK, "application/json"},
+			{"application/vnd.api+json", http.StatusOK, "application/json"},
+			{"image/png", http.StatusNotAcceptable, "application/json"},
+		}
+
+		for testID, tt := range tests {
+			t.Logf("\tTest %d:\tWhen accepting %q.", testID, tt.accept)
+			{
+				r := httptest.NewRequest(http.MethodGet, "/items", nil)
+				r.Header.Set("Accept", tt.accept)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if w.Code != tt.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d : %d", failed, testID, tt.status, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d.", success, testID, tt.status)
+
+				if got := w.Header().Get("Content-Type"); got != tt.contentType {
+					t.Fatalf("\t%s\tTest %d:\tShould receive content type %q : %q", failed, testID, tt.contentType, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive content type %q.", success, testID, tt.contentType)
+			}
+		}
+	}
+}
+
+func TestRespondUnsupported(t *testing.T) {
+	app := newApp()
+
+	t.Log("Given the need to respond with values the negotiated media type can't represent.")
+	{
+		tests := []struct {
+			path        string
+			accept      string
+			status      int
+			contentType string
+		}{
+			{"/sparse", "text/csv", http.StatusNotAcceptable, "application/json"},
+			{"/sparse", "text/csv, application/json;q=0.5", http.StatusOK, "application/json"},
+			{"/sparse", "text/csv, */*;q=0.1", http.StatusOK, "application/json"},
+			{"/nil", "text/csv", http.StatusNotAcceptable, "application/json"},
+			{"/nil", "", http.StatusOK, "application/json"},
+		}
+
+		for testID, tt := range tests {
+			t.Logf("\tTest %d:\tWhen requesting %s accepting %q.", testID, tt.path, tt.accept)
+			{
+				r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+				r.Header.Set("Accept", tt.accept)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if w.Code != tt.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d : %d", failed, testID, tt.status, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d.", success, testID, tt.status)
+
+				if got := w.Header().Get("Content-Type"); got != tt.contentType {
+					t.Fatalf("\t%s\tTest %d:\tShould receive content type %q : %q", failed, testID, tt.contentType, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive content type %q.", success, testID, tt.contentType)
+			}
+		}
+	}
+}
+
+func TestRespondCSV(t *testing.T) {
+	app := newApp()
+
+	t.Log("Given the need to export a collection as CSV.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen accepting text/csv.", testID)
+		{
+			r := httptest.NewRequest(http.MethodGet, "/items", nil)
+			r.Header.Set("Accept", "text/csv")
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			exp := "name,cost,tags,notes\n" +
+				"Comic Books,25,\"[\"\"paper\"\"]\",\n" +
+				"McDonalds Toys,75,null,\n"
+			if diff := cmp.Diff(exp, w.Body.String()); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the expected result.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen texts start like spreadsheet formulas.", testID)
+		{
+			r := httptest.NewRequest(http.MethodGet, "/formulas", nil)
+			r.Header.Set("Accept", "text/csv")
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			exp := "name,cost,tags,notes\n" +
+				"\"'=HYPERLINK(\"\"http://example.com\"\")\",-5,null,\n" +
+				"'+1,1,null,\n" +
+				"'-1,2,null,\n" +
+				"'@SUM(A1:A2),3,null,\n" +
+				"'\tTab,4,null,\n" +
+				"\"'\rReturn\",5,null,\n" +
+				"Plain = text,6,null,\n"
+			if diff := cmp.Diff(exp, w.Body.String()); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould quote the formulas. Diff:\n%s", failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould quote the formulas.", success, testID)
+		}
+	}
+}
+
+func TestDecodeFormats(t *testing.T) {
+	app := newApp()
+
+	mp, err := msgpack.Marshal(map[string]interface{}{"name": "Puzzles", "cost": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log("Given the need to decode request bodies by content type.")
+	{
+		tests := []struct {
+			contentType string
+			body        []byte
+			status      int
+		}{
+			{"", []byte(`{"name":"Puzzles","cost":10}`), http.StatusCreated},
+			{"application/json; charset=utf-8", []byte(`{"name":"Puzzles","cost":10}`), http.StatusCreated},
+			{"text/csv", []byte("name,cost\nPuzzles,10\n"), http.StatusCreated},
+			{"application/msgpack", mp, http.StatusCreated},
+			{"text/csv", []byte("name,cost\n,10\n"), http.StatusBadRequest},
+			{"text/csv", []byte("name,price\nPuzzles,10\n"), http.StatusBadRequest},
+			{"application/xml", []byte("<item/>"), http.StatusUnsupportedMediaType},
+		}
+
+		for testID, tt := range tests {
+			t.Logf("\tTest %d:\tWhen posting %q.", testID, tt.contentType)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/items", bytes.NewReader(tt.body))
+				if tt.contentType != "" {
+					r.Header.Set("Content-Type", tt.contentType)
+				}
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if w.Code != tt.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d : %d %s", failed, testID, tt.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d.", success, testID, tt.status)
+
+				if w.Code != http.StatusCreated {
+					continue
+				}
+
+				var got item
+				if err := json.NewDecoder(strings.NewReader(w.Body.String())).Decode(&got); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", failed, testID, err)
+				}
+				if diff := cmp.Diff(item{Name: "Puzzles", Cost: 10}, got); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get the expected result.", success, testID)
+			}
+		}
+	}
+}
@@ -5,13 +5,11 @@ import (
 	"syscall"
 
 	"github.com/pkg/errors"
+	"github.com/tullo/service/foundation/validate"
 )
 
 // FieldError is used to indicate an error with a specific request field.
-type FieldError struct {
-	Field string `json:"field"`
-	Error string `json:"error"`
-}
+type FieldError = validate.FieldError
 
 // ErrorResponse is the form used for API responses from failures in the API.
 type ErrorResponse struct {
@@ -19,18 +17,39 @@ type ErrorResponse struct {
 	Fields []FieldError `json:"fields,omitempty"`
 }
 
+// ProblemDetails is the form used for API responses from failures in the API
+// as defined by RFC 7807.
+type ProblemDetails struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code,omitempty"`
+	TraceID  string       `json:"trace_id,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
 // Error is used to pass an error during the request through the
-// application with web specific context.
+// application with web specific context. Type, Title and Code describe the
+// problem type when the error is reported as an RFC 7807 problem. They are
+// optional and default to a generic problem type for the status code.
+// Detail is the message shown to the client, typically translated into the
+// client's language. It defaults to the message of the wrapped error.
 type Error struct {
 	Err    error
 	Status int
 	Fields []FieldError
+	Type   string
+	Title  string
+	Code   string
+	Detail string
 }
 
 // NewRequestError wraps a provided error with an HTTP status code. This
 // function should be used when handlers encounter expected errors.
 func NewRequestError(err error, status int) error {
-	return &Error{err, status, nil}
+	return &Error{Err: err, Status: status}
 }
 
 // Error implements the error interface. It uses the default message of the
@@ -39,6 +58,14 @@ func (err *Error) Error() string {
 	return err.Err.Error()
 }
 
+// detail returns the message reported to the client.
+func (err *Error) detail() string {
+	if err.Detail != "" {
+		return err.Detail
+	}
+	return err.Err.Error()
+}
+
 // shutdown is a type used to help with the graceful termination of the service.
 type shutdown struct {
 	Message string
@@ -0,0 +1,156 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Fieldset is a set of names selected by a query parameter holding a comma
+// separated list. A nil Fieldset selects everything.
+type Fieldset map[string]bool
+
+// Has reports whether the name is selected.
+func (fs Fieldset) Has(name string) bool {
+	return fs == nil || fs[name]
+}
+
+// Any reports whether any of the names is selected.
+func (fs Fieldset) Any(names ...string) bool {
+	for _, name := range names {
+		if fs.Has(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFields reads the fields query parameter of a request selecting the
+// fields of the response. The fields are the JSON names of the fields of the
+// struct type of val. Unknown fields are reported as a 400. It returns nil
+// when the parameter is missing.
+func ParseFields(r *http.Request, val interface{}) (Fieldset, error) {
+	t := reflect.TypeOf(val)
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return parseList(r, "fields", jsonNames(t))
+}
+
+// ParseIncludes reads the include query parameter of a request selecting
+// the related resources to embed in the response. Unknown relations are
+// reported as a 400. It returns an empty set when the parameter is missing.
+func ParseIncludes(r *http.Request, relations ...string) (Fieldset, error) {
+	fs, err := parseList(r, "include", relations)
+	if fs == nil && err == nil {
+		fs = Fieldset{}
+	}
+	return fs, err
+}
+
+// parseList reads a query parameter holding a comma separated list of the
+// allowed names.
+func parseList(r *http.Request, param string, allowed []string) (Fieldset, error) {
+	s := r.URL.Query().Get(param)
+	if s == "" {
+		return nil, nil
+	}
+
+	fs := make(Fieldset)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(allowed, name) {
+			sorted := append([]string{}, allowed...)
+			sort.Strings(sorted)
+			err := fmt.Errorf("invalid %s %q, use one of: %s", param, name, strings.Join(sorted, ", "))
+			return nil, NewRequestError(err, http.StatusBadRequest)
+		}
+		fs[name] = true
+	}
+
+	return fs, nil
+}
+
+// Sparse returns the fields of a struct selected by the Fieldset as a map
+// keyed by their JSON names. Slices of structs are returned as slices of
+// maps. The result is encoded like the value by all registered encoders.
+func Sparse(val interface{}, fs Fieldset) interface{} {
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return val
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return sparse(v, fs)
+
+	case reflect.Slice:
+		docs := make([]map[string]interface{}, v.Len())
+		for i := range docs {
+			e := v.Index(i)
+			for e.Kind() == reflect.Pointer {
+				e = e.Elem()
+			}
+			docs[i] = sparse(e, fs)
+		}
+		return docs
+	}
+
+	return val
+}
+
+// sparse returns the selected fields of a struct keyed by their JSON names.
+func sparse(v reflect.Value, fs Fieldset) map[string]interface{} {
+	doc := make(map[string]interface{})
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := jsonName(t.Field(i))
+		if !ok || !fs.Has(name) {
+			continue
+		}
+		doc[name] = v.Field(i).Interface()
+	}
+
+	return doc
+}
+
+// jsonNames returns the JSON names of the fields of a struct type.
+func jsonNames(t reflect.Type) []string {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := jsonName(t.Field(i)); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// jsonName returns the JSON name of a struct field. It reports false for
+// fields which are not encoded.
+func jsonName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return f.Name, true
+	}
+	return name, true
+}
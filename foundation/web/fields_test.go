@@ -0,0 +1,92 @@
+package web_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tullo/service/foundation/web"
+)
+
+func TestSparse(t *testing.T) {
+	notes := "fragile"
+	items := []item{
+		{Name: "Comic Books", Cost: 25, Notes: &notes},
+		{Name: "McDonalds Toys", Cost: 75},
+	}
+
+	t.Log("Given the need to return a sparse fieldset.")
+	{
+		tests := []struct {
+			name   string
+			target string
+			exp    interface{}
+			status int
+		}{
+			{"all fields", "/items", items, 0},
+			{"some fields", "/items?fields=name,cost", []map[string]interface{}{{"name": "Comic Books", "cost": 25}, {"name": "McDonalds Toys", "cost": 75}}, 0},
+			{"blank fields", "/items?fields=name,,%20notes", []map[string]interface{}{{"name": "Comic Books", "notes": &notes}, {"name": "McDonalds Toys", "notes": (*string)(nil)}}, 0},
+			{"unknown field", "/items?fields=name,color", nil, http.StatusBadRequest},
+		}
+
+		for testID, tt := range tests {
+			t.Logf("\tTest %d:\tWhen requesting %s.", testID, tt.name)
+			{
+				r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+				fs, err := web.ParseFields(r, []item{})
+				if tt.status != 0 {
+					var werr *web.Error
+					if !errors.As(err, &werr) || werr.Status != tt.status {
+						t.Fatalf("\t%s\tTest %d:\tShould be rejected with a status code of %d : %v", failed, testID, tt.status, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould be rejected with a status code of %d.", success, testID, tt.status)
+					continue
+				}
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to parse the fields : %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to parse the fields.", success, testID)
+
+				got := interface{}(items)
+				if fs != nil {
+					got = web.Sparse(items, fs)
+				}
+				if diff := cmp.Diff(tt.exp, got); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get the expected result.", success, testID)
+			}
+		}
+	}
+
+	t.Log("Given the need to embed related resources.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen requesting relations.", testID)
+		{
+			r := httptest.NewRequest(http.MethodGet, "/items?include=owner", nil)
+			fs, err := web.ParseIncludes(r, "sales", "owner")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to parse the relations : %v", failed, testID, err)
+			}
+			if !fs.Has("owner") || fs.Has("sales") {
+				t.Fatalf("\t%s\tTest %d:\tShould only include the owner : %v", failed, testID, fs)
+			}
+			t.Logf("\t%s\tTest %d:\tShould only include the owner.", success, testID)
+
+			r = httptest.NewRequest(http.MethodGet, "/items", nil)
+			if fs, _ := web.ParseIncludes(r, "sales", "owner"); fs.Has("owner") || fs.Has("sales") {
+				t.Fatalf("\t%s\tTest %d:\tShould include nothing by default : %v", failed, testID, fs)
+			}
+			t.Logf("\t%s\tTest %d:\tShould include nothing by default.", success, testID)
+
+			r = httptest.NewRequest(http.MethodGet, "/items?include=manager", nil)
+			if _, err := web.ParseIncludes(r, "sales", "owner"); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject unknown relations.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject unknown relations.", success, testID)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package web
 
+import (
+	"context"
+	"net/http"
+)
+
 // Middleware is a function designed to run some code before and/or after
 // another Handler. It is designed to remove boilerplate or other concerns not
 // direct to any given Handler.
@@ -22,3 +27,28 @@ func wrapMiddleware(mw []Middleware, handler Handler) Handler {
 
 	return handler
 }
+
+// MaxBodyBytes limits the size of request bodies to n bytes. Reading beyond
+// the limit fails with an *http.MaxBytesError which Decode reports as a 413.
+// Applied as application middleware it sets the global limit, applied to a
+// single route it overrides the global limit for that route.
+func MaxBodyBytes(n int64) Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler Handler) Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if v, ok := ctx.Value(KeyValues).(*Values); ok {
+				v.maxBodyBytes = n
+			}
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
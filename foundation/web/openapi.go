@@ -0,0 +1,204 @@
+package web
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tullo/service/foundation/openapi"
+)
+
+// Doc documents a route registered with App.Handle. It is turned into an
+// operation of the OpenAPI document of the App.
+type Doc struct {
+	Summary     string
+	Description string
+	Tags        []string
+
+	// Auth marks routes requiring a bearer token.
+	Auth bool
+
+	// Params describes path and query parameters. Path parameters which are
+	// not described are documented as strings.
+	Params []openapi.Parameter
+
+	// Request is a value of the type of the request body. It's documented
+	// for all media types a request can be decoded from. Requests overrides
+	// the body per media type.
+	Request  interface{}
+	Requests map[string]interface{}
+
+	// Responses holds a value of the type of the response body by status
+	// code. A nil value documents a response without a body. Bodies of
+	// error responses are documented as problem documents.
+	Responses map[int]interface{}
+
+	// ContentType overrides the media types of successful responses, which
+	// default to all media types responses can be encoded in.
+	ContentType string
+}
+
+// route is a route registered with App.Handle.
+type route struct {
+	verb string
+	path string
+}
+
+// Document adds the documentation of a route registered with App.Handle.
+func (a *App) Document(verb string, path string, doc Doc) {
+	a.docs[verb+" "+path] = doc
+}
+
+// Undocumented returns the routes registered with App.Handle that have no
+// documentation.
+func (a *App) Undocumented() []string {
+	var routes []string
+	for _, rt := range a.routes {
+		if _, ok := a.docs[rt.verb+" "+rt.path]; !ok {
+			routes = append(routes, rt.verb+" "+rt.path)
+		}
+	}
+	return routes
+}
+
+// pathParam matches the parameters of a route pattern including an optional
+// regular expression.
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]+)?\}`)
+
+// OpenAPI returns the OpenAPI document describing the documented routes of
+// the App.
+func (a *App) OpenAPI(info openapi.Info) openapi.Document {
+	schemas := make(openapi.Schemas)
+	doc := openapi.Document{
+		OpenAPI: openapi.Version,
+		Info:    info,
+		Paths:   make(map[string]openapi.PathItem),
+		Components: openapi.Components{
+			Schemas: schemas,
+		},
+	}
+
+	problem := map[string]openapi.MediaType{
+		ProblemContentType: {Schema: schemas.Of(ProblemDetails{})},
+		"application/json": {Schema: schemas.Of(ErrorResponse{})},
+	}
+
+	for _, rt := range a.routes {
+		d, ok := a.docs[rt.verb+" "+rt.path]
+		if !ok {
+			continue
+		}
+
+		op := openapi.Operation{
+			OperationID: operationID(rt.verb, rt.path),
+			Summary:     d.Summary,
+			Description: d.Description,
+			Tags:        d.Tags,
+			Responses:   make(map[string]openapi.Response),
+		}
+
+		// Document the path parameters in the order of the pattern.
+		described := make(map[string]bool)
+		for _, p := range d.Params {
+			described[p.Name] = true
+		}
+		for _, m := range pathParam.FindAllStringSubmatch(rt.path, -1) {
+			if !described[m[1]] {
+				op.Parameters = append(op.Parameters, openapi.Parameter{
+					Name:     m[1],
+					In:       "path",
+					Required: true,
+					Schema:   &openapi.Schema{Type: "string"},
+				})
+			}
+		}
+		op.Parameters = append(op.Parameters, d.Params...)
+
+		if d.Request != nil || d.Requests != nil {
+			content := make(map[string]openapi.MediaType)
+			if d.Request != nil {
+				for _, ct := range decoderContentTypes() {
+					content[ct] = openapi.MediaType{Schema: schemas.Of(d.Request)}
+				}
+			}
+			for ct, body := range d.Requests {
+				content[ct] = openapi.MediaType{Schema: schemas.Of(body)}
+			}
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: content}
+		}
+
+		for status, body := range d.Responses {
+			resp := openapi.Response{Description: http.StatusText(status)}
+			switch {
+			case status >= http.StatusBadRequest:
+				resp.Content = problem
+			case body != nil:
+				resp.Content = make(map[string]openapi.MediaType)
+				cts := encoderContentTypes()
+				if d.ContentType != "" {
+					cts = []string{d.ContentType}
+				}
+				for _, ct := range cts {
+					resp.Content[ct] = openapi.MediaType{Schema: schemas.Of(body)}
+				}
+			}
+			op.Responses[strconv.Itoa(status)] = resp
+		}
+
+		if d.Auth {
+			op.Security = []openapi.SecurityRequirement{{"bearerAuth": {}}}
+			doc.Components.SecuritySchemes = map[string]openapi.SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			}
+		}
+
+		path := pathParam.ReplaceAllString(rt.path, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(openapi.PathItem)
+		}
+		doc.Paths[path][strings.ToLower(rt.verb)] = &op
+	}
+
+	return doc
+}
+
+// operationID derives a unique operation id from a route.
+func operationID(verb string, path string) string {
+	id := strings.ToLower(verb)
+	for _, seg := range strings.Split(path, "/") {
+		if m := pathParam.FindStringSubmatch(seg); m != nil {
+			seg = "by-" + m[1]
+		}
+		if seg != "" {
+			id += "-" + seg
+		}
+	}
+	return id
+}
+
+// encoderContentTypes returns the media types responses can be encoded in.
+func encoderContentTypes() []string {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+
+	cts := make([]string, 0, len(codecs.encoders))
+	for _, enc := range codecs.encoders {
+		cts = append(cts, enc.ContentType())
+	}
+	return cts
+}
+
+// decoderContentTypes returns the media types requests can be decoded from.
+func decoderContentTypes() []string {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+
+	cts := make([]string, 0, len(codecs.decoders))
+	for ct := range codecs.decoders {
+		cts = append(cts, ct)
+	}
+	sort.Strings(cts)
+	return cts
+}
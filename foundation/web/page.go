@@ -0,0 +1,122 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Bounds of the number of rows returned per page.
+const (
+	DefaultRowsPerPage = 20
+	MaxRowsPerPage     = 100
+)
+
+// PageQuery holds the pagination parameters of a request for a list.
+type PageQuery struct {
+	Page        int
+	RowsPerPage int
+
+	// Count asks for the total number of rows, which needs another query.
+	Count bool
+}
+
+// ParsePage reads the page, rows and count query parameters of a request.
+// Missing parameters get their defaults and the rows are capped at
+// MaxRowsPerPage. Malformed values are reported as a 400.
+func ParsePage(r *http.Request) (PageQuery, error) {
+	pq := PageQuery{
+		Page:        1,
+		RowsPerPage: DefaultRowsPerPage,
+	}
+
+	q := r.URL.Query()
+	if s := q.Get("page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return PageQuery{}, NewRequestError(fmt.Errorf("invalid page format: %s", s), http.StatusBadRequest)
+		}
+		pq.Page = n
+	}
+	if s := q.Get("rows"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return PageQuery{}, NewRequestError(fmt.Errorf("invalid rows format: %s", s), http.StatusBadRequest)
+		}
+		pq.RowsPerPage = min(n, MaxRowsPerPage)
+	}
+	if s := q.Get("count"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return PageQuery{}, NewRequestError(fmt.Errorf("invalid count format: %s", s), http.StatusBadRequest)
+		}
+		pq.Count = b
+	}
+
+	return pq, nil
+}
+
+// Links holds the URLs of the pages around a page of a list. Prev and Next
+// are omitted at the ends of the list, Last is only known with a total.
+type Links struct {
+	Self  string `json:"self"`
+	First string `json:"first"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+// Page is the envelope of a page of a list.
+type Page struct {
+	Data        interface{} `json:"data"`
+	Page        int         `json:"page"`
+	RowsPerPage int         `json:"rows_per_page"`
+	Total       *int        `json:"total,omitempty"`
+	Links       Links       `json:"links"`
+}
+
+// NewPage wraps the rows of a page in an envelope. The length is the number
+// of rows in data and total the number of rows in the list, or nil when they
+// were not counted. Without a total a full page is assumed to have a next
+// page.
+func NewPage(r *http.Request, pq PageQuery, data interface{}, length int, total *int) Page {
+	p := Page{
+		Data:        data,
+		Page:        pq.Page,
+		RowsPerPage: pq.RowsPerPage,
+		Total:       total,
+		Links: Links{
+			Self:  pageURL(r, pq.Page, pq.RowsPerPage),
+			First: pageURL(r, 1, pq.RowsPerPage),
+		},
+	}
+
+	if pq.Page > 1 {
+		p.Links.Prev = pageURL(r, pq.Page-1, pq.RowsPerPage)
+	}
+
+	switch {
+	case total != nil:
+		last := max(1, (*total+pq.RowsPerPage-1)/pq.RowsPerPage)
+		p.Links.Last = pageURL(r, last, pq.RowsPerPage)
+		if pq.Page < last {
+			p.Links.Next = pageURL(r, pq.Page+1, pq.RowsPerPage)
+		}
+	case length == pq.RowsPerPage:
+		p.Links.Next = pageURL(r, pq.Page+1, pq.RowsPerPage)
+	}
+
+	return p
+}
+
+// pageURL returns the URL of the request for another page. The other query
+// parameters are kept.
+func pageURL(r *http.Request, page int, rows int) string {
+	q := r.URL.Query()
+	q.Set("page", strconv.Itoa(page))
+	q.Set("rows", strconv.Itoa(rows))
+
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	return u.String()
+}
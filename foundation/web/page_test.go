@@ -0,0 +1,61 @@
+package web_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tullo/service/foundation/web"
+)
+
+func TestPage(t *testing.T) {
+	total := func(n int) *int { return &n }
+
+	t.Log("Given the need to paginate a list.")
+	{
+		tests := []struct {
+			name   string
+			target string
+			length int
+			total  *int
+			exp    web.Links
+			status int
+		}{
+			{"default page", "/items", 20, nil, web.Links{Self: "/items?page=1&rows=20", First: "/items?page=1&rows=20", Next: "/items?page=2&rows=20"}, 0},
+			{"partial page", "/items?page=2&rows=5&sort=name", 3, nil, web.Links{Self: "/items?page=2&rows=5&sort=name", First: "/items?page=1&rows=5&sort=name", Prev: "/items?page=1&rows=5&sort=name"}, 0},
+			{"counted page", "/items?page=2&rows=5&count=true", 5, total(12), web.Links{Self: "/items?count=true&page=2&rows=5", First: "/items?count=true&page=1&rows=5", Prev: "/items?count=true&page=1&rows=5", Next: "/items?count=true&page=3&rows=5", Last: "/items?count=true&page=3&rows=5"}, 0},
+			{"empty list", "/items?count=true", 0, total(0), web.Links{Self: "/items?count=true&page=1&rows=20", First: "/items?count=true&page=1&rows=20", Last: "/items?count=true&page=1&rows=20"}, 0},
+			{"capped rows", "/items?rows=1000", 0, nil, web.Links{Self: "/items?page=1&rows=100", First: "/items?page=1&rows=100"}, 0},
+			{"invalid page", "/items?page=0", 0, nil, web.Links{}, http.StatusBadRequest},
+			{"invalid count", "/items?count=maybe", 0, nil, web.Links{}, http.StatusBadRequest},
+		}
+
+		for testID, tt := range tests {
+			t.Logf("\tTest %d:\tWhen requesting a %s.", testID, tt.name)
+			{
+				r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+				pq, err := web.ParsePage(r)
+				if tt.status != 0 {
+					var werr *web.Error
+					if !errors.As(err, &werr) || werr.Status != tt.status {
+						t.Fatalf("\t%s\tTest %d:\tShould be rejected with a status code of %d : %v", failed, testID, tt.status, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould be rejected with a status code of %d.", success, testID, tt.status)
+					continue
+				}
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to parse the page : %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to parse the page.", success, testID)
+
+				p := web.NewPage(r, pq, nil, tt.length, tt.total)
+				if diff := cmp.Diff(tt.exp, p.Links); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get the expected links. Diff:\n%s", failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get the expected links.", success, testID)
+			}
+		}
+	}
+}
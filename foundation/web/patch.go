@@ -0,0 +1,91 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// Media types of the supported patch documents.
+const (
+	MergePatchContentType = "application/merge-patch+json" // RFC 7396
+	JSONPatchContentType  = "application/json-patch+json"  // RFC 6902
+)
+
+// PatchOperation is a single operation of a JSON Patch document. It documents
+// the format of JSON Patch requests.
+type PatchOperation struct {
+	Op    string      `json:"op" validate:"required,oneof=add remove replace move copy test"`
+	Path  string      `json:"path" validate:"required"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Patch applies the patch in the body of an HTTP request to the provided
+// value. The value is the current state of the resource expressed as the
+// document clients patch. JSON Merge Patch and JSON Patch documents are
+// supported, other media types are rejected with a 415.
+//
+// The patched document replaces the value. It must not contain fields unknown
+// to the value and is checked for validation tags. A JSON Patch test operation
+// that fails is reported as a 409, any other patch that can't be applied as a
+// 422.
+func Patch(r *http.Request, val interface{}) error {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mt != MergePatchContentType && mt != JSONPatchContentType) {
+		err := fmt.Errorf("unsupported media type: %s, use %s or %s", r.Header.Get("Content-Type"), MergePatchContentType, JSONPatchContentType)
+		return NewRequestError(err, http.StatusUnsupportedMediaType)
+	}
+
+	body, err := ReadBody(r)
+	if err != nil {
+		return err
+	}
+	if len(body) == 0 {
+		return NewRequestError(errors.New("request body must not be empty"), http.StatusBadRequest)
+	}
+
+	doc, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("encoding document: %w", err)
+	}
+
+	var patched []byte
+	switch mt {
+	case MergePatchContentType:
+		patched, err = jsonpatch.MergePatch(doc, body)
+		if errors.Is(err, jsonpatch.ErrBadJSONPatch) {
+			return NewRequestError(errors.New("request body contains malformed JSON"), http.StatusBadRequest)
+		}
+
+	case JSONPatchContentType:
+		var patch jsonpatch.Patch
+		patch, err = jsonpatch.DecodePatch(body)
+		if err != nil {
+			return NewRequestError(fmt.Errorf("request body contains a malformed JSON patch: %w", err), http.StatusBadRequest)
+		}
+		patched, err = patch.Apply(doc)
+	}
+	switch {
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		return NewRequestError(fmt.Errorf("applying patch: %w", err), http.StatusConflict)
+	case err != nil:
+		return NewRequestError(fmt.Errorf("applying patch: %w", err), http.StatusUnprocessableEntity)
+	}
+
+	// Replace the value with the patched document. Fields removed by the
+	// patch are cleared.
+	rv := reflect.ValueOf(val).Elem()
+	rv.Set(reflect.Zero(rv.Type()))
+	if err := (jsonCodec{}).Decode(bytes.NewReader(patched), val); err != nil {
+		return NewRequestError(fmt.Errorf("patched document is invalid: %w", err), http.StatusUnprocessableEntity)
+	}
+
+	return check(r, val)
+}
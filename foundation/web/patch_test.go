@@ -0,0 +1,79 @@
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tullo/service/foundation/web"
+)
+
+func TestPatch(t *testing.T) {
+	app := web.NewApp(make(chan os.Signal, 1), respondErrors)
+	app.Handle(http.MethodPatch, "/items", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		notes := "fragile"
+		it := item{Name: "Comic Books", Cost: 25, Tags: []string{"paper"}, Notes: &notes}
+		if err := web.Patch(r, &it); err != nil {
+			return err
+		}
+		return web.Respond(ctx, w, it, http.StatusOK)
+	})
+
+	t.Log("Given the need to patch a document.")
+	{
+		tests := []struct {
+			name        string
+			contentType string
+			body        string
+			status      int
+			exp         item
+		}{
+			{"merge patch", web.MergePatchContentType, `{"cost":30,"notes":null}`, http.StatusOK, item{Name: "Comic Books", Cost: 30, Tags: []string{"paper"}}},
+			{"json patch", web.JSONPatchContentType, `[{"op":"add","path":"/tags/-","value":"books"},{"op":"remove","path":"/notes"}]`, http.StatusOK, item{Name: "Comic Books", Cost: 25, Tags: []string{"paper", "books"}}},
+			{"passing test", web.JSONPatchContentType, `[{"op":"test","path":"/cost","value":25},{"op":"replace","path":"/cost","value":20}]`, http.StatusOK, item{Name: "Comic Books", Cost: 20, Tags: []string{"paper"}, Notes: ptr("fragile")}},
+			{"failing test", web.JSONPatchContentType, `[{"op":"test","path":"/cost","value":10},{"op":"replace","path":"/cost","value":20}]`, http.StatusConflict, item{}},
+			{"missing path", web.JSONPatchContentType, `[{"op":"replace","path":"/color","value":"red"}]`, http.StatusUnprocessableEntity, item{}},
+			{"unknown field", web.MergePatchContentType, `{"color":"red"}`, http.StatusUnprocessableEntity, item{}},
+			{"validation", web.MergePatchContentType, `{"name":null}`, http.StatusBadRequest, item{}},
+			{"malformed", web.JSONPatchContentType, `{"op":"add"}`, http.StatusBadRequest, item{}},
+			{"media type", "application/json", `{"cost":30}`, http.StatusUnsupportedMediaType, item{}},
+		}
+
+		for testID, tt := range tests {
+			t.Logf("\tTest %d:\tWhen sending a %s.", testID, tt.name)
+			{
+				r := httptest.NewRequest(http.MethodPatch, "/items", strings.NewReader(tt.body))
+				r.Header.Set("Content-Type", tt.contentType)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if w.Code != tt.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d : %d %s", failed, testID, tt.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d.", success, testID, tt.status)
+
+				if w.Code != http.StatusOK {
+					continue
+				}
+
+				var got item
+				if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response : %v", failed, testID, err)
+				}
+				if diff := cmp.Diff(tt.exp, got); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get the expected result.", success, testID)
+			}
+		}
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -1,87 +1,56 @@
 package web
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
-	"reflect"
-	"strings"
 
 	"github.com/go-chi/chi/v5"
-	en "github.com/go-playground/locales/en"
-	ut "github.com/go-playground/universal-translator"
-	validator "github.com/go-playground/validator/v10"
-	en_translations "github.com/go-playground/validator/v10/translations/en"
+	"github.com/tullo/service/foundation/i18n"
+	"github.com/tullo/service/foundation/validate"
 )
 
-// validate holds the settings and caches for validating request struct values.
-var validate *validator.Validate
-
-// translator is a cache of locale and translation information.
-var translator *ut.UniversalTranslator
-
-func init() {
-
-	// Instantiate the validator for use.
-	validate = validator.New()
-
-	// Instantiate the english locale for the validator library.
-	enLocale := en.New()
-
-	// Create a value using English as the fallback locale (first argument).
-	// Provide one or more arguments for additional supported locales.
-	translator = ut.New(enLocale, enLocale)
-
-	// Register the english error messages for validation errors.
-	lang, _ := translator.GetTranslator("en")
-	en_translations.RegisterDefaultTranslations(validate, lang)
-
-	// Use JSON tag names for errors instead of Go struct names.
-	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
-		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
-		if name == "-" {
-			return ""
-		}
-		return name
-	})
-}
-
-// Decode reads the body of an HTTP request looking for a JSON document. The
-// body is decoded into the provided value.
+// Decode reads the body of an HTTP request using the decoder registered for
+// its Content-Type. A request without a Content-Type is decoded as JSON. The
+// body is decoded into the provided value. Unsupported media types are
+// rejected with a 415 and bodies exceeding the limit set by MaxBodyBytes with
+// a 413.
 //
 // If the provided value is a struct then it is checked for validation tags.
+// Validation errors are reported in the language negotiated from the
+// Accept-Language header.
 func Decode(r *http.Request, val interface{}) error {
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-	if err := decoder.Decode(val); err != nil {
-		return NewRequestError(err, http.StatusBadRequest)
+	decoder, ok := lookupDecoder(r.Header.Get("Content-Type"))
+	if !ok {
+		err := fmt.Errorf("unsupported media type: %s", r.Header.Get("Content-Type"))
+		return NewRequestError(err, http.StatusUnsupportedMediaType)
+	}
+	if err := decoder.Decode(r.Body, val); err != nil {
+		return bodyError(err)
 	}
 
-	if err := validate.Struct(val); err != nil {
+	return check(r, val)
+}
+
+// check validates the provided value against its validation tags. Failures
+// are reported in the language negotiated from the Accept-Language header.
+func check(r *http.Request, val interface{}) error {
+	if err := validate.Check(val); err != nil {
 
 		// Use a type assertion to get the real error value.
-		verrors, ok := err.(validator.ValidationErrors)
-		if !ok {
+		var verr *validate.Errors
+		if !errors.As(err, &verr) {
 			return err
 		}
 
-		// lang controls the language of the error messages. You could look at the
-		// Accept-Language header if you intend to support multiple languages.
-		lang, _ := translator.GetTranslator("en")
-
-		var fields []FieldError
-		for _, verror := range verrors {
-			field := FieldError{
-				Field: verror.Field(),
-				Error: verror.Translate(lang),
-			}
-			fields = append(fields, field)
-		}
+		// lang controls the language of the error messages.
+		lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
 
 		return &Error{
-			Err:    errors.New("field validation error"),
+			Err:    verr,
 			Status: http.StatusBadRequest,
-			Fields: fields,
+			Fields: verr.Fields(lang),
+			Detail: verr.Summary(lang),
 		}
 	}
 
@@ -0,0 +1,80 @@
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tullo/service/foundation/web"
+)
+
+// newItem decodes an item and echoes it back.
+func newItem(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var it item
+	if err := web.Decode(r, &it); err != nil {
+		return err
+	}
+	return web.Respond(ctx, w, it, http.StatusCreated)
+}
+
+func TestDecodeLimits(t *testing.T) {
+	app := web.NewApp(make(chan os.Signal, 1), web.MaxBodyBytes(64), respondErrors)
+	app.Handle(http.MethodPost, "/items", newItem)
+	app.Handle(http.MethodPost, "/items/bulk", newItem, web.MaxBodyBytes(1<<10))
+
+	t.Log("Given the need to reject malformed request bodies.")
+	{
+		long := `{"name":"` + strings.Repeat("x", 100) + `","cost":10}`
+
+		tests := []struct {
+			name        string
+			target      string
+			contentType string
+			body        string
+			status      int
+			error       string
+		}{
+			{"valid", "/items", "application/json", `{"name":"Puzzles","cost":10}`, http.StatusCreated, ""},
+			{"oversized", "/items", "application/json", long, http.StatusRequestEntityTooLarge, "request body must not exceed 64 bytes"},
+			{"route limit", "/items/bulk", "application/json", long, http.StatusCreated, ""},
+			{"media type", "/items", "text/plain", `{"name":"Puzzles","cost":10}`, http.StatusUnsupportedMediaType, "unsupported media type: text/plain"},
+			{"empty", "/items", "application/json", ``, http.StatusBadRequest, "request body must not be empty"},
+			{"trailing", "/items", "application/json", `{"name":"Puzzles","cost":10} {}`, http.StatusBadRequest, "request body must only contain a single JSON document"},
+			{"syntax", "/items", "application/json", "{\n\"name\":\"Puzzles\",\n\"cost\":10,}", http.StatusBadRequest, "request body contains malformed JSON at line 3, column 11: invalid character '}' looking for beginning of object key string"},
+			{"type", "/items", "application/json", `{"name":"Puzzles","cost":"ten"}`, http.StatusBadRequest, `request body contains an invalid value for field "cost" at line 1, column 31: expected int`},
+			{"truncated", "/items", "application/json", `{"name":"Puz`, http.StatusBadRequest, "request body contains malformed JSON at line 1, column 13: unexpected end of input"},
+		}
+
+		for testID, tt := range tests {
+			t.Logf("\tTest %d:\tWhen posting a %s body.", testID, tt.name)
+			{
+				r := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+				r.Header.Set("Content-Type", tt.contentType)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if w.Code != tt.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d : %d %s", failed, testID, tt.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d.", success, testID, tt.status)
+
+				if tt.error == "" {
+					continue
+				}
+
+				var got web.ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to unmarshal the response to an error type : %v", failed, testID, err)
+				}
+				if got.Error != tt.error {
+					t.Fatalf("\t%s\tTest %d:\tShould get the expected error : got %q exp %q", failed, testID, got.Error, tt.error)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get the expected error.", success, testID)
+			}
+		}
+	}
+}
@@ -1,16 +1,39 @@
 package web
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel"
 )
 
-// Respond converts a Go value to JSON and sends it to the client.
+// ProblemContentType is the media type of RFC 7807 problem responses.
+const ProblemContentType = "application/problem+json"
+
+// Respond converts a Go value into the media type negotiated with the client
+// and sends it to the client. JSON is used if no media type was negotiated.
+// When the negotiated media type can't represent the value, the next one the
+// client accepts is used, or the request fails with a 406.
 func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statusCode int) error {
+	var enc Encoder = jsonCodec{}
+	if v, ok := ctx.Value(KeyValues).(*Values); ok && v.encoder != nil {
+		enc = v.encoder
+		if statusCode != http.StatusNoContent && !canEncode(enc, data) {
+			if enc, ok = negotiateValueEncoder(v.accept, data); !ok {
+				err := fmt.Errorf("the response can't be represented in the requested media types: %s", v.accept)
+				return NewRequestError(err, http.StatusNotAcceptable)
+			}
+		}
+	}
+	return respond(ctx, w, data, statusCode, enc, enc.ContentType())
+}
+
+// respond converts a Go value using the encoder and sends it to the client
+// with the specified content type.
+func respond(ctx context.Context, w http.ResponseWriter, data interface{}, statusCode int, enc Encoder, contentType string) error {
 	ctx, span := otel.Tracer(name).Start(ctx, "foundation.web.respond")
 	defer span.End()
 
@@ -25,37 +48,38 @@ func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statu
 		return nil
 	}
 
-	// Convert the response value to JSON.
-	jsonData, err := json.Marshal(data)
-	if err != nil {
+	// Encode the response value.
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, data); err != nil {
 		return err
 	}
 
-	// Set the content type and headers once we know marshaling has succeeded.
-	w.Header().Set("Content-Type", "application/json")
+	// Set the content type and headers once we know encoding has succeeded.
+	w.Header().Set("Content-Type", contentType)
 
 	// Write the status code to the response.
 	w.WriteHeader(statusCode)
 
 	// Send the result back to the client.
-	if _, err := w.Write(jsonData); err != nil {
+	if _, err := w.Write(buf.Bytes()); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// RespondError sends an error response back to the client.
+// RespondError sends an error response back to the client in the legacy
+// `{error, fields}` form. Error responses are always JSON.
 func RespondError(ctx context.Context, w http.ResponseWriter, err error) error {
 
 	// If the error was of the type *Error, the handler has
 	// a specific status code and error to return.
 	if webErr, ok := errors.Cause(err).(*Error); ok {
 		er := ErrorResponse{
-			Error:  webErr.Err.Error(),
+			Error:  webErr.detail(),
 			Fields: webErr.Fields,
 		}
-		if err := Respond(ctx, w, er, webErr.Status); err != nil {
+		if err := respond(ctx, w, er, webErr.Status, jsonCodec{}, "application/json"); err != nil {
 			return err
 		}
 		return nil
@@ -65,8 +89,41 @@ func RespondError(ctx context.Context, w http.ResponseWriter, err error) error {
 	er := ErrorResponse{
 		Error: http.StatusText(http.StatusInternalServerError),
 	}
-	if err := Respond(ctx, w, er, http.StatusInternalServerError); err != nil {
+	if err := respond(ctx, w, er, http.StatusInternalServerError, jsonCodec{}, "application/json"); err != nil {
 		return err
 	}
 	return nil
 }
+
+// RespondProblem sends an error response back to the client as an RFC 7807
+// problem. The instance identifies the request the problem occurred on.
+func RespondProblem(ctx context.Context, w http.ResponseWriter, err error, instance string) error {
+	pd := ProblemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(http.StatusInternalServerError),
+		Status:   http.StatusInternalServerError,
+		Instance: instance,
+	}
+	if v, ok := ctx.Value(KeyValues).(*Values); ok {
+		pd.TraceID = v.TraceID
+	}
+
+	// If the error was of the type *Error, the handler has a specific
+	// problem to report. If not, the handler sent any arbitrary error
+	// value so the details are not shared with the client.
+	if webErr, ok := errors.Cause(err).(*Error); ok {
+		pd.Status = webErr.Status
+		pd.Title = http.StatusText(webErr.Status)
+		pd.Detail = webErr.detail()
+		pd.Code = webErr.Code
+		pd.Errors = webErr.Fields
+		if webErr.Type != "" {
+			pd.Type = webErr.Type
+		}
+		if webErr.Title != "" {
+			pd.Title = webErr.Title
+		}
+	}
+
+	return respond(ctx, w, pd, pd.Status, jsonCodec{}, ProblemContentType)
+}
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EventStreamContentType is the media type of Server-Sent Events streams.
+const EventStreamContentType = "text/event-stream"
+
+// EventStream sends Server-Sent Events to the client. Each event is flushed
+// to the client as soon as it is written.
+//
+// The write timeout of the server applies to each write instead of the whole
+// response, so the stream may stay open for as long as the client listens.
+type EventStream struct {
+	ctx          context.Context
+	span         trace.Span
+	w            http.ResponseWriter
+	rc           *http.ResponseController
+	writeTimeout time.Duration
+}
+
+// NewEventStream writes the response headers and returns an EventStream for
+// sending events. Every write must complete within the write timeout, zero
+// means writes don't time out. Close must be called once the stream ends.
+func NewEventStream(ctx context.Context, w http.ResponseWriter, writeTimeout time.Duration) *EventStream {
+	ctx, span := otel.Tracer(name).Start(ctx, "foundation.web.eventstream")
+
+	// Set the status code for the request logger middleware.
+	if v, ok := ctx.Value(KeyValues).(*Values); ok {
+		v.StatusCode = http.StatusOK
+	}
+
+	s := EventStream{
+		ctx:          ctx,
+		span:         span,
+		w:            w,
+		rc:           http.NewResponseController(w),
+		writeTimeout: writeTimeout,
+	}
+
+	s.extendDeadline()
+
+	w.Header().Set("Content-Type", EventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	return &s
+}
+
+// Send writes an event of the type with the value encoded as JSON as its
+// data and flushes it to the client. The ID is what the client sends in the
+// Last-Event-ID header when it reconnects. It returns an error once the client
+// has disconnected so the producer of the events can stop.
+func (s *EventStream) Send(id string, typ string, val interface{}) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if id != "" {
+		b.WriteString("id: " + id + "\n")
+	}
+	if typ != "" {
+		b.WriteString("event: " + typ + "\n")
+	}
+	writeData(&b, data)
+
+	return s.write(b.Bytes())
+}
+
+// Ping writes a comment line. Sending it periodically keeps idle connections
+// from being closed by proxies and detects disconnected clients.
+func (s *EventStream) Ping() error {
+	return s.write([]byte(":\n\n"))
+}
+
+// Close ends the stream.
+func (s *EventStream) Close() {
+	s.span.End()
+}
+
+// write sends the bytes to the client within the write timeout.
+func (s *EventStream) write(p []byte) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	s.extendDeadline()
+	if _, err := s.w.Write(p); err != nil {
+		return err
+	}
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// extendDeadline gives the next write the full write timeout.
+func (s *EventStream) extendDeadline() {
+	var deadline time.Time
+	if s.writeTimeout > 0 {
+		deadline = time.Now().Add(s.writeTimeout)
+	}
+
+	// Recorders used in tests don't support deadlines.
+	_ = s.rc.SetWriteDeadline(deadline)
+}
+
+// writeData writes the data field of an event, one field per line of data.
+func writeData(b *bytes.Buffer, data []byte) {
+	for line := range strings.SplitSeq(string(data), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+}
+
+// =============================================================================
+
+// eventStreamCodec encodes a response as a single event. It lets requests of
+// clients which only accept text/event-stream reach the handlers of event
+// streams.
+type eventStreamCodec struct{}
+
+// ContentType implements the Encoder interface.
+func (eventStreamCodec) ContentType() string {
+	return EventStreamContentType
+}
+
+// Encode implements the Encoder interface.
+func (eventStreamCodec) Encode(w io.Writer, val interface{}) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	writeData(&b, data)
+	_, err = w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,90 @@
+package web_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tullo/service/foundation/web"
+)
+
+func TestEventStream(t *testing.T) {
+	app := newApp()
+
+	app.Handle(http.MethodGet, "/items/events", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		stream := web.NewEventStream(ctx, w, 100*time.Millisecond)
+		defer stream.Close()
+
+		if err := stream.Send("1", "item.created", item{Name: "Comic Books", Cost: 25}); err != nil {
+			return err
+		}
+
+		// Outlast the write timeout of the server between two events.
+		time.Sleep(300 * time.Millisecond)
+
+		if err := stream.Ping(); err != nil {
+			return err
+		}
+		return stream.Send("2", "item.created", item{Name: "Puzzles", Cost: 10})
+	})
+
+	t.Log("Given the need to push events to clients.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen streaming events for longer than the write timeout.", testID)
+		{
+			srv := httptest.NewUnstartedServer(app)
+			srv.Config.WriteTimeout = 100 * time.Millisecond
+			srv.Start()
+			defer srv.Close()
+
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/items/events", nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a request : %v", failed, testID, err)
+			}
+			req.Header.Set("Accept", web.EventStreamContentType)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to open the stream : %v", failed, testID, err)
+			}
+			defer resp.Body.Close()
+
+			if got := resp.Header.Get("Content-Type"); got != web.EventStreamContentType {
+				t.Fatalf("\t%s\tTest %d:\tShould receive content type %q : %q", failed, testID, web.EventStreamContentType, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive content type %q.", success, testID, web.EventStreamContentType)
+
+			var lines []string
+			sc := bufio.NewScanner(resp.Body)
+			for sc.Scan() {
+				lines = append(lines, sc.Text())
+			}
+			if err := sc.Err(); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read the stream : %v", failed, testID, err)
+			}
+
+			exp := []string{
+				"id: 1",
+				"event: item.created",
+				`data: {"name":"Comic Books","cost":25,"tags":null,"notes":null}`,
+				"",
+				":",
+				"",
+				"id: 2",
+				"event: item.created",
+				`data: {"name":"Puzzles","cost":10,"tags":null,"notes":null}`,
+				"",
+			}
+			if diff := cmp.Diff(exp, lines); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould receive all events. Diff:\n%s", failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive all events.", success, testID)
+		}
+
+	}
+}
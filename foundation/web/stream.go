@@ -0,0 +1,95 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NDJSONContentType is the media type of newline delimited JSON streams.
+const NDJSONContentType = "application/x-ndjson"
+
+// Stream sends a sequence of values to the client as newline delimited JSON.
+// Each value is flushed to the client as soon as it is written.
+//
+// The write timeout of the server applies to each value instead of the whole
+// response, so large exports aren't cut off.
+type Stream struct {
+	ctx          context.Context
+	span         trace.Span
+	w            http.ResponseWriter
+	rc           *http.ResponseController
+	enc          *json.Encoder
+	writeTimeout time.Duration
+}
+
+// NewStream writes the response headers and returns a Stream for sending
+// values. The status code is recorded for the request logger middleware.
+// Every value must be written within the write timeout, zero means writes
+// don't time out. Close must be called once all values have been sent.
+//
+// Once the headers are written an error can't be reported to the client
+// anymore, it can only end the stream.
+func NewStream(ctx context.Context, w http.ResponseWriter, statusCode int, writeTimeout time.Duration) *Stream {
+	ctx, span := otel.Tracer(name).Start(ctx, "foundation.web.stream")
+
+	// Set the status code for the request logger middleware.
+	if v, ok := ctx.Value(KeyValues).(*Values); ok {
+		v.StatusCode = statusCode
+	}
+
+	s := Stream{
+		ctx:          ctx,
+		span:         span,
+		w:            w,
+		rc:           http.NewResponseController(w),
+		enc:          json.NewEncoder(w),
+		writeTimeout: writeTimeout,
+	}
+
+	s.extendDeadline()
+
+	w.Header().Set("Content-Type", NDJSONContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+
+	return &s
+}
+
+// Send writes a value as a single line and flushes it to the client. It
+// returns an error once the client has disconnected so the producer of the
+// values can stop.
+func (s *Stream) Send(val interface{}) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	s.extendDeadline()
+	if err := s.enc.Encode(val); err != nil {
+		return err
+	}
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// Close ends the stream.
+func (s *Stream) Close() {
+	s.span.End()
+}
+
+// extendDeadline gives the next value the full write timeout.
+func (s *Stream) extendDeadline() {
+	var deadline time.Time
+	if s.writeTimeout > 0 {
+		deadline = time.Now().Add(s.writeTimeout)
+	}
+
+	// Recorders used in tests don't support deadlines.
+	_ = s.rc.SetWriteDeadline(deadline)
+}
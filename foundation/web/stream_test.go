@@ -0,0 +1,120 @@
+package web_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tullo/service/foundation/web"
+)
+
+func TestStream(t *testing.T) {
+	app := newApp()
+
+	var status int
+	record := func(handler web.Handler) web.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			err := handler(ctx, w, r)
+			status = ctx.Value(web.KeyValues).(*web.Values).StatusCode
+			return err
+		}
+	}
+
+	app.Handle(http.MethodGet, "/items/export", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		stream := web.NewStream(ctx, w, http.StatusOK, time.Second)
+		defer stream.Close()
+
+		for _, it := range []item{{Name: "Comic Books", Cost: 25}, {Name: "Puzzles", Cost: 10}} {
+			if err := stream.Send(it); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, record)
+
+	t.Log("Given the need to stream a collection as newline delimited JSON.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen exporting all items.", testID)
+		{
+			r := httptest.NewRequest(http.MethodGet, "/items/export", nil)
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Type"); got != web.NDJSONContentType {
+				t.Fatalf("\t%s\tTest %d:\tShould receive content type %q : %q", failed, testID, web.NDJSONContentType, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive content type %q.", success, testID, web.NDJSONContentType)
+
+			if status != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould record a status code of 200 : %d", failed, testID, status)
+			}
+			t.Logf("\t%s\tTest %d:\tShould record a status code of 200.", success, testID)
+
+			exp := `{"name":"Comic Books","cost":25,"tags":null,"notes":null}` + "\n" +
+				`{"name":"Puzzles","cost":10,"tags":null,"notes":null}` + "\n"
+			if diff := cmp.Diff(exp, w.Body.String()); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get the expected result. Diff:\n%s", failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the expected result.", success, testID)
+
+			if !w.Flushed {
+				t.Fatalf("\t%s\tTest %d:\tShould flush the rows to the client.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould flush the rows to the client.", success, testID)
+		}
+	}
+}
+
+func TestStreamWriteTimeout(t *testing.T) {
+	app := newApp()
+
+	const writeTimeout = 200 * time.Millisecond
+	app.Handle(http.MethodGet, "/items/slow", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		stream := web.NewStream(ctx, w, http.StatusOK, writeTimeout)
+		defer stream.Close()
+
+		for range 4 {
+			time.Sleep(writeTimeout / 2)
+			if err := stream.Send(item{Name: "Puzzles", Cost: 10}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	srv := httptest.NewUnstartedServer(app)
+	srv.Config.WriteTimeout = writeTimeout
+	srv.Start()
+	defer srv.Close()
+
+	t.Log("Given the need to stream for longer than the write timeout of the server.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the rows take twice the write timeout.", testID)
+		{
+			resp, err := http.Get(srv.URL + "/items/slow")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to make the request : %v", failed, testID, err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read the whole stream : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to read the whole stream.", success, testID)
+
+			row := `{"name":"Puzzles","cost":10,"tags":null,"notes":null}` + "\n"
+			exp := row + row + row + row
+			if diff := cmp.Diff(exp, string(body)); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get every row. Diff:\n%s", failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get every row.", success, testID)
+		}
+	}
+}
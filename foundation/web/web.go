@@ -3,6 +3,7 @@ package web
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"syscall"
@@ -26,6 +27,14 @@ type Values struct {
 	TraceID    string
 	Now        time.Time
 	StatusCode int
+
+	// encoder is the response encoder negotiated from the Accept header.
+	encoder Encoder
+	accept  string
+
+	// maxBodyBytes is the number of bytes that may be read from the request
+	// body. Zero means the body is not limited.
+	maxBodyBytes int64
 }
 
 // A Handler is a type that handles an http request within our own little mini
@@ -47,6 +56,8 @@ type App struct {
 	otmux    http.Handler
 	shutdown chan os.Signal
 	mw       []Middleware
+	routes   []route
+	docs     map[string]Doc
 }
 
 // NewApp creates an App value that handle a set of routes for the application.
@@ -65,6 +76,7 @@ func NewApp(shutdown chan os.Signal, mw ...Middleware) *App {
 		otmux:    otelhttp.NewHandler(mux, "request"),
 		shutdown: shutdown,
 		mw:       mw,
+		docs:     make(map[string]Doc),
 	}
 }
 
@@ -113,6 +125,10 @@ func (a *App) handle(debug bool, verb string, path string, handler Handler, mw .
 	// Add the application's general middleware to the handler chain.
 	handler = wrapMiddleware(a.mw, handler)
 
+	// The chain to execute when the response can't be encoded in any of the
+	// media types the client accepts.
+	notAcceptable := wrapMiddleware(a.mw, wrapMiddleware(mw, respondNotAcceptable))
+
 	// The function to execute for each request.
 	h := func(w http.ResponseWriter, r *http.Request) {
 
@@ -128,8 +144,21 @@ func (a *App) handle(debug bool, verb string, path string, handler Handler, mw .
 		}
 		ctx = context.WithValue(ctx, KeyValues, &v)
 
+		// Enforce the body size limit set by the MaxBodyBytes middleware.
+		r.Body = &limitedBody{ReadCloser: r.Body, v: &v}
+
+		// Select the response encoder before any work is done on behalf of
+		// the client.
+		next := handler
+		enc, ok := negotiateEncoder(r.Header.Get("Accept"))
+		if !ok {
+			next = notAcceptable
+		}
+		v.encoder = enc
+		v.accept = r.Header.Get("Accept")
+
 		// Call the wrapped handler functions.
-		if err := handler(ctx, w, r); err != nil {
+		if err := next(ctx, w, r); err != nil {
 			a.SignalShutdown()
 			return
 		}
@@ -149,4 +178,12 @@ func (a *App) handle(debug bool, verb string, path string, handler Handler, mw .
 		return
 	}
 	a.mux.MethodFunc(verb, path, h)
+	a.routes = append(a.routes, route{verb: verb, path: path})
+}
+
+// respondNotAcceptable is the handler used when none of the registered
+// encoders produces a media type the client accepts.
+func respondNotAcceptable(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	err := fmt.Errorf("none of the requested media types are supported: %s", r.Header.Get("Accept"))
+	return NewRequestError(err, http.StatusNotAcceptable)
 }